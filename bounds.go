@@ -0,0 +1,103 @@
+package gpx
+
+import (
+	"math"
+	"slices"
+)
+
+// Bounds returns the bounds of all of g's waypoints, route points, and track
+// points, or nil if g has no points. If the points are more compactly bounded
+// by crossing the antimeridian then the returned MinLon is greater than
+// MaxLon.
+func (g *GPX) Bounds() *BoundsType {
+	var lats, lons []float64
+	add := func(wpts []*WptType) {
+		for _, wpt := range wpts {
+			lats = append(lats, wpt.Lat)
+			lons = append(lons, wpt.Lon)
+		}
+	}
+	add(g.Wpt)
+	for _, rte := range g.Rte {
+		add(rte.RtePt)
+	}
+	for _, trk := range g.Trk {
+		for _, trkSeg := range trk.TrkSeg {
+			add(trkSeg.TrkPt)
+		}
+	}
+	if len(lats) == 0 {
+		return nil
+	}
+	minLon, maxLon := lonBounds(lons)
+	return &BoundsType{
+		MinLat: slices.Min(lats),
+		MinLon: minLon,
+		MaxLat: slices.Max(lats),
+		MaxLon: maxLon,
+	}
+}
+
+// lonBounds returns the smallest range of longitudes that contains all of
+// lons. The range is found by removing the largest gap between consecutive
+// longitudes around the circle, so minLon is greater than maxLon if the range
+// crosses the antimeridian.
+func lonBounds(lons []float64) (float64, float64) {
+	sorted := slices.Clone(lons)
+	slices.Sort(sorted)
+	n := len(sorted)
+	minLon, maxLon := sorted[0], sorted[n-1]
+	largestGap := sorted[0] + 360 - sorted[n-1]
+	for i := 1; i < n; i++ {
+		if gap := sorted[i] - sorted[i-1]; gap > largestGap {
+			largestGap = gap
+			minLon, maxLon = sorted[i], sorted[i-1]
+		}
+	}
+	return minLon, maxLon
+}
+
+// unionBounds returns the smallest bounds that contain all of bounds, or nil
+// if bounds is empty. Bounds with MinLon greater than MaxLon cross the
+// antimeridian.
+func unionBounds(bounds []*BoundsType) *BoundsType {
+	if len(bounds) == 0 {
+		return nil
+	}
+	result := &BoundsType{
+		MinLat: bounds[0].MinLat,
+		MaxLat: bounds[0].MaxLat,
+	}
+	for _, b := range bounds[1:] {
+		result.MinLat = min(result.MinLat, b.MinLat)
+		result.MaxLat = max(result.MaxLat, b.MaxLat)
+	}
+	// The smallest range of longitudes starts at the start of one of the
+	// ranges, so try each one in turn.
+	bestExtent := math.Inf(1)
+	for _, candidate := range bounds {
+		start := candidate.MinLon
+		extent := 0.0
+		for _, b := range bounds {
+			minLon, maxLon := b.MinLon, b.MaxLon
+			if maxLon < minLon {
+				maxLon += 360
+			}
+			for minLon < start {
+				minLon += 360
+				maxLon += 360
+			}
+			extent = max(extent, maxLon-start)
+		}
+		if extent < bestExtent {
+			bestExtent = extent
+			result.MinLon = start
+			result.MaxLon = math.Remainder(start+extent, 360)
+		}
+	}
+	if bestExtent >= 360 {
+		result.MinLon = -180
+		result.MaxLon = 180
+	}
+	return result
+}
@@ -0,0 +1,126 @@
+package gpx_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	gpx "github.com/twpayne/go-gpx"
+)
+
+func TestGPXBounds(t *testing.T) {
+	newWpts := func(latLons ...float64) []*gpx.WptType {
+		wpts := make([]*gpx.WptType, 0, len(latLons)/2)
+		for i := 0; i < len(latLons); i += 2 {
+			wpts = append(wpts, &gpx.WptType{
+				Lat: latLons[i],
+				Lon: latLons[i+1],
+			})
+		}
+		return wpts
+	}
+
+	for i, tc := range []struct {
+		gpx      *gpx.GPX
+		expected *gpx.BoundsType
+	}{
+		{
+			gpx:      &gpx.GPX{},
+			expected: nil,
+		},
+		{
+			gpx: &gpx.GPX{
+				Wpt: newWpts(1, 2),
+			},
+			expected: &gpx.BoundsType{
+				MinLat: 1,
+				MinLon: 2,
+				MaxLat: 1,
+				MaxLon: 2,
+			},
+		},
+		{
+			gpx: &gpx.GPX{
+				Wpt: newWpts(1, 2),
+				Rte: []*gpx.RteType{
+					{RtePt: newWpts(-3, 4)},
+				},
+				Trk: []*gpx.TrkType{
+					{
+						TrkSeg: []*gpx.TrkSegType{
+							{TrkPt: newWpts(5, -6, 0, 0)},
+						},
+					},
+				},
+			},
+			expected: &gpx.BoundsType{
+				MinLat: -3,
+				MinLon: -6,
+				MaxLat: 5,
+				MaxLon: 4,
+			},
+		},
+		{
+			gpx: &gpx.GPX{
+				Trk: []*gpx.TrkType{
+					{
+						TrkSeg: []*gpx.TrkSegType{
+							{TrkPt: newWpts(-17, 178, -17.5, 179.5, -18, -179.5, -18.5, -178)},
+						},
+					},
+				},
+			},
+			expected: &gpx.BoundsType{
+				MinLat: -18.5,
+				MinLon: 178,
+				MaxLat: -17,
+				MaxLon: -178,
+			},
+		},
+		{
+			gpx: &gpx.GPX{
+				Wpt: newWpts(0, 170, 0, -170, 0, 0),
+			},
+			expected: &gpx.BoundsType{
+				MinLon: 0,
+				MaxLon: -170,
+			},
+		},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.gpx.Bounds())
+		})
+	}
+}
+
+func TestWriteRefresh(t *testing.T) {
+	g := &gpx.GPX{
+		Version: "1.1",
+		Metadata: &gpx.MetadataType{
+			Name: "name",
+			Bounds: &gpx.BoundsType{
+				MinLat: 10,
+				MinLon: 10,
+				MaxLat: 10,
+				MaxLon: 10,
+			},
+		},
+		Wpt: []*gpx.WptType{
+			{Lat: 1, Lon: 2},
+			{Lat: 3, Lon: 4},
+		},
+	}
+	sb := &strings.Builder{}
+	assert.NoError(t, g.Write(sb, gpx.WithRefreshBounds(), gpx.WithRefreshTime(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))))
+	assert.Equal(t, ""+
+		"<gpx version=\"1.1\" creator=\"\" xmlns:xsi=\"http://www.w3.org/2001/XMLSchema-instance\" xmlns=\"http://www.topografix.com/GPX/1/1\" xsi:schemaLocation=\"http://www.topografix.com/GPX/1/1 https://www.topografix.com/GPX/1/1/gpx.xsd\">"+
+		"<metadata><name>name</name><time>2024-06-01T12:00:00Z</time><bounds minlat=\"1\" minlon=\"2\" maxlat=\"3\" maxlon=\"4\"></bounds></metadata>"+
+		"<wpt lat=\"1\" lon=\"2\"></wpt>"+
+		"<wpt lat=\"3\" lon=\"4\"></wpt>"+
+		"</gpx>", sb.String())
+	assert.Equal(t, &gpx.BoundsType{MinLat: 10, MinLon: 10, MaxLat: 10, MaxLon: 10}, g.Metadata.Bounds)
+	assert.True(t, g.Metadata.Time.IsZero())
+}
@@ -0,0 +1,227 @@
+package gpx
+
+import (
+	"time"
+
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/xy"
+	"github.com/twpayne/go-geom/xy/location"
+)
+
+// crossingIterations is the number of bisection steps used to locate a
+// boundary crossing.
+const crossingIterations = 48
+
+// A CropRegion is a region to crop to. It returns whether w is in the region.
+type CropRegion func(w *WptType) bool
+
+// A CropOption sets an option for cropping.
+type CropOption func(*cropOptions)
+
+type cropOptions struct {
+	interpolateCrossings bool
+}
+
+// WithInterpolateCrossings adds an interpolated point wherever the points
+// leave or enter the region, so cropped segments run exactly to the region's
+// boundary. Values are interpolated as TrkSegType.ResampleTime.
+func WithInterpolateCrossings() CropOption {
+	return func(o *cropOptions) {
+		o.interpolateCrossings = true
+	}
+}
+
+// CropTime returns a CropRegion containing points with times between start
+// and end inclusive. A zero start or end leaves that end of the range open.
+// Points without a time are never in the region.
+func CropTime(start, end time.Time) CropRegion {
+	return func(w *WptType) bool {
+		switch {
+		case w.Time.IsZero():
+			return false
+		case !start.IsZero() && w.Time.Before(start):
+			return false
+		case !end.IsZero() && w.Time.After(end):
+			return false
+		default:
+			return true
+		}
+	}
+}
+
+// CropBounds returns a CropRegion containing points within bounds. If
+// bounds.MinLon is greater than bounds.MaxLon then the region crosses the
+// antimeridian.
+func CropBounds(bounds *BoundsType) CropRegion {
+	return func(w *WptType) bool {
+		if w.Lat < bounds.MinLat || w.Lat > bounds.MaxLat {
+			return false
+		}
+		if bounds.MinLon <= bounds.MaxLon {
+			return bounds.MinLon <= w.Lon && w.Lon <= bounds.MaxLon
+		}
+		return w.Lon >= bounds.MinLon || w.Lon <= bounds.MaxLon
+	}
+}
+
+// CropPolygon returns a CropRegion containing points within polygon, whose
+// coordinates are longitudes and latitudes. Points on the polygon's boundary
+// are in the region and points in its holes are not.
+func CropPolygon(polygon *geom.Polygon) CropRegion {
+	layout := polygon.Layout()
+	return func(w *WptType) bool {
+		if polygon.NumLinearRings() == 0 {
+			return false
+		}
+		coord := geom.Coord{w.Lon, w.Lat}
+		if !xy.IsPointInRing(layout, coord, polygon.LinearRing(0).FlatCoords()) {
+			return false
+		}
+		for i := 1; i < polygon.NumLinearRings(); i++ {
+			if xy.LocatePointInRing(layout, coord, polygon.LinearRing(i).FlatCoords()) == location.Interior {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Crop returns a copy of g containing only the waypoints, route points, and
+// track points in region. Routes and tracks are cropped as RteType.Crop and
+// TrkType.Crop, and routes and tracks left with no points are removed. g's
+// metadata is unchanged, use WithRefreshBounds when writing to update its
+// bounds.
+func (g *GPX) Crop(region CropRegion, options ...CropOption) (*GPX, error) {
+	result := *g
+	result.Wpt = nil
+	for _, wpt := range g.Wpt {
+		if region(wpt) {
+			result.Wpt = append(result.Wpt, wpt)
+		}
+	}
+	result.Rte = nil
+	for _, rte := range g.Rte {
+		croppedRte, err := rte.Crop(region, options...)
+		if err != nil {
+			return nil, err
+		}
+		if len(croppedRte.RtePt) > 0 {
+			result.Rte = append(result.Rte, croppedRte)
+		}
+	}
+	result.Trk = nil
+	for _, trk := range g.Trk {
+		croppedTrk, err := trk.Crop(region, options...)
+		if err != nil {
+			return nil, err
+		}
+		if len(croppedTrk.TrkSeg) > 0 {
+			result.Trk = append(result.Trk, croppedTrk)
+		}
+	}
+	return &result, nil
+}
+
+// Crop returns a copy of r containing only the route points in region. A
+// route that leaves and re-enters region remains a single route.
+func (r *RteType) Crop(region CropRegion, options ...CropOption) (*RteType, error) {
+	runs, err := cropWpts(r.RtePt, region, newCropOptions(options))
+	if err != nil {
+		return nil, err
+	}
+	rte := *r
+	rte.RtePt = nil
+	for _, run := range runs {
+		rte.RtePt = append(rte.RtePt, run...)
+	}
+	return &rte, nil
+}
+
+// Crop returns a copy of t containing only the track points in region.
+// Segments are split wherever they leave and re-enter region, and segments
+// left with no points are removed.
+func (t *TrkType) Crop(region CropRegion, options ...CropOption) (*TrkType, error) {
+	o := newCropOptions(options)
+	trk := *t
+	trk.TrkSeg = nil
+	for _, trkSeg := range t.TrkSeg {
+		runs, err := cropWpts(trkSeg.TrkPt, region, o)
+		if err != nil {
+			return nil, err
+		}
+		for _, run := range runs {
+			trk.TrkSeg = append(trk.TrkSeg, &TrkSegType{
+				TrkPt:      run,
+				Extensions: trkSeg.Extensions,
+			})
+		}
+	}
+	return &trk, nil
+}
+
+func newCropOptions(options []CropOption) *cropOptions {
+	o := &cropOptions{}
+	for _, option := range options {
+		option(o)
+	}
+	return o
+}
+
+// cropWpts returns the runs of consecutive points in wpts that are in region.
+func cropWpts(wpts []*WptType, region CropRegion, o *cropOptions) ([][]*WptType, error) {
+	var runs [][]*WptType
+	var run []*WptType
+	prevInside := false
+	for i, wpt := range wpts {
+		inside := region(wpt)
+		switch {
+		case inside && !prevInside:
+			if o.interpolateCrossings && i > 0 {
+				crossing, err := cropCrossing(region, wpt, wpts[i-1])
+				if err != nil {
+					return nil, err
+				}
+				run = append(run, crossing)
+			}
+			run = append(run, wpt)
+		case inside:
+			run = append(run, wpt)
+		case prevInside:
+			if o.interpolateCrossings {
+				crossing, err := cropCrossing(region, wpts[i-1], wpt)
+				if err != nil {
+					return nil, err
+				}
+				run = append(run, crossing)
+			}
+			runs = append(runs, run)
+			run = nil
+		}
+		prevInside = inside
+	}
+	if run != nil {
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// cropCrossing returns the point where the line from inside, which is in
+// region, to outside, which is not, crosses region's boundary. The crossing
+// is found by bisection so the returned point is always in region.
+func cropCrossing(region CropRegion, inside, outside *WptType) (*WptType, error) {
+	lo, hi := 0.0, 1.0
+	for range crossingIterations {
+		f := (lo + hi) / 2
+		probe := &WptType{}
+		probe.Lat, probe.Lon = interpolateLatLon(inside, outside, f)
+		if !inside.Time.IsZero() && !outside.Time.IsZero() {
+			probe.Time = inside.Time.Add(time.Duration(f * float64(outside.Time.Sub(inside.Time))))
+		}
+		if region(probe) {
+			lo = f
+		} else {
+			hi = f
+		}
+	}
+	return interpolateWpt(inside, outside, lo)
+}
@@ -0,0 +1,196 @@
+package gpx_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/twpayne/go-geom"
+
+	gpx "github.com/twpayne/go-gpx"
+)
+
+func TestTrkTypeCrop(t *testing.T) {
+	t0 := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	trkPts := make([]*gpx.WptType, 0, 7)
+	for i, lon := range []float64{0, 1, 2, 3, 2, 1, 0} {
+		trkPts = append(trkPts, &gpx.WptType{
+			Lat:  0.5,
+			Lon:  lon,
+			Time: t0.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	trk := &gpx.TrkType{
+		Name: "trk",
+		TrkSeg: []*gpx.TrkSegType{
+			{
+				TrkPt: trkPts,
+			},
+		},
+	}
+	square := geom.NewPolygon(geom.XY).MustSetCoords([][]geom.Coord{
+		{{-0.5, 0}, {1.5, 0}, {1.5, 1}, {-0.5, 1}, {-0.5, 0}},
+	})
+	squareWithHole := geom.NewPolygon(geom.XY).MustSetCoords([][]geom.Coord{
+		{{-0.5, 0}, {3.5, 0}, {3.5, 1}, {-0.5, 1}, {-0.5, 0}},
+		{{0.5, 0.25}, {1.5, 0.25}, {1.5, 0.75}, {0.5, 0.75}, {0.5, 0.25}},
+	})
+
+	for i, tc := range []struct {
+		region         gpx.CropRegion
+		expectedTrkPts [][]*gpx.WptType
+	}{
+		{
+			region: gpx.CropTime(t0.Add(time.Minute), t0.Add(3*time.Minute)),
+			expectedTrkPts: [][]*gpx.WptType{
+				trkPts[1:4],
+			},
+		},
+		{
+			region: gpx.CropTime(time.Time{}, t0.Add(time.Minute)),
+			expectedTrkPts: [][]*gpx.WptType{
+				trkPts[0:2],
+			},
+		},
+		{
+			region: gpx.CropBounds(&gpx.BoundsType{MinLat: 0, MinLon: 0.5, MaxLat: 1, MaxLon: 2.5}),
+			expectedTrkPts: [][]*gpx.WptType{
+				trkPts[1:3],
+				trkPts[4:6],
+			},
+		},
+		{
+			region: gpx.CropBounds(&gpx.BoundsType{MinLat: 0, MinLon: 2.5, MaxLat: 1, MaxLon: 0.5}),
+			expectedTrkPts: [][]*gpx.WptType{
+				trkPts[0:1],
+				trkPts[3:4],
+				trkPts[6:7],
+			},
+		},
+		{
+			region: gpx.CropPolygon(square),
+			expectedTrkPts: [][]*gpx.WptType{
+				trkPts[0:2],
+				trkPts[5:7],
+			},
+		},
+		{
+			region: gpx.CropPolygon(squareWithHole),
+			expectedTrkPts: [][]*gpx.WptType{
+				trkPts[0:1],
+				trkPts[2:5],
+				trkPts[6:7],
+			},
+		},
+		{
+			region: gpx.CropBounds(&gpx.BoundsType{MinLat: 2, MinLon: 0, MaxLat: 3, MaxLon: 3}),
+		},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			actual, err := trk.Crop(tc.region)
+			assert.NoError(t, err)
+			assert.Equal(t, "trk", actual.Name)
+			var actualTrkPts [][]*gpx.WptType
+			for _, trkSeg := range actual.TrkSeg {
+				actualTrkPts = append(actualTrkPts, trkSeg.TrkPt)
+			}
+			assert.Equal(t, tc.expectedTrkPts, actualTrkPts)
+		})
+	}
+	assert.Equal(t, 7, len(trk.TrkSeg[0].TrkPt))
+}
+
+func TestTrkTypeCropInterpolateCrossings(t *testing.T) {
+	t0 := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	trk := &gpx.TrkType{
+		TrkSeg: []*gpx.TrkSegType{
+			{
+				TrkPt: []*gpx.WptType{
+					{Lon: 0, Ele: 100, Time: t0, Present: gpx.WptEle},
+					{Lon: 0.01, Ele: 200, Time: t0.Add(time.Minute), Present: gpx.WptEle},
+					{Lon: 0.02, Ele: 300, Time: t0.Add(2 * time.Minute), Present: gpx.WptEle},
+				},
+			},
+		},
+	}
+
+	cropped, err := trk.Crop(gpx.CropBounds(&gpx.BoundsType{MinLat: -1, MinLon: 0.005, MaxLat: 1, MaxLon: 0.015}), gpx.WithInterpolateCrossings())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(cropped.TrkSeg))
+	trkPts := cropped.TrkSeg[0].TrkPt
+	assert.Equal(t, 3, len(trkPts))
+	assertInDelta(t, 0.005, trkPts[0].Lon, 1e-9)
+	assertInDelta(t, 150, trkPts[0].Ele, 1e-3)
+	assert.True(t, trkPts[0].Time.Sub(t0.Add(30*time.Second)).Abs() < time.Millisecond)
+	assert.Equal(t, trk.TrkSeg[0].TrkPt[1], trkPts[1])
+	assertInDelta(t, 0.015, trkPts[2].Lon, 1e-9)
+	assertInDelta(t, 250, trkPts[2].Ele, 1e-3)
+
+	cropped, err = trk.Crop(gpx.CropTime(t0.Add(90*time.Second), time.Time{}), gpx.WithInterpolateCrossings())
+	assert.NoError(t, err)
+	trkPts = cropped.TrkSeg[0].TrkPt
+	assert.Equal(t, 2, len(trkPts))
+	assert.True(t, trkPts[0].Time.Sub(t0.Add(90*time.Second)).Abs() < time.Millisecond)
+	assert.False(t, trkPts[0].Time.Before(t0.Add(90*time.Second)))
+	assertInDelta(t, 0.015, trkPts[0].Lon, 1e-9)
+}
+
+func TestGPXCrop(t *testing.T) {
+	g := &gpx.GPX{
+		Version: "1.1",
+		Wpt: []*gpx.WptType{
+			{Lat: 0.5, Lon: 0.5, Name: "inside"},
+			{Lat: 5, Lon: 5, Name: "outside"},
+		},
+		Rte: []*gpx.RteType{
+			{
+				Name: "rte1",
+				RtePt: []*gpx.WptType{
+					{Lat: 0.5, Lon: 0.5},
+					{Lat: 5, Lon: 5},
+					{Lat: 0.6, Lon: 0.6},
+				},
+			},
+			{
+				Name: "rte2",
+				RtePt: []*gpx.WptType{
+					{Lat: 5, Lon: 5},
+				},
+			},
+		},
+		Trk: []*gpx.TrkType{
+			{
+				Name: "trk1",
+				TrkSeg: []*gpx.TrkSegType{
+					{
+						TrkPt: []*gpx.WptType{
+							{Lat: 5, Lon: 5},
+						},
+					},
+				},
+			},
+			{
+				Name: "trk2",
+				TrkSeg: []*gpx.TrkSegType{
+					{
+						TrkPt: []*gpx.WptType{
+							{Lat: 0.5, Lon: 0.5},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	cropped, err := g.Crop(gpx.CropBounds(&gpx.BoundsType{MinLat: 0, MinLon: 0, MaxLat: 1, MaxLon: 1}))
+	assert.NoError(t, err)
+	assert.Equal(t, "1.1", cropped.Version)
+	assert.Equal(t, []*gpx.WptType{g.Wpt[0]}, cropped.Wpt)
+	assert.Equal(t, 1, len(cropped.Rte))
+	assert.Equal(t, "rte1", cropped.Rte[0].Name)
+	assert.Equal(t, []*gpx.WptType{g.Rte[0].RtePt[0], g.Rte[0].RtePt[2]}, cropped.Rte[0].RtePt)
+	assert.Equal(t, 1, len(cropped.Trk))
+	assert.Equal(t, "trk2", cropped.Trk[0].Name)
+	assert.Equal(t, 2, len(g.Wpt))
+}
@@ -0,0 +1,597 @@
+package gpx
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// A TokenType is the type of a Token.
+type TokenType int
+
+// Token types.
+const (
+	MetadataToken TokenType = iota + 1
+	WptToken
+	RteToken
+	RtePtToken
+	TrkToken
+	TrkSegToken
+	TrkPtToken
+	ExtensionsToken
+)
+
+// A Token is an element of a GPX document returned by a Decoder.
+//
+// RteToken and TrkToken tokens carry the route or track without its points or
+// segments. A TrkSegToken carries an empty segment; the segment's extensions,
+// which follow its points in the document, are set on the same TrkSegType
+// once they have been read.
+type Token struct {
+	Type        TokenType
+	Metadata    *MetadataType
+	Wpt         *WptType
+	Rte         *RteType
+	Trk         *TrkType
+	TrkSeg      *TrkSegType
+	Extensions  *ExtensionsType
+	RteIndex    int
+	TrkIndex    int
+	TrkSegIndex int
+	Index       int
+}
+
+type decoderState int
+
+const (
+	decoderStateStart decoderState = iota
+	decoderStateGPX
+	decoderStateRte
+	decoderStateTrk
+	decoderStateTrkSeg
+	decoderStateEnd
+)
+
+// A Decoder reads a GPX document one element at a time.
+//
+// GPX 1.0 documents are normalized into the GPX 1.1 model: the url, urlname,
+// author, email, time, keywords, and bounds children of the gpx element are
+// returned as a MetadataToken and url and urlname elements become links.
+type Decoder struct {
+	d           *xml.Decoder
+	o           *readOptions
+	state       decoderState
+	root        *GPX
+	scopes      []map[string]string
+	metadata    *MetadataType
+	queue       []*Token
+	rte         *RteType
+	rteEmitted  bool
+	trk         *TrkType
+	trkEmitted  bool
+	trkSeg      *TrkSegType
+	wptIndex    int
+	rteIndex    int
+	rtePtIndex  int
+	trkIndex    int
+	trkSegIndex int
+	trkPtIndex  int
+	points      int
+}
+
+var errUnexpectedRootElement = errors.New("unexpected root element")
+
+// NewDecoder returns a new Decoder that reads from r.
+func NewDecoder(r io.Reader, options ...ReadOption) *Decoder {
+	d := xml.NewDecoder(r)
+	d.CharsetReader = charset.NewReaderLabel
+	return &Decoder{
+		d:        d,
+		o:        newReadOptions(options),
+		rteIndex: -1,
+		trkIndex: -1,
+	}
+}
+
+// Root returns a GPX containing only the attributes of the document's root
+// element, reading it if needed.
+func (d *Decoder) Root() (*GPX, error) {
+	if d.state == decoderStateStart {
+		if err := d.readRoot(); err != nil {
+			return nil, err
+		}
+	}
+	return d.root, nil
+}
+
+// Next returns the next Token in the document. It returns io.EOF at the end
+// of the document.
+func (d *Decoder) Next() (*Token, error) {
+	if d.state == decoderStateStart {
+		if err := d.readRoot(); err != nil {
+			return nil, err
+		}
+	}
+	for {
+		if len(d.queue) > 0 {
+			token := d.queue[0]
+			d.queue = d.queue[1:]
+			return token, nil
+		}
+		if d.state == decoderStateEnd {
+			return nil, io.EOF
+		}
+		xmlToken, err := d.d.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		switch xmlToken := xmlToken.(type) {
+		case xml.StartElement:
+			if err := d.startElement(xmlToken); err != nil {
+				return nil, err
+			}
+		case xml.EndElement:
+			d.endElement()
+		}
+	}
+}
+
+func (d *Decoder) readRoot() error {
+	for {
+		xmlToken, err := d.d.Token()
+		if err != nil {
+			return err
+		}
+		start, ok := xmlToken.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local != "gpx" {
+			return fmt.Errorf("%s: %w", start.Name.Local, errUnexpectedRootElement)
+		}
+		d.root = &GPX{}
+		for _, attr := range start.Attr {
+			switch attr.Name.Local {
+			case "version":
+				d.root.Version = attr.Value
+			case "creator":
+				d.root.Creator = attr.Value
+			}
+		}
+		d.root.setRootAttrs(start.Attr)
+		var namespaces map[string]string
+		for k, v := range d.root.XMLAttrs {
+			if prefix, ok := strings.CutPrefix(k, "xmlns:"); ok {
+				if namespaces == nil {
+					namespaces = make(map[string]string)
+				}
+				namespaces[prefix] = v
+			}
+		}
+		d.scopes = append(d.scopes, namespaces)
+		if d.root.Version == "" && start.Name.Space == gpx10Namespace {
+			d.root.Version = "1.0"
+		}
+		d.state = decoderStateGPX
+		return nil
+	}
+}
+
+func (d *Decoder) startElement(start xml.StartElement) error {
+	switch d.state {
+	case decoderStateGPX:
+		return d.startGPXChildElement(start)
+	case decoderStateRte:
+		if start.Name.Local != "rtept" {
+			if err := d.rte.decodeElement(d.d, start, d.o); err != nil {
+				return err
+			}
+			if start.Name.Local != "extensions" {
+				return nil
+			}
+			return d.processExtensions(d.rte.Extensions, d.namespaces())
+		}
+		rtePt, err := d.decodeWpt(start)
+		if err != nil {
+			return err
+		}
+		if !d.rteEmitted {
+			d.pushRteToken()
+		}
+		d.push(&Token{
+			Type:     RtePtToken,
+			Wpt:      rtePt,
+			RteIndex: d.rteIndex,
+			Index:    d.rtePtIndex,
+		})
+		d.rtePtIndex++
+		return nil
+	case decoderStateTrk:
+		if start.Name.Local != "trkseg" {
+			if err := d.trk.decodeElement(d.d, start, d.o); err != nil {
+				return err
+			}
+			if start.Name.Local != "extensions" {
+				return nil
+			}
+			return d.processExtensions(d.trk.Extensions, d.namespaces())
+		}
+		if !d.trkEmitted {
+			d.pushTrkToken()
+		}
+		d.pushScope(start)
+		d.trkSeg = &TrkSegType{}
+		d.trkSegIndex++
+		d.trkPtIndex = 0
+		d.state = decoderStateTrkSeg
+		d.push(&Token{
+			Type:        TrkSegToken,
+			TrkSeg:      d.trkSeg,
+			TrkIndex:    d.trkIndex,
+			TrkSegIndex: d.trkSegIndex,
+		})
+		return nil
+	case decoderStateTrkSeg:
+		switch start.Name.Local {
+		case "trkpt":
+			trkPt, err := d.decodeWpt(start)
+			if err != nil {
+				return err
+			}
+			d.push(&Token{
+				Type:        TrkPtToken,
+				Wpt:         trkPt,
+				TrkIndex:    d.trkIndex,
+				TrkSegIndex: d.trkSegIndex,
+				Index:       d.trkPtIndex,
+			})
+			d.trkPtIndex++
+			return nil
+		case "extensions":
+			extensions, err := d.decodeExtensions(start)
+			if err != nil {
+				return err
+			}
+			d.trkSeg.Extensions = extensions
+			return nil
+		default:
+			return d.skip(start)
+		}
+	default:
+		return d.d.Skip()
+	}
+}
+
+func (d *Decoder) startGPXChildElement(start xml.StartElement) error {
+	switch start.Name.Local {
+	case "name", "desc", "author", "email", "url", "urlname", "time", "keywords", "bounds":
+		if d.o.strict && d.root.Version != "1.0" {
+			return fmt.Errorf("%s: %w", start.Name.Local, errUnexpectedElement)
+		}
+		if d.metadata == nil {
+			d.metadata = &MetadataType{}
+		}
+		return d.metadata.decodeGPX10Element(d.d, start, d.o)
+	}
+	d.pushMetadataToken()
+	switch start.Name.Local {
+	case "metadata":
+		metadata := &MetadataType{}
+		if err := metadata.decodeXML(d.d, start, d.o); err != nil {
+			return err
+		}
+		if err := d.processExtensions(metadata.Extensions, d.scopeNamespaces(start)); err != nil {
+			return err
+		}
+		d.push(&Token{
+			Type:     MetadataToken,
+			Metadata: metadata,
+		})
+		return nil
+	case "wpt":
+		wpt, err := d.decodeWpt(start)
+		if err != nil {
+			return err
+		}
+		d.push(&Token{
+			Type:  WptToken,
+			Wpt:   wpt,
+			Index: d.wptIndex,
+		})
+		d.wptIndex++
+		return nil
+	case "rte":
+		d.pushScope(start)
+		d.rte = &RteType{}
+		d.rteEmitted = false
+		d.rteIndex++
+		d.rtePtIndex = 0
+		d.state = decoderStateRte
+		return nil
+	case "trk":
+		d.pushScope(start)
+		d.trk = &TrkType{}
+		d.trkEmitted = false
+		d.trkIndex++
+		d.trkSegIndex = -1
+		d.state = decoderStateTrk
+		return nil
+	case "extensions":
+		extensions, err := d.decodeExtensions(start)
+		if err != nil {
+			return err
+		}
+		d.push(&Token{
+			Type:       ExtensionsToken,
+			Extensions: extensions,
+		})
+		return nil
+	default:
+		return d.skip(start)
+	}
+}
+
+func (d *Decoder) decodeWpt(start xml.StartElement) (*WptType, error) {
+	d.points++
+	if d.o.maxPoints > 0 && d.points > d.o.maxPoints {
+		return nil, fmt.Errorf("%d: %w", d.o.maxPoints, errTooManyPoints)
+	}
+	wpt := &WptType{}
+	if err := wpt.decodeXML(d.d, start, d.o); err != nil {
+		return nil, err
+	}
+	if err := d.processExtensions(wpt.Extensions, d.scopeNamespaces(start)); err != nil {
+		return nil, err
+	}
+	return wpt, nil
+}
+
+func (d *Decoder) decodeExtensions(start xml.StartElement) (*ExtensionsType, error) {
+	extensions := &ExtensionsType{}
+	if err := d.d.DecodeElement(extensions, &start); err != nil {
+		return nil, err
+	}
+	if err := d.processExtensions(extensions, d.namespaces()); err != nil {
+		return nil, err
+	}
+	return extensions, nil
+}
+
+// processExtensions sets the namespace prefixes in scope on extensions,
+// decodes any elements in registered namespaces, and calls the extensions
+// handler. namespaces are the prefixes in scope for the parent of the
+// extensions element. Prefixes declared on the extensions element itself take
+// precedence.
+func (d *Decoder) processExtensions(extensions *ExtensionsType, namespaces map[string]string) error {
+	if extensions == nil {
+		return nil
+	}
+	extensions.Namespaces = mergeNamespaces(namespaces, extensions.Namespaces)
+	if err := extensions.decodeTyped(d.o.extensionRegistry); err != nil {
+		return err
+	}
+	if d.o.extensionsHandler != nil {
+		return d.o.extensionsHandler(extensions)
+	}
+	return nil
+}
+
+func (d *Decoder) skip(start xml.StartElement) error {
+	return skipElement(d.d, start, d.o)
+}
+
+// namespaces returns the namespace prefixes in the current scope. The
+// returned map must not be modified.
+func (d *Decoder) namespaces() map[string]string {
+	if len(d.scopes) == 0 {
+		return nil
+	}
+	return d.scopes[len(d.scopes)-1]
+}
+
+// scopeNamespaces returns the namespace prefixes in scope inside start, a
+// child of the current element. The returned map must not be modified.
+func (d *Decoder) scopeNamespaces(start xml.StartElement) map[string]string {
+	declared := declaredNamespaces(start.Attr)
+	if declared == nil {
+		return d.namespaces()
+	}
+	return mergeNamespaces(d.namespaces(), declared)
+}
+
+// pushScope enters the scope of start.
+func (d *Decoder) pushScope(start xml.StartElement) {
+	d.scopes = append(d.scopes, d.scopeNamespaces(start))
+}
+
+func (d *Decoder) endElement() {
+	if len(d.scopes) > 0 {
+		d.scopes = d.scopes[:len(d.scopes)-1]
+	}
+	switch d.state {
+	case decoderStateGPX:
+		d.pushMetadataToken()
+		d.state = decoderStateEnd
+	case decoderStateRte:
+		d.state = decoderStateGPX
+		if !d.rteEmitted {
+			d.pushRteToken()
+		}
+	case decoderStateTrk:
+		d.state = decoderStateGPX
+		if !d.trkEmitted {
+			d.pushTrkToken()
+		}
+	case decoderStateTrkSeg:
+		d.state = decoderStateTrk
+	}
+}
+
+func (d *Decoder) push(token *Token) {
+	d.queue = append(d.queue, token)
+}
+
+// pushMetadataToken pushes the metadata read from GPX 1.0 elements, if any.
+func (d *Decoder) pushMetadataToken() {
+	if d.metadata == nil {
+		return
+	}
+	d.push(&Token{
+		Type:     MetadataToken,
+		Metadata: d.metadata,
+	})
+	d.metadata = nil
+}
+
+func (d *Decoder) pushRteToken() {
+	d.rteEmitted = true
+	d.push(&Token{
+		Type:     RteToken,
+		Rte:      d.rte,
+		RteIndex: d.rteIndex,
+	})
+}
+
+func (d *Decoder) pushTrkToken() {
+	d.trkEmitted = true
+	d.push(&Token{
+		Type:     TrkToken,
+		Trk:      d.trk,
+		TrkIndex: d.trkIndex,
+	})
+}
+
+// decodeElement decodes the child element start of a rte, other than a
+// rtept, into r.
+func (r *RteType) decodeElement(d *xml.Decoder, start xml.StartElement, o *readOptions) error {
+	switch start.Name.Local {
+	case "name":
+		return d.DecodeElement(&r.Name, &start)
+	case "cmt":
+		return d.DecodeElement(&r.Cmt, &start)
+	case "desc":
+		return d.DecodeElement(&r.Desc, &start)
+	case "src":
+		return d.DecodeElement(&r.Src, &start)
+	case "link":
+		link := &LinkType{}
+		if err := d.DecodeElement(link, &start); err != nil {
+			return err
+		}
+		r.Link = append(r.Link, link)
+		return nil
+	case "url":
+		var url string
+		if err := d.DecodeElement(&url, &start); err != nil {
+			return err
+		}
+		r.Link = appendURL(r.Link, url)
+		return nil
+	case "urlname":
+		var urlName string
+		if err := d.DecodeElement(&urlName, &start); err != nil {
+			return err
+		}
+		r.Link = appendURLName(r.Link, urlName)
+		return nil
+	case "number":
+		return d.DecodeElement(&r.Number, &start)
+	case "type":
+		return d.DecodeElement(&r.Type, &start)
+	case "extensions":
+		r.Extensions = &ExtensionsType{}
+		return d.DecodeElement(r.Extensions, &start)
+	default:
+		return skipElement(d, start, o)
+	}
+}
+
+// decodeElement decodes the child element start of a trk, other than a
+// trkseg, into t.
+func (t *TrkType) decodeElement(d *xml.Decoder, start xml.StartElement, o *readOptions) error {
+	switch start.Name.Local {
+	case "name":
+		return d.DecodeElement(&t.Name, &start)
+	case "cmt":
+		return d.DecodeElement(&t.Cmt, &start)
+	case "desc":
+		return d.DecodeElement(&t.Desc, &start)
+	case "src":
+		return d.DecodeElement(&t.Src, &start)
+	case "link":
+		link := &LinkType{}
+		if err := d.DecodeElement(link, &start); err != nil {
+			return err
+		}
+		t.Link = append(t.Link, link)
+		return nil
+	case "url":
+		var url string
+		if err := d.DecodeElement(&url, &start); err != nil {
+			return err
+		}
+		t.Link = appendURL(t.Link, url)
+		return nil
+	case "urlname":
+		var urlName string
+		if err := d.DecodeElement(&urlName, &start); err != nil {
+			return err
+		}
+		t.Link = appendURLName(t.Link, urlName)
+		return nil
+	case "number":
+		return d.DecodeElement(&t.Number, &start)
+	case "type":
+		return d.DecodeElement(&t.Type, &start)
+	case "extensions":
+		t.Extensions = &ExtensionsType{}
+		return d.DecodeElement(t.Extensions, &start)
+	default:
+		return skipElement(d, start, o)
+	}
+}
+
+// declaredNamespaces returns the namespace prefixes declared in attrs,
+// excluding the xsi prefix which is always declared on the root element.
+func declaredNamespaces(attrs []xml.Attr) map[string]string {
+	var namespaces map[string]string
+	for _, attr := range attrs {
+		if attr.Name.Space != "xmlns" || attr.Name.Local == "xsi" && attr.Value == xsiNamespace {
+			continue
+		}
+		if namespaces == nil {
+			namespaces = make(map[string]string)
+		}
+		namespaces[attr.Name.Local] = attr.Value
+	}
+	return namespaces
+}
+
+// mergeNamespaces returns a new map containing the namespace prefixes in
+// outer overridden by those in inner, or nil if both are empty.
+func mergeNamespaces(outer, inner map[string]string) map[string]string {
+	if len(outer) == 0 && len(inner) == 0 {
+		return nil
+	}
+	namespaces := make(map[string]string, len(outer)+len(inner))
+	maps.Copy(namespaces, outer)
+	maps.Copy(namespaces, inner)
+	return namespaces
+}
+
+// skipElement skips the unexpected element start, or returns an error if o is
+// strict.
+func skipElement(d *xml.Decoder, start xml.StartElement, o *readOptions) error {
+	if o.strict {
+		return fmt.Errorf("%s: %w", start.Name.Local, errUnexpectedElement)
+	}
+	return d.Skip()
+}
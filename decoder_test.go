@@ -0,0 +1,174 @@
+package gpx_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	gpx "github.com/twpayne/go-gpx"
+)
+
+func TestDecoder(t *testing.T) {
+	d := gpx.NewDecoder(bytes.NewBufferString(`<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="creator" xmlns="http://www.topografix.com/GPX/1/1">
+	<metadata>
+		<name>name</name>
+	</metadata>
+	<wpt lat="1" lon="2"></wpt>
+	<rte>
+		<name>rte</name>
+		<rtept lat="3" lon="4"></rtept>
+		<rtept lat="5" lon="6"></rtept>
+	</rte>
+	<trk>
+		<name>trk</name>
+		<trkseg>
+			<trkpt lat="7" lon="8">
+				<time>2001-11-28T21:05:28Z</time>
+			</trkpt>
+		</trkseg>
+		<trkseg></trkseg>
+	</trk>
+	<trk></trk>
+</gpx>`))
+
+	root, err := d.Root()
+	assert.NoError(t, err)
+	assert.Equal(t, &gpx.GPX{Version: "1.1", Creator: "creator"}, root)
+
+	for i, expected := range []*gpx.Token{
+		{
+			Type:     gpx.MetadataToken,
+			Metadata: &gpx.MetadataType{Name: "name"},
+		},
+		{
+			Type: gpx.WptToken,
+			Wpt:  &gpx.WptType{Lat: 1, Lon: 2},
+		},
+		{
+			Type: gpx.RteToken,
+			Rte:  &gpx.RteType{Name: "rte"},
+		},
+		{
+			Type: gpx.RtePtToken,
+			Wpt:  &gpx.WptType{Lat: 3, Lon: 4},
+		},
+		{
+			Type:  gpx.RtePtToken,
+			Wpt:   &gpx.WptType{Lat: 5, Lon: 6},
+			Index: 1,
+		},
+		{
+			Type: gpx.TrkToken,
+			Trk:  &gpx.TrkType{Name: "trk"},
+		},
+		{
+			Type:   gpx.TrkSegToken,
+			TrkSeg: &gpx.TrkSegType{},
+		},
+		{
+			Type: gpx.TrkPtToken,
+			Wpt: &gpx.WptType{
+				Lat:  7,
+				Lon:  8,
+				Time: time.Date(2001, 11, 28, 21, 5, 28, 0, time.UTC),
+			},
+		},
+		{
+			Type:        gpx.TrkSegToken,
+			TrkSeg:      &gpx.TrkSegType{},
+			TrkSegIndex: 1,
+		},
+		{
+			Type:     gpx.TrkToken,
+			Trk:      &gpx.TrkType{},
+			TrkIndex: 1,
+		},
+	} {
+		token, err := d.Next()
+		assert.NoError(t, err, "token %d", i)
+		assert.Equal(t, expected, token, "token %d", i)
+	}
+	_, err = d.Next()
+	assert.IsError(t, err, io.EOF)
+}
+
+func TestDecoderUnexpectedEOF(t *testing.T) {
+	d := gpx.NewDecoder(bytes.NewBufferString(`<gpx version="1.1"><wpt lat="1" lon="2"></wpt>`))
+	_, err := d.Next()
+	assert.NoError(t, err)
+	_, err = d.Next()
+	assert.Error(t, err)
+}
+
+func TestDecoderNamespaces(t *testing.T) {
+	g, err := gpx.Read(strings.NewReader(`<gpx version="1.1" xmlns:a="urn:a">` +
+		`<wpt lat="1" lon="2"><extensions><a:x/></extensions></wpt>` +
+		`<wpt lat="3" lon="4" xmlns:b="urn:b"><extensions><b:y/></extensions></wpt>` +
+		`<wpt lat="5" lon="6"><extensions><a:z/></extensions></wpt>` +
+		`</gpx>`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "urn:a"}, g.Wpt[0].Extensions.Namespaces)
+	assert.Equal(t, map[string]string{"a": "urn:a", "b": "urn:b"}, g.Wpt[1].Extensions.Namespaces)
+	g.Wpt[0].Extensions.Namespaces["c"] = "urn:c"
+	assert.Equal(t, map[string]string{"a": "urn:a"}, g.Wpt[2].Extensions.Namespaces)
+}
+
+func TestDecoderExamples(t *testing.T) {
+	dir := "testdata"
+	err := fs.WalkDir(os.DirFS(dir), ".", func(filename string, dirEntry fs.DirEntry, err error) error {
+		assert.NoError(t, err)
+		if dirEntry.IsDir() || filepath.Ext(filename) != ".gpx" {
+			return nil
+		}
+		data, err := os.ReadFile(filepath.Join(dir, filename))
+		assert.NoError(t, err)
+		expected, err := gpx.Read(bytes.NewReader(data))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, decodeAll(t, gpx.NewDecoder(bytes.NewReader(data))))
+		return nil
+	})
+	assert.NoError(t, err)
+}
+
+func decodeAll(t *testing.T, d *gpx.Decoder) *gpx.GPX {
+	t.Helper()
+	g, err := d.Root()
+	assert.NoError(t, err)
+	for {
+		token, err := d.Next()
+		if errors.Is(err, io.EOF) {
+			return g
+		}
+		assert.NoError(t, err)
+		switch token.Type {
+		case gpx.MetadataToken:
+			g.Metadata = token.Metadata
+		case gpx.WptToken:
+			g.Wpt = append(g.Wpt, token.Wpt)
+		case gpx.RteToken:
+			g.Rte = append(g.Rte, token.Rte)
+		case gpx.RtePtToken:
+			rte := g.Rte[token.RteIndex]
+			rte.RtePt = append(rte.RtePt, token.Wpt)
+		case gpx.TrkToken:
+			g.Trk = append(g.Trk, token.Trk)
+		case gpx.TrkSegToken:
+			trk := g.Trk[token.TrkIndex]
+			trk.TrkSeg = append(trk.TrkSeg, token.TrkSeg)
+		case gpx.TrkPtToken:
+			trkSeg := g.Trk[token.TrkIndex].TrkSeg[token.TrkSegIndex]
+			trkSeg.TrkPt = append(trkSeg.TrkPt, token.Wpt)
+		case gpx.ExtensionsToken:
+			g.Extensions = token.Extensions
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package gpx
+
+import "math"
+
+// defaultElevationHysteresis is the default minimum change in elevation in
+// meters that is counted as ascent or descent.
+const defaultElevationHysteresis = 5
+
+// ElevationStats are elevation statistics of a track, track segment, or
+// route. Elevations and distances are in meters. Min and Max are computed
+// from the recorded elevations, before any smoothing.
+type ElevationStats struct {
+	Ascent  float64
+	Descent float64
+	Min     float64
+	Max     float64
+	Profile []ElevationProfilePoint
+}
+
+// An ElevationProfilePoint is a point in an elevation profile. Distance is
+// the cumulative distance along the track, excluding gaps between segments.
+type ElevationProfilePoint struct {
+	Distance float64
+	Ele      float64
+}
+
+// An ElevationOption sets an option for computing ElevationStats.
+type ElevationOption func(*elevationOptions)
+
+type elevationOptions struct {
+	hysteresis      float64
+	smoothingWindow int
+}
+
+// elevationAccumulator accumulates ElevationStats over segments.
+type elevationAccumulator struct {
+	o        elevationOptions
+	stats    ElevationStats
+	distance float64
+}
+
+// WithElevationHysteresis sets the minimum change in elevation in meters,
+// relative to the last elevation counted, that is counted as ascent or
+// descent. Smaller changes are treated as noise. The default is 5m.
+func WithElevationHysteresis(hysteresis float64) ElevationOption {
+	return func(o *elevationOptions) {
+		o.hysteresis = hysteresis
+	}
+}
+
+// WithElevationSmoothing smooths elevations with a centered moving average
+// over window points before computing statistics. The default window is 1,
+// which disables smoothing.
+func WithElevationSmoothing(window int) ElevationOption {
+	return func(o *elevationOptions) {
+		o.smoothingWindow = window
+	}
+}
+
+// ElevationStats returns elevation statistics of ts.
+func (ts *TrkSegType) ElevationStats(options ...ElevationOption) *ElevationStats {
+	a := newElevationAccumulator(options)
+	a.addSegment(ts.TrkPt)
+	return a.result()
+}
+
+// ElevationStats returns elevation statistics of t. Changes in elevation
+// between segments are not counted as ascent or descent.
+func (t *TrkType) ElevationStats(options ...ElevationOption) *ElevationStats {
+	a := newElevationAccumulator(options)
+	for _, trkSeg := range t.TrkSeg {
+		a.addSegment(trkSeg.TrkPt)
+	}
+	return a.result()
+}
+
+// ElevationStats returns elevation statistics of r.
+func (r *RteType) ElevationStats(options ...ElevationOption) *ElevationStats {
+	a := newElevationAccumulator(options)
+	a.addSegment(r.RtePt)
+	return a.result()
+}
+
+func newElevationAccumulator(options []ElevationOption) *elevationAccumulator {
+	a := &elevationAccumulator{
+		o: elevationOptions{
+			hysteresis:      defaultElevationHysteresis,
+			smoothingWindow: 1,
+		},
+		stats: ElevationStats{
+			Min: math.Inf(1),
+			Max: math.Inf(-1),
+		},
+	}
+	for _, option := range options {
+		option(&a.o)
+	}
+	return a
+}
+
+func (a *elevationAccumulator) addSegment(wpts []*WptType) {
+	profile := make([]ElevationProfilePoint, 0, len(wpts))
+	for i, wpt := range wpts {
+		if i > 0 {
+			a.distance += wpts[i-1].Distance(wpt)
+		}
+		if wpt.Has(WptEle) {
+			a.stats.Min = min(a.stats.Min, wpt.Ele)
+			a.stats.Max = max(a.stats.Max, wpt.Ele)
+			profile = append(profile, ElevationProfilePoint{
+				Distance: a.distance,
+				Ele:      wpt.Ele,
+			})
+		}
+	}
+	if len(profile) == 0 {
+		return
+	}
+	profile = smoothElevations(profile, a.o.smoothingWindow)
+	ref := profile[0].Ele
+	for _, p := range profile {
+		switch delta := p.Ele - ref; {
+		case delta >= a.o.hysteresis:
+			a.stats.Ascent += delta
+			ref = p.Ele
+		case -delta >= a.o.hysteresis:
+			a.stats.Descent -= delta
+			ref = p.Ele
+		}
+	}
+	a.stats.Profile = append(a.stats.Profile, profile...)
+}
+
+func (a *elevationAccumulator) result() *ElevationStats {
+	stats := a.stats
+	if len(stats.Profile) == 0 {
+		stats.Min = 0
+		stats.Max = 0
+	}
+	return &stats
+}
+
+// smoothElevations returns profile with its elevations replaced by a centered
+// moving average over window points.
+func smoothElevations(profile []ElevationProfilePoint, window int) []ElevationProfilePoint {
+	if window <= 1 {
+		return profile
+	}
+	smoothed := make([]ElevationProfilePoint, len(profile))
+	halfWindow := window / 2
+	for i := range profile {
+		start := max(i-halfWindow, 0)
+		end := min(i+window-halfWindow, len(profile))
+		sum := 0.0
+		for _, p := range profile[start:end] {
+			sum += p.Ele
+		}
+		smoothed[i] = ElevationProfilePoint{
+			Distance: profile[i].Distance,
+			Ele:      sum / float64(end-start),
+		}
+	}
+	return smoothed
+}
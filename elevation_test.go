@@ -0,0 +1,132 @@
+package gpx_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	gpx "github.com/twpayne/go-gpx"
+)
+
+func TestTrkTypeElevationStats(t *testing.T) {
+	newTrkPts := func(lon0 float64, eles ...float64) []*gpx.WptType {
+		trkPts := make([]*gpx.WptType, 0, len(eles))
+		for i, ele := range eles {
+			trkPts = append(trkPts, &gpx.WptType{
+				Lon:     lon0 + 0.001*float64(i),
+				Ele:     ele,
+				Present: gpx.WptEle,
+			})
+		}
+		return trkPts
+	}
+	d := 0.001 * metersPerDegree
+
+	for i, tc := range []struct {
+		trk             *gpx.TrkType
+		options         []gpx.ElevationOption
+		expectedAscent  float64
+		expectedDescent float64
+		expectedMin     float64
+		expectedMax     float64
+		expectedProfile []gpx.ElevationProfilePoint
+	}{
+		{
+			trk:             &gpx.TrkType{},
+			expectedProfile: nil,
+		},
+		{
+			trk: &gpx.TrkType{
+				TrkSeg: []*gpx.TrkSegType{
+					{TrkPt: newTrkPts(0, 0, 2, 1, 3, 10, 8, 12, 0)},
+				},
+			},
+			expectedAscent:  10,
+			expectedDescent: 10,
+			expectedMin:     0,
+			expectedMax:     12,
+		},
+		{
+			trk: &gpx.TrkType{
+				TrkSeg: []*gpx.TrkSegType{
+					{TrkPt: newTrkPts(0, 0, 2, 1, 3, 10, 8, 12, 0)},
+				},
+			},
+			options:         []gpx.ElevationOption{gpx.WithElevationHysteresis(0)},
+			expectedAscent:  15,
+			expectedDescent: 15,
+			expectedMin:     0,
+			expectedMax:     12,
+		},
+		{
+			trk: &gpx.TrkType{
+				TrkSeg: []*gpx.TrkSegType{
+					{TrkPt: newTrkPts(0, 100, 110)},
+					{TrkPt: newTrkPts(1, 50, 40)},
+				},
+			},
+			expectedAscent:  10,
+			expectedDescent: 10,
+			expectedMin:     40,
+			expectedMax:     110,
+			expectedProfile: []gpx.ElevationProfilePoint{
+				{Distance: 0, Ele: 100},
+				{Distance: d, Ele: 110},
+				{Distance: d, Ele: 50},
+				{Distance: 2 * d, Ele: 40},
+			},
+		},
+		{
+			trk: &gpx.TrkType{
+				TrkSeg: []*gpx.TrkSegType{
+					{TrkPt: newTrkPts(0, 0, 6, 0)},
+				},
+			},
+			options: []gpx.ElevationOption{
+				gpx.WithElevationHysteresis(0),
+				gpx.WithElevationSmoothing(3),
+			},
+			expectedAscent:  1,
+			expectedDescent: 1,
+			expectedMin:     0,
+			expectedMax:     6,
+			expectedProfile: []gpx.ElevationProfilePoint{
+				{Distance: 0, Ele: 3},
+				{Distance: d, Ele: 2},
+				{Distance: 2 * d, Ele: 3},
+			},
+		},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			stats := tc.trk.ElevationStats(tc.options...)
+			assertInDelta(t, tc.expectedAscent, stats.Ascent, 1e-9)
+			assertInDelta(t, tc.expectedDescent, stats.Descent, 1e-9)
+			assert.Equal(t, tc.expectedMin, stats.Min)
+			assert.Equal(t, tc.expectedMax, stats.Max)
+			if tc.expectedProfile != nil {
+				assert.Equal(t, len(tc.expectedProfile), len(stats.Profile))
+				for j, p := range tc.expectedProfile {
+					assertInDelta(t, p.Distance, stats.Profile[j].Distance, 1e-6)
+					assertInDelta(t, p.Ele, stats.Profile[j].Ele, 1e-9)
+				}
+			}
+		})
+	}
+}
+
+func TestRteTypeElevationStats(t *testing.T) {
+	stats := (&gpx.RteType{
+		RtePt: []*gpx.WptType{
+			{Ele: 10},
+			{Lat: 0.001},
+			{Lat: 0.002, Ele: 30},
+		},
+	}).ElevationStats()
+	assert.Equal(t, 20.0, stats.Ascent)
+	assert.Equal(t, 0.0, stats.Descent)
+	assert.Equal(t, 10.0, stats.Min)
+	assert.Equal(t, 30.0, stats.Max)
+	assert.Equal(t, 2, len(stats.Profile))
+	assertInDelta(t, 0.002*metersPerDegree, stats.Profile[1].Distance, 1e-6)
+}
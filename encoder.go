@@ -0,0 +1,376 @@
+package gpx
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var (
+	errEncoderClosed     = errors.New("encoder closed")
+	errEncoderNotStarted = errors.New("encoder not started")
+	errEncoderStarted    = errors.New("encoder already started")
+	errNotInTrk          = errors.New("not in trk")
+	errNotInTrkSeg       = errors.New("not in trkseg")
+	errInTrk             = errors.New("in trk")
+	errOutOfOrder        = errors.New("out of order")
+	errUndeclaredPrefix  = errors.New("undeclared namespace prefix")
+)
+
+// An encoderPhase is the kind of element that an Encoder is writing. GPX
+// requires all waypoints to be written before all routes, and all routes
+// before all tracks.
+type encoderPhase int
+
+const (
+	encoderPhaseWpt encoderPhase = iota
+	encoderPhaseRte
+	encoderPhaseTrk
+)
+
+// An Encoder writes a GPX document incrementally. Waypoints, routes, and
+// tracks must be written in that order. As the root element is written by
+// Start, the namespaces used by the extensions of elements written later must
+// already be declared, either in the XMLAttrs of the GPX passed to Start or by
+// the extensions of its elements.
+type Encoder struct {
+	e          *xml.Encoder
+	o          *writeOptions
+	root       *GPX
+	namespaces map[string]string
+	phase      encoderPhase
+	trk        *TrkType
+	trkSeg     *TrkSegType
+	gpx10      bool
+	closed     bool
+}
+
+// NewEncoder returns a new Encoder that writes to w. options are applied to
+// the GPX passed to Start, and typed extension values of all elements are
+// encoded with the registry passed to WithWriteExtensionRegistry.
+func NewEncoder(w io.Writer, options ...WriteOption) *Encoder {
+	return &Encoder{
+		e: xml.NewEncoder(w),
+		o: newWriteOptions(options),
+	}
+}
+
+// Indent sets the encoder to generate XML in which each element begins on a
+// new indented line, as xml.Encoder.Indent.
+func (e *Encoder) Indent(prefix, indent string) {
+	e.e.Indent(prefix, indent)
+}
+
+// Start writes the root element of g followed by any metadata, waypoints,
+// routes, and tracks that g already contains.
+func (e *Encoder) Start(g *GPX) error {
+	switch {
+	case e.closed:
+		return errEncoderClosed
+	case e.root != nil:
+		return errEncoderStarted
+	}
+	encoded, err := g.withWriteOptions(e.o)
+	if err != nil {
+		return err
+	}
+	e.root = g
+	g = encoded
+	e.gpx10 = g.Version == "1.0"
+	switch {
+	case len(g.Trk) > 0:
+		e.phase = encoderPhaseTrk
+	case len(g.Rte) > 0:
+		e.phase = encoderPhaseRte
+	}
+	start := g.startElement()
+	e.namespaces = make(map[string]string)
+	for _, attr := range start.Attr {
+		if prefix, ok := strings.CutPrefix(attr.Name.Local, "xmlns:"); ok {
+			e.namespaces[prefix] = attr.Value
+		}
+	}
+	if err := e.e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := g.encodeBody(e.e); err != nil {
+		return err
+	}
+	return e.e.Flush()
+}
+
+// EncodeWpt writes w as a waypoint.
+func (e *Encoder) EncodeWpt(w *WptType) error {
+	w, err := w.mapExtensions(e.encodeTyped)
+	if err != nil {
+		return err
+	}
+	if err := e.checkPhase(encoderPhaseWpt, &GPX{Wpt: []*WptType{w}}); err != nil {
+		return err
+	}
+	if err := e.encodeWpt(w, "wpt"); err != nil {
+		return err
+	}
+	return e.e.Flush()
+}
+
+// EncodeRte writes r as a route.
+func (e *Encoder) EncodeRte(r *RteType) error {
+	r, err := r.mapExtensions(e.encodeTyped)
+	if err != nil {
+		return err
+	}
+	if err := e.checkPhase(encoderPhaseRte, &GPX{Rte: []*RteType{r}}); err != nil {
+		return err
+	}
+	if e.gpx10 {
+		err = r.encodeGPX10(e.e)
+	} else {
+		err = e.e.EncodeElement(r, xml.StartElement{Name: xml.Name{Local: "rte"}})
+	}
+	if err != nil {
+		return err
+	}
+	return e.e.Flush()
+}
+
+// EncodeTrk writes t as a complete track.
+func (e *Encoder) EncodeTrk(t *TrkType) error {
+	t, err := t.mapExtensions(e.encodeTyped)
+	if err != nil {
+		return err
+	}
+	if err := e.checkPhase(encoderPhaseTrk, &GPX{Trk: []*TrkType{t}}); err != nil {
+		return err
+	}
+	if e.gpx10 {
+		err = t.encodeGPX10(e.e)
+	} else {
+		err = e.e.EncodeElement(t, xml.StartElement{Name: xml.Name{Local: "trk"}})
+	}
+	if err != nil {
+		return err
+	}
+	return e.e.Flush()
+}
+
+// StartTrk opens a new track and writes all of t's fields except its
+// segments.
+func (e *Encoder) StartTrk(t *TrkType) error {
+	t, err := t.mapExtensions(e.encodeTyped)
+	if err != nil {
+		return err
+	}
+	if err := e.checkPhase(encoderPhaseTrk, &GPX{Trk: []*TrkType{t}}); err != nil {
+		return err
+	}
+	e.trk = t
+	if err := e.e.EncodeToken(xml.StartElement{Name: xml.Name{Local: "trk"}}); err != nil {
+		return err
+	}
+	if e.gpx10 {
+		err = t.encodeGPX10Header(e.e)
+	} else {
+		err = t.encodeHeader(e.e)
+	}
+	if err != nil {
+		return err
+	}
+	return e.e.Flush()
+}
+
+// StartTrkSeg opens a new segment in the current track. ts's points, if any,
+// are written immediately and its extensions are written when the segment is
+// ended.
+func (e *Encoder) StartTrkSeg(ts *TrkSegType) error {
+	switch {
+	case e.trk == nil:
+		return errNotInTrk
+	case e.trkSeg != nil:
+		if err := e.EndTrkSeg(); err != nil {
+			return err
+		}
+	}
+	encoded, err := ts.mapExtensions(e.encodeTyped)
+	if err != nil {
+		return err
+	}
+	if err := e.checkNamespaces(&GPX{Trk: []*TrkType{{TrkSeg: []*TrkSegType{encoded}}}}); err != nil {
+		return err
+	}
+	e.trkSeg = ts
+	if err := e.e.EncodeToken(xml.StartElement{Name: xml.Name{Local: "trkseg"}}); err != nil {
+		return err
+	}
+	for _, trkPt := range encoded.TrkPt {
+		if err := e.encodeWpt(trkPt, "trkpt"); err != nil {
+			return err
+		}
+	}
+	return e.e.Flush()
+}
+
+// EncodeTrkPt writes w as a point in the current segment.
+func (e *Encoder) EncodeTrkPt(w *WptType) error {
+	if e.trkSeg == nil {
+		return errNotInTrkSeg
+	}
+	w, err := w.mapExtensions(e.encodeTyped)
+	if err != nil {
+		return err
+	}
+	if err := e.checkNamespaces(&GPX{Wpt: []*WptType{w}}); err != nil {
+		return err
+	}
+	if err := e.encodeWpt(w, "trkpt"); err != nil {
+		return err
+	}
+	return e.e.Flush()
+}
+
+// EndTrkSeg closes the current segment.
+func (e *Encoder) EndTrkSeg() error {
+	if e.trkSeg == nil {
+		return errNotInTrkSeg
+	}
+	if e.trkSeg.Extensions != nil && !e.gpx10 {
+		extensions, err := e.encodeTyped(e.trkSeg.Extensions)
+		if err != nil {
+			return err
+		}
+		if err := e.e.EncodeElement(extensions, xml.StartElement{Name: xml.Name{Local: "extensions"}}); err != nil {
+			return err
+		}
+	}
+	e.trkSeg = nil
+	if err := e.e.EncodeToken(xml.EndElement{Name: xml.Name{Local: "trkseg"}}); err != nil {
+		return err
+	}
+	return e.e.Flush()
+}
+
+// EndTrk closes the current segment, if any, and the current track.
+func (e *Encoder) EndTrk() error {
+	if e.trk == nil {
+		return errNotInTrk
+	}
+	if e.trkSeg != nil {
+		if err := e.EndTrkSeg(); err != nil {
+			return err
+		}
+	}
+	e.trk = nil
+	if err := e.e.EncodeToken(xml.EndElement{Name: xml.Name{Local: "trk"}}); err != nil {
+		return err
+	}
+	return e.e.Flush()
+}
+
+// Close closes any open segment and track, writes the root extensions, closes
+// the root element, and flushes all output. It does not close the underlying io.Writer.
+func (e *Encoder) Close() error {
+	switch {
+	case e.closed:
+		return errEncoderClosed
+	case e.root == nil:
+		return errEncoderNotStarted
+	}
+	if e.trk != nil {
+		if err := e.EndTrk(); err != nil {
+			return err
+		}
+	}
+	e.closed = true
+	extensions, err := e.encodeTyped(e.root.Extensions)
+	if err != nil {
+		return err
+	}
+	root := &GPX{
+		Version:    e.root.Version,
+		Extensions: extensions,
+	}
+	if err := root.encodeExtensions(e.e); err != nil {
+		return err
+	}
+	if err := e.e.EncodeToken(xml.EndElement{Name: xml.Name{Local: "gpx"}}); err != nil {
+		return err
+	}
+	return e.e.Close()
+}
+
+func (e *Encoder) encodeWpt(w *WptType, localName string) error {
+	if e.gpx10 {
+		return w.encodeGPX10(e.e, localName)
+	}
+	return e.e.EncodeElement(w, xml.StartElement{Name: xml.Name{Local: localName}})
+}
+
+// encodeTyped returns x with its typed values encoded with the registry passed
+// to NewEncoder.
+func (e *Encoder) encodeTyped(x *ExtensionsType) (*ExtensionsType, error) {
+	return x.encodeTyped(e.o.extensionRegistry)
+}
+
+// checkPhase checks that g, containing a single element of the kind
+// written in phase, can be written next.
+func (e *Encoder) checkPhase(phase encoderPhase, g *GPX) error {
+	switch {
+	case e.closed:
+		return errEncoderClosed
+	case e.root == nil:
+		return errEncoderNotStarted
+	case e.trk != nil:
+		return errInTrk
+	case phase < e.phase:
+		return errOutOfOrder
+	}
+	if err := e.checkNamespaces(g); err != nil {
+		return err
+	}
+	e.phase = phase
+	return nil
+}
+
+// checkNamespaces checks that all namespace prefixes used by the extensions in
+// g are declared on the root element.
+func (e *Encoder) checkNamespaces(g *GPX) error {
+	for prefix, namespace := range g.extensionNamespaces() {
+		if e.namespaces[prefix] != namespace {
+			return fmt.Errorf("%s: %w", prefix, errUndeclaredPrefix)
+		}
+	}
+	return nil
+}
+
+// encodeHeader writes all of t's child elements except its segments.
+func (t *TrkType) encodeHeader(e *xml.Encoder) error {
+	if err := maybeEmitStringElement(e, "name", t.Name); err != nil {
+		return err
+	}
+	if err := maybeEmitStringElement(e, "cmt", t.Cmt); err != nil {
+		return err
+	}
+	if err := maybeEmitStringElement(e, "desc", t.Desc); err != nil {
+		return err
+	}
+	if err := maybeEmitStringElement(e, "src", t.Src); err != nil {
+		return err
+	}
+	if err := e.EncodeElement(t.Link, xml.StartElement{Name: xml.Name{Local: "link"}}); err != nil {
+		return err
+	}
+	if err := maybeEmitIntElement(e, "number", t.Number); err != nil {
+		return err
+	}
+	if err := maybeEmitStringElement(e, "type", t.Type); err != nil {
+		return err
+	}
+	if t.Extensions != nil {
+		if err := e.EncodeElement(t.Extensions, xml.StartElement{Name: xml.Name{Local: "extensions"}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package gpx_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	gpx "github.com/twpayne/go-gpx"
+)
+
+func TestEncoder(t *testing.T) {
+	wpt := &gpx.WptType{
+		Lat:  42.438878,
+		Lon:  -71.119277,
+		Name: "5066",
+	}
+	trkPts := []*gpx.WptType{
+		{
+			Lat:     47.644548,
+			Lon:     -122.326897,
+			Ele:     4.46,
+			Time:    time.Date(2009, 10, 17, 18, 37, 26, 0, time.UTC),
+			Present: gpx.WptEle,
+		},
+		{
+			Lat:     47.644548,
+			Lon:     -122.326897,
+			Ele:     4.94,
+			Time:    time.Date(2009, 10, 17, 18, 37, 31, 0, time.UTC),
+			Present: gpx.WptEle,
+		},
+	}
+	g := &gpx.GPX{
+		Version: "1.1",
+		Creator: "ExpertGPS 1.1 - http://www.topografix.com",
+		Metadata: &gpx.MetadataType{
+			Name: "name",
+		},
+	}
+
+	sb := &strings.Builder{}
+	e := gpx.NewEncoder(sb)
+	e.Indent("", "\t")
+	assert.NoError(t, e.Start(g))
+	assert.NoError(t, e.EncodeWpt(wpt))
+	assert.NoError(t, e.StartTrk(&gpx.TrkType{Name: "trk", Number: 1}))
+	assert.Error(t, e.EncodeWpt(wpt))
+	assert.Error(t, e.EncodeTrkPt(trkPts[0]))
+	assert.NoError(t, e.StartTrkSeg(&gpx.TrkSegType{}))
+	for _, trkPt := range trkPts {
+		assert.NoError(t, e.EncodeTrkPt(trkPt))
+	}
+	assert.NoError(t, e.StartTrkSeg(&gpx.TrkSegType{TrkPt: trkPts[:1]}))
+	assert.NoError(t, e.Close())
+	assert.Error(t, e.Close())
+
+	expected := &strings.Builder{}
+	assert.NoError(t, (&gpx.GPX{
+		Version:  g.Version,
+		Creator:  g.Creator,
+		Metadata: g.Metadata,
+		Wpt:      []*gpx.WptType{wpt},
+		Trk: []*gpx.TrkType{
+			{
+				Name:   "trk",
+				Number: 1,
+				TrkSeg: []*gpx.TrkSegType{
+					{TrkPt: trkPts},
+					{TrkPt: trkPts[:1]},
+				},
+			},
+		},
+	}).WriteIndent(expected, "", "\t"))
+	assert.Equal(t, strings.Split(expected.String(), "\n"), strings.Split(sb.String(), "\n"))
+}
+
+func TestEncoderNotStarted(t *testing.T) {
+	e := gpx.NewEncoder(&strings.Builder{})
+	assert.Error(t, e.EncodeWpt(&gpx.WptType{}))
+	assert.Error(t, e.StartTrk(&gpx.TrkType{}))
+	assert.Error(t, e.Close())
+}
+
+func TestEncoderOrder(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		g      *gpx.GPX
+		encode func(*gpx.Encoder) error
+	}{
+		{
+			name: "wpt_after_rte",
+			g:    &gpx.GPX{Version: "1.1"},
+			encode: func(e *gpx.Encoder) error {
+				if err := e.EncodeRte(&gpx.RteType{}); err != nil {
+					return err
+				}
+				return e.EncodeWpt(&gpx.WptType{})
+			},
+		},
+		{
+			name: "rte_after_trk",
+			g:    &gpx.GPX{Version: "1.1"},
+			encode: func(e *gpx.Encoder) error {
+				if err := e.EncodeTrk(&gpx.TrkType{}); err != nil {
+					return err
+				}
+				return e.EncodeRte(&gpx.RteType{})
+			},
+		},
+		{
+			name: "wpt_after_start_with_trk",
+			g:    &gpx.GPX{Version: "1.1", Trk: []*gpx.TrkType{{}}},
+			encode: func(e *gpx.Encoder) error {
+				return e.EncodeWpt(&gpx.WptType{})
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			e := gpx.NewEncoder(&strings.Builder{})
+			assert.NoError(t, e.Start(tc.g))
+			assert.EqualError(t, tc.encode(e), "out of order")
+		})
+	}
+}
+
+func TestEncoderNamespaces(t *testing.T) {
+	trkPt := &gpx.WptType{Lat: 1, Lon: 2}
+	assert.NoError(t, trkPt.SetTrackPointExtension(&gpx.TrackPointExtension{Version: 1, HR: 120}))
+
+	e := gpx.NewEncoder(&strings.Builder{})
+	assert.NoError(t, e.Start(&gpx.GPX{Version: "1.1"}))
+	assert.NoError(t, e.StartTrk(&gpx.TrkType{}))
+	assert.NoError(t, e.StartTrkSeg(&gpx.TrkSegType{}))
+	assert.EqualError(t, e.EncodeTrkPt(trkPt), "gpxtpx: undeclared namespace prefix")
+
+	sb := &strings.Builder{}
+	e = gpx.NewEncoder(sb)
+	assert.NoError(t, e.Start(&gpx.GPX{
+		Version: "1.1",
+		XMLAttrs: map[string]string{
+			"xmlns:gpxtpx": gpx.TrackPointExtensionV1Namespace,
+		},
+	}))
+	assert.NoError(t, e.StartTrk(&gpx.TrkType{}))
+	assert.NoError(t, e.StartTrkSeg(&gpx.TrkSegType{}))
+	assert.NoError(t, e.EncodeTrkPt(trkPt))
+	assert.NoError(t, e.Close())
+	assert.Contains(t, sb.String(), `xmlns:gpxtpx="http://www.garmin.com/xmlschemas/TrackPointExtension/v1"`)
+}
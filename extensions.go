@@ -0,0 +1,544 @@
+package gpx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"iter"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// An extensionElement is the location of a top-level element in an
+// ExtensionsType's XML.
+type extensionElement struct {
+	start      xml.StartElement
+	namespace  string
+	startIndex int64
+	endIndex   int64
+}
+
+// allExtensions returns an iterator over all non-nil extensions in g.
+func (g *GPX) allExtensions() iter.Seq[*ExtensionsType] {
+	return func(yield func(*ExtensionsType) bool) {
+		visit := func(extensions *ExtensionsType) bool {
+			return extensions == nil || yield(extensions)
+		}
+		if !visit(g.Extensions) {
+			return
+		}
+		if g.Metadata != nil && !visit(g.Metadata.Extensions) {
+			return
+		}
+		for _, wpt := range g.Wpt {
+			if !visit(wpt.Extensions) {
+				return
+			}
+		}
+		for _, rte := range g.Rte {
+			if !visit(rte.Extensions) {
+				return
+			}
+			for _, rtePt := range rte.RtePt {
+				if !visit(rtePt.Extensions) {
+					return
+				}
+			}
+		}
+		for _, trk := range g.Trk {
+			if !visit(trk.Extensions) {
+				return
+			}
+			for _, trkSeg := range trk.TrkSeg {
+				if !visit(trkSeg.Extensions) {
+					return
+				}
+				for _, trkPt := range trkSeg.TrkPt {
+					if !visit(trkPt.Extensions) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// An extensionsFunc returns a replacement for x, which may be nil, or x itself
+// if x is unchanged.
+type extensionsFunc func(x *ExtensionsType) (*ExtensionsType, error)
+
+// mapExtensions returns g with all extensions replaced by f. Only the parts of
+// g that contain replaced extensions are copied.
+func (g *GPX) mapExtensions(f extensionsFunc) (*GPX, error) {
+	gCopy := *g
+	var err error
+	if gCopy.Extensions, err = f(g.Extensions); err != nil {
+		return nil, err
+	}
+	if g.Metadata != nil {
+		extensions, err := f(g.Metadata.Extensions)
+		if err != nil {
+			return nil, err
+		}
+		if extensions != g.Metadata.Extensions {
+			metadata := *g.Metadata
+			metadata.Extensions = extensions
+			gCopy.Metadata = &metadata
+		}
+	}
+	if gCopy.Wpt, _, err = mapExtensions(g.Wpt, f); err != nil {
+		return nil, err
+	}
+	if gCopy.Rte, _, err = mapExtensions(g.Rte, f); err != nil {
+		return nil, err
+	}
+	if gCopy.Trk, _, err = mapExtensions(g.Trk, f); err != nil {
+		return nil, err
+	}
+	return &gCopy, nil
+}
+
+// mapExtensions returns r with all extensions replaced by f.
+func (r *RteType) mapExtensions(f extensionsFunc) (*RteType, error) {
+	extensions, err := f(r.Extensions)
+	if err != nil {
+		return nil, err
+	}
+	rtePt, changed, err := mapExtensions(r.RtePt, f)
+	if err != nil {
+		return nil, err
+	}
+	if extensions == r.Extensions && !changed {
+		return r, nil
+	}
+	rCopy := *r
+	rCopy.Extensions = extensions
+	rCopy.RtePt = rtePt
+	return &rCopy, nil
+}
+
+// mapExtensions returns t with all extensions replaced by f.
+func (t *TrkType) mapExtensions(f extensionsFunc) (*TrkType, error) {
+	extensions, err := f(t.Extensions)
+	if err != nil {
+		return nil, err
+	}
+	trkSeg, changed, err := mapExtensions(t.TrkSeg, f)
+	if err != nil {
+		return nil, err
+	}
+	if extensions == t.Extensions && !changed {
+		return t, nil
+	}
+	tCopy := *t
+	tCopy.Extensions = extensions
+	tCopy.TrkSeg = trkSeg
+	return &tCopy, nil
+}
+
+// mapExtensions returns ts with all extensions replaced by f.
+func (ts *TrkSegType) mapExtensions(f extensionsFunc) (*TrkSegType, error) {
+	extensions, err := f(ts.Extensions)
+	if err != nil {
+		return nil, err
+	}
+	trkPt, changed, err := mapExtensions(ts.TrkPt, f)
+	if err != nil {
+		return nil, err
+	}
+	if extensions == ts.Extensions && !changed {
+		return ts, nil
+	}
+	tsCopy := *ts
+	tsCopy.Extensions = extensions
+	tsCopy.TrkPt = trkPt
+	return &tsCopy, nil
+}
+
+// mapExtensions returns w with its extensions replaced by f.
+func (w *WptType) mapExtensions(f extensionsFunc) (*WptType, error) {
+	extensions, err := f(w.Extensions)
+	if err != nil {
+		return nil, err
+	}
+	if extensions == w.Extensions {
+		return w, nil
+	}
+	wCopy := *w
+	wCopy.Extensions = extensions
+	return &wCopy, nil
+}
+
+// mapExtensions returns values with all extensions replaced by f, and whether
+// any value was changed. values is only copied if a value is changed.
+func mapExtensions[T interface {
+	comparable
+	mapExtensions(f extensionsFunc) (T, error)
+}](values []T, f extensionsFunc) ([]T, bool, error) {
+	var result []T
+	for i, value := range values {
+		mapped, err := value.mapExtensions(f)
+		if err != nil {
+			return nil, false, err
+		}
+		if mapped != value && result == nil {
+			result = slices.Clone(values)
+		}
+		if result != nil {
+			result[i] = mapped
+		}
+	}
+	if result == nil {
+		return values, false, nil
+	}
+	return result, true, nil
+}
+
+// extensionNamespaces returns the namespace prefixes used by all extensions
+// in g. Typed values must already have been encoded.
+func (g *GPX) extensionNamespaces() map[string]string {
+	var namespaces map[string]string
+	for extensions := range g.allExtensions() {
+		for prefix, namespace := range extensions.Namespaces {
+			if _, ok := namespaces[prefix]; ok {
+				continue
+			}
+			if namespaces == nil {
+				namespaces = make(map[string]string)
+			}
+			namespaces[prefix] = namespace
+		}
+	}
+	return namespaces
+}
+
+// decodeElement decodes the first top-level element of x with local name local
+// in one of namespaces into v. It returns the namespace of the element and
+// whether the element was found.
+func (x *ExtensionsType) decodeElement(namespaces []string, local string, v any) (string, bool, error) {
+	if x == nil {
+		return "", false, nil
+	}
+	d := xml.NewDecoder(bytes.NewReader(x.XML))
+	for {
+		token, err := d.Token()
+		switch {
+		case errors.Is(err, io.EOF):
+			return "", false, nil
+		case err != nil:
+			return "", false, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		namespace := x.namespace(start.Name)
+		if start.Name.Local != local || !slices.Contains(namespaces, namespace) {
+			if err := d.Skip(); err != nil {
+				return "", false, err
+			}
+			continue
+		}
+		if err := d.DecodeElement(v, &start); err != nil {
+			return "", false, err
+		}
+		return namespace, true, nil
+	}
+}
+
+// elements returns all top-level elements in x.
+func (x *ExtensionsType) elements() ([]*extensionElement, error) {
+	if x == nil {
+		return nil, nil
+	}
+	var elements []*extensionElement
+	d := xml.NewDecoder(bytes.NewReader(x.XML))
+	for {
+		startIndex := d.InputOffset()
+		token, err := d.Token()
+		switch {
+		case errors.Is(err, io.EOF):
+			return elements, nil
+		case err != nil:
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if err := d.Skip(); err != nil {
+			return nil, err
+		}
+		elements = append(elements, &extensionElement{
+			start:      start.Copy(),
+			namespace:  x.namespace(start.Name),
+			startIndex: startIndex,
+			endIndex:   d.InputOffset(),
+		})
+	}
+}
+
+// namespace returns the namespace of name, resolving prefixes declared
+// outside x.
+func (x *ExtensionsType) namespace(name xml.Name) string {
+	if namespace, ok := x.Namespaces[name.Space]; ok {
+		return namespace
+	}
+	return name.Space
+}
+
+// prefix returns the prefix for namespace in x, declaring it with a prefix
+// derived from preferredPrefix if needed.
+func (x *ExtensionsType) prefix(namespace, preferredPrefix string) string {
+	for _, prefix := range slices.Sorted(maps.Keys(x.Namespaces)) {
+		if x.Namespaces[prefix] == namespace {
+			return prefix
+		}
+	}
+	prefix := preferredPrefix
+	for i := 2; ; i++ {
+		if _, ok := x.Namespaces[prefix]; !ok {
+			break
+		}
+		prefix = preferredPrefix + strconv.Itoa(i)
+	}
+	namespaces := maps.Clone(x.Namespaces)
+	if namespaces == nil {
+		namespaces = make(map[string]string)
+	}
+	namespaces[prefix] = namespace
+	x.Namespaces = namespaces
+	return prefix
+}
+
+// renamePrefixes returns a copy of x with the namespace prefixes in renames,
+// a map of old prefixes to new prefixes, renamed in its XML and Namespaces. If
+// x is nil or renames is empty then x is returned.
+func (x *ExtensionsType) renamePrefixes(renames map[string]string) (*ExtensionsType, error) {
+	if x == nil || len(renames) == 0 {
+		return x, nil
+	}
+	data, err := renameXMLPrefixes(x.XML, renames)
+	if err != nil {
+		return nil, err
+	}
+	namespaces := make(map[string]string, len(x.Namespaces))
+	for prefix, namespace := range x.Namespaces {
+		if newPrefix, ok := renames[prefix]; ok {
+			prefix = newPrefix
+		}
+		namespaces[prefix] = namespace
+	}
+	return &ExtensionsType{
+		XML:        data,
+		Namespaces: namespaces,
+		Typed:      x.Typed,
+	}, nil
+}
+
+// removeElements removes all top-level elements of x with local name local
+// in one of namespaces. It returns the offset in x.XML of the first removed
+// element, or the length of x.XML if there was no such element.
+func (x *ExtensionsType) removeElements(namespaces []string, local string) (int, error) {
+	elements, err := x.elements()
+	if err != nil {
+		return 0, err
+	}
+	var xmlData []byte
+	var offset int64
+	index := -1
+	for _, element := range elements {
+		if element.start.Name.Local != local || !slices.Contains(namespaces, element.namespace) {
+			continue
+		}
+		xmlData = append(xmlData, x.XML[offset:element.startIndex]...)
+		if index < 0 {
+			index = len(xmlData)
+		}
+		offset = element.endIndex
+	}
+	if index < 0 {
+		return len(x.XML), nil
+	}
+	x.XML = append(xmlData, x.XML[offset:]...)
+	return index, nil
+}
+
+// usesPrefix returns whether prefix is used by any element or attribute in
+// x.XML.
+func (x *ExtensionsType) usesPrefix(prefix string) (bool, error) {
+	d := xml.NewDecoder(io.MultiReader(
+		strings.NewReader("<extensions>"),
+		bytes.NewReader(x.XML),
+		strings.NewReader("</extensions>"),
+	))
+	for {
+		token, err := d.RawToken()
+		switch {
+		case errors.Is(err, io.EOF):
+			return false, nil
+		case err != nil:
+			return false, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Space == prefix {
+			return true, nil
+		}
+		for _, attr := range start.Attr {
+			if attr.Name.Space == prefix {
+				return true, nil
+			}
+		}
+	}
+}
+
+// removeUnusedPrefixes removes the prefixes of namespaces that are not used in
+// x.XML from x.Namespaces and returns the removed prefixes.
+func (x *ExtensionsType) removeUnusedPrefixes(namespaces []string) ([]string, error) {
+	var removed []string
+	for _, prefix := range slices.Sorted(maps.Keys(x.Namespaces)) {
+		if !slices.Contains(namespaces, x.Namespaces[prefix]) {
+			continue
+		}
+		switch used, err := x.usesPrefix(prefix); {
+		case err != nil:
+			return nil, err
+		case !used:
+			removed = append(removed, prefix)
+		}
+	}
+	if len(removed) > 0 {
+		x.Namespaces = maps.Clone(x.Namespaces)
+		for _, prefix := range removed {
+			delete(x.Namespaces, prefix)
+		}
+	}
+	return removed, nil
+}
+
+// setElement replaces the elements of e with local name local in one of
+// namespaces with the element encoded by encode, creating *e if needed. If
+// encode is nil then the elements are removed. Prefixes of namespaces that are
+// no longer used are removed, and are reused if encode uses the same
+// namespace.
+func setElement(e **ExtensionsType, namespaces []string, local string, encode func(*xml.Encoder, *ExtensionsType) error) error {
+	if *e == nil {
+		if encode == nil {
+			return nil
+		}
+		*e = &ExtensionsType{}
+	}
+	x := *e
+	previousNamespaces := x.Namespaces
+	index, err := x.removeElements(namespaces, local)
+	if err != nil {
+		return err
+	}
+	removed, err := x.removeUnusedPrefixes(namespaces)
+	if err != nil {
+		return err
+	}
+	if encode == nil {
+		return nil
+	}
+	remainingNamespaces := x.Namespaces
+	data, err := x.encodeElement(encode)
+	if err != nil {
+		return err
+	}
+	for _, prefix := range removed {
+		namespace := previousNamespaces[prefix]
+		if _, ok := x.Namespaces[prefix]; ok || !slices.Contains(slices.Collect(maps.Values(x.Namespaces)), namespace) {
+			continue
+		}
+		x.Namespaces = maps.Clone(remainingNamespaces)
+		if x.Namespaces == nil {
+			x.Namespaces = make(map[string]string)
+		}
+		x.Namespaces[prefix] = namespace
+		if data, err = x.encodeElement(encode); err != nil {
+			return err
+		}
+		break
+	}
+	x.XML = slices.Concat(x.XML[:index], data, x.XML[index:])
+	return nil
+}
+
+// encodeElement returns the element encoded by encode.
+func (x *ExtensionsType) encodeElement(encode func(*xml.Encoder, *ExtensionsType) error) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	xmlEncoder := xml.NewEncoder(buffer)
+	if err := encode(xmlEncoder, x); err != nil {
+		return nil, err
+	}
+	if err := xmlEncoder.Flush(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// renameXMLPrefixes returns data, an XML fragment, with the namespace prefixes
+// in renames renamed in all element names, attribute names, and namespace
+// declarations.
+func renameXMLPrefixes(data []byte, renames map[string]string) ([]byte, error) {
+	rename := func(name xml.Name) xml.Name {
+		switch {
+		case name.Space == "xmlns":
+			if newPrefix, ok := renames[name.Local]; ok {
+				return xml.Name{Local: "xmlns:" + newPrefix}
+			}
+			return xml.Name{Local: "xmlns:" + name.Local}
+		case name.Space != "":
+			prefix := name.Space
+			if newPrefix, ok := renames[prefix]; ok {
+				prefix = newPrefix
+			}
+			return xml.Name{Local: prefix + ":" + name.Local}
+		default:
+			return name
+		}
+	}
+	d := xml.NewDecoder(bytes.NewReader(data))
+	buffer := &bytes.Buffer{}
+	e := xml.NewEncoder(buffer)
+	for {
+		token, err := d.RawToken()
+		switch {
+		case errors.Is(err, io.EOF):
+			if err := e.Flush(); err != nil {
+				return nil, err
+			}
+			return buffer.Bytes(), nil
+		case err != nil:
+			return nil, err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			start := xml.StartElement{
+				Name: rename(token.Name),
+				Attr: make([]xml.Attr, 0, len(token.Attr)),
+			}
+			for _, attr := range token.Attr {
+				start.Attr = append(start.Attr, xml.Attr{
+					Name:  rename(attr.Name),
+					Value: attr.Value,
+				})
+			}
+			err = e.EncodeToken(start)
+		case xml.EndElement:
+			err = e.EncodeToken(xml.EndElement{Name: rename(token.Name)})
+		default:
+			err = e.EncodeToken(token)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package gpx
+
+import "math"
+
+// defaultMeasurementNoise is the default standard deviation of position
+// measurements in meters.
+const defaultMeasurementNoise = 5
+
+// defaultProcessNoise is the default standard deviation of acceleration in
+// meters per second squared.
+const defaultProcessNoise = 1
+
+// A FilterOption sets an option for FilterOutliers.
+type FilterOption func(*filterOptions)
+
+type filterOptions struct {
+	maxSpeed        float64
+	maxAcceleration float64
+	maxHDOP         float64
+	minSat          int
+	requireFix      bool
+}
+
+// A SmoothOption sets an option for Smooth.
+type SmoothOption func(*smoothOptions)
+
+type smoothOptions struct {
+	measurementNoise float64
+	processNoise     float64
+	qualityWeights   bool
+}
+
+// kalmanState is the state of a one-dimensional constant velocity Kalman
+// filter: a position, a velocity, and their covariance matrix.
+type kalmanState struct {
+	x [2]float64
+	p [2][2]float64
+}
+
+// WithMaxSpeed drops points that imply a speed greater than maxSpeed meters
+// per second from the previous kept point.
+func WithMaxSpeed(maxSpeed float64) FilterOption {
+	return func(o *filterOptions) {
+		o.maxSpeed = maxSpeed
+	}
+}
+
+// WithMaxAcceleration drops points that imply an acceleration greater than
+// maxAcceleration meters per second squared from the previous kept point.
+func WithMaxAcceleration(maxAcceleration float64) FilterOption {
+	return func(o *filterOptions) {
+		o.maxAcceleration = maxAcceleration
+	}
+}
+
+// WithMaxHDOP drops points with an HDOP greater than maxHDOP.
+func WithMaxHDOP(maxHDOP float64) FilterOption {
+	return func(o *filterOptions) {
+		o.maxHDOP = maxHDOP
+	}
+}
+
+// WithMinSat drops points with fewer than minSat satellites.
+func WithMinSat(minSat int) FilterOption {
+	return func(o *filterOptions) {
+		o.minSat = minSat
+	}
+}
+
+// WithRequireFix drops points with a fix of "none".
+func WithRequireFix() FilterOption {
+	return func(o *filterOptions) {
+		o.requireFix = true
+	}
+}
+
+// WithMeasurementNoise sets the standard deviation of position measurements in
+// meters. The default is 5m.
+func WithMeasurementNoise(measurementNoise float64) SmoothOption {
+	return func(o *smoothOptions) {
+		o.measurementNoise = measurementNoise
+	}
+}
+
+// WithProcessNoise sets the standard deviation of unmodeled acceleration in
+// meters per second squared. Larger values follow the measurements more
+// closely. The default is 1m/s².
+func WithProcessNoise(processNoise float64) SmoothOption {
+	return func(o *smoothOptions) {
+		o.processNoise = processNoise
+	}
+}
+
+// WithQualityWeights weights each point by its quality. The measurement noise
+// of a point is multiplied by its HDOP, if present, and points with a fix of
+// "none" are ignored.
+func WithQualityWeights() SmoothOption {
+	return func(o *smoothOptions) {
+		o.qualityWeights = true
+	}
+}
+
+// FilterOutliers returns a new TrkSegType containing ts's points without
+// points that fail the quality checks or that imply impossible speeds or
+// accelerations, as set by options. Speed and acceleration checks are
+// relative to the previous kept point and are skipped for points without a
+// time.
+func (ts *TrkSegType) FilterOutliers(options ...FilterOption) *TrkSegType {
+	var o filterOptions
+	for _, option := range options {
+		option(&o)
+	}
+	result := &TrkSegType{
+		Extensions: ts.Extensions,
+	}
+	var prev *WptType
+	prevSpeed := math.NaN()
+	for _, trkPt := range ts.TrkPt {
+		switch {
+		case o.maxHDOP > 0 && trkPt.Has(WptHDOP) && trkPt.HDOP > o.maxHDOP:
+			continue
+		case o.minSat > 0 && trkPt.Has(WptSat) && trkPt.Sat < o.minSat:
+			continue
+		case o.requireFix && trkPt.Fix == "none":
+			continue
+		}
+		if prev != nil && !prev.Time.IsZero() && !trkPt.Time.IsZero() {
+			dt := trkPt.Time.Sub(prev.Time).Seconds()
+			distance := prev.Distance(trkPt)
+			if dt <= 0 {
+				if distance > 0 {
+					continue
+				}
+			} else {
+				speed := distance / dt
+				if o.maxSpeed > 0 && speed > o.maxSpeed {
+					continue
+				}
+				if o.maxAcceleration > 0 && !math.IsNaN(prevSpeed) && math.Abs(speed-prevSpeed)/dt > o.maxAcceleration {
+					continue
+				}
+				prevSpeed = speed
+			}
+		}
+		result.TrkPt = append(result.TrkPt, trkPt)
+		prev = trkPt
+	}
+	return result
+}
+
+// Smooth returns a new TrkSegType with ts's positions smoothed by a constant
+// velocity Kalman filter followed by a Rauch-Tung-Striebel smoother. Each
+// point is a shallow copy of the original point with only Lat and Lon
+// changed. All points must have a time.
+func (ts *TrkSegType) Smooth(options ...SmoothOption) (*TrkSegType, error) {
+	o := smoothOptions{
+		measurementNoise: defaultMeasurementNoise,
+		processNoise:     defaultProcessNoise,
+	}
+	for _, option := range options {
+		option(&o)
+	}
+	for _, trkPt := range ts.TrkPt {
+		if trkPt.Time.IsZero() {
+			return nil, errMissingTime
+		}
+	}
+	result := &TrkSegType{
+		Extensions: ts.Extensions,
+	}
+	if len(ts.TrkPt) == 0 {
+		return result, nil
+	}
+
+	n := len(ts.TrkPt)
+	origin := ts.TrkPt[0]
+	xs := make([]float64, n)
+	ys := make([]float64, n)
+	dts := make([]float64, n)
+	variances := make([]float64, n)
+	for i, trkPt := range ts.TrkPt {
+		xs[i], ys[i] = localXY(origin, trkPt)
+		if i > 0 {
+			dts[i] = trkPt.Time.Sub(ts.TrkPt[i-1].Time).Seconds()
+		}
+		sigma := o.measurementNoise
+		if o.qualityWeights {
+			if trkPt.Has(WptHDOP) {
+				sigma *= trkPt.HDOP
+			}
+			if trkPt.Fix == "none" {
+				sigma = math.Inf(1)
+			}
+		}
+		variances[i] = sigma * sigma
+	}
+	q := o.processNoise * o.processNoise
+	xs = smoothAxis(xs, dts, variances, q)
+	ys = smoothAxis(ys, dts, variances, q)
+
+	latScale := math.Pi / 180 * earthRadius
+	lonScale := latScale * math.Cos(origin.Lat*math.Pi/180)
+	result.TrkPt = make([]*WptType, 0, n)
+	for i, trkPt := range ts.TrkPt {
+		wpt := *trkPt
+		wpt.Lat = origin.Lat + ys[i]/latScale
+		if lonScale != 0 {
+			wpt.Lon = math.Remainder(origin.Lon+xs[i]/lonScale, 360)
+		}
+		result.TrkPt = append(result.TrkPt, &wpt)
+	}
+	return result, nil
+}
+
+// smoothAxis returns zs smoothed by a one-dimensional constant velocity Kalman
+// filter and Rauch-Tung-Striebel smoother. dts are the times since the
+// previous measurements in seconds, variances are the measurement variances,
+// and q is the process noise variance.
+func smoothAxis(zs, dts, variances []float64, q float64) []float64 {
+	n := len(zs)
+	predicted := make([]kalmanState, n)
+	filtered := make([]kalmanState, n)
+	for i := range zs {
+		var s kalmanState
+		if i == 0 {
+			s = kalmanState{
+				x: [2]float64{zs[0], 0},
+				p: [2][2]float64{{variances[0], 0}, {0, 1e6}},
+			}
+			if math.IsInf(variances[0], 1) {
+				s.p[0][0] = 1e12
+			}
+		} else {
+			s = filtered[i-1].predict(dts[i], q)
+		}
+		predicted[i] = s
+		filtered[i] = s.update(zs[i], variances[i])
+	}
+	smoothed := make([]float64, n)
+	next := filtered[n-1]
+	smoothed[n-1] = next.x[0]
+	for i := n - 2; i >= 0; i-- {
+		next = filtered[i].smooth(predicted[i+1], next, dts[i+1])
+		smoothed[i] = next.x[0]
+	}
+	return smoothed
+}
+
+// predict returns the state predicted after dt seconds.
+func (s kalmanState) predict(dt, q float64) kalmanState {
+	p := s.p
+	return kalmanState{
+		x: [2]float64{s.x[0] + dt*s.x[1], s.x[1]},
+		p: [2][2]float64{
+			{
+				p[0][0] + dt*(p[1][0]+p[0][1]) + dt*dt*p[1][1] + q*dt*dt*dt*dt/4,
+				p[0][1] + dt*p[1][1] + q*dt*dt*dt/2,
+			},
+			{
+				p[1][0] + dt*p[1][1] + q*dt*dt*dt/2,
+				p[1][1] + q*dt*dt,
+			},
+		},
+	}
+}
+
+// update returns the state updated with a measurement z with variance r.
+func (s kalmanState) update(z, r float64) kalmanState {
+	if math.IsInf(r, 1) {
+		return s
+	}
+	p := s.p
+	innovation := p[0][0] + r
+	k0 := p[0][0] / innovation
+	k1 := p[1][0] / innovation
+	y := z - s.x[0]
+	return kalmanState{
+		x: [2]float64{s.x[0] + k0*y, s.x[1] + k1*y},
+		p: [2][2]float64{
+			{(1 - k0) * p[0][0], (1 - k0) * p[0][1]},
+			{p[1][0] - k1*p[0][0], p[1][1] - k1*p[0][1]},
+		},
+	}
+}
+
+// smooth returns the smoothed state given the filtered state s, the
+// predicted and smoothed next states, and the time to the next state.
+func (s kalmanState) smooth(predictedNext, smoothedNext kalmanState, dt float64) kalmanState {
+	// C = P F^T predictedNext.P^-1
+	pf := [2][2]float64{
+		{s.p[0][0] + dt*s.p[0][1], s.p[0][1]},
+		{s.p[1][0] + dt*s.p[1][1], s.p[1][1]},
+	}
+	pp := predictedNext.p
+	det := pp[0][0]*pp[1][1] - pp[0][1]*pp[1][0]
+	if det == 0 {
+		return s
+	}
+	inv := [2][2]float64{
+		{pp[1][1] / det, -pp[0][1] / det},
+		{-pp[1][0] / det, pp[0][0] / det},
+	}
+	c := [2][2]float64{
+		{pf[0][0]*inv[0][0] + pf[0][1]*inv[1][0], pf[0][0]*inv[0][1] + pf[0][1]*inv[1][1]},
+		{pf[1][0]*inv[0][0] + pf[1][1]*inv[1][0], pf[1][0]*inv[0][1] + pf[1][1]*inv[1][1]},
+	}
+	dx := [2]float64{smoothedNext.x[0] - predictedNext.x[0], smoothedNext.x[1] - predictedNext.x[1]}
+	dp := [2][2]float64{
+		{smoothedNext.p[0][0] - pp[0][0], smoothedNext.p[0][1] - pp[0][1]},
+		{smoothedNext.p[1][0] - pp[1][0], smoothedNext.p[1][1] - pp[1][1]},
+	}
+	// P = P + C dp C^T
+	cdp := [2][2]float64{
+		{c[0][0]*dp[0][0] + c[0][1]*dp[1][0], c[0][0]*dp[0][1] + c[0][1]*dp[1][1]},
+		{c[1][0]*dp[0][0] + c[1][1]*dp[1][0], c[1][0]*dp[0][1] + c[1][1]*dp[1][1]},
+	}
+	return kalmanState{
+		x: [2]float64{
+			s.x[0] + c[0][0]*dx[0] + c[0][1]*dx[1],
+			s.x[1] + c[1][0]*dx[0] + c[1][1]*dx[1],
+		},
+		p: [2][2]float64{
+			{s.p[0][0] + cdp[0][0]*c[0][0] + cdp[0][1]*c[0][1], s.p[0][1] + cdp[0][0]*c[1][0] + cdp[0][1]*c[1][1]},
+			{s.p[1][0] + cdp[1][0]*c[0][0] + cdp[1][1]*c[0][1], s.p[1][1] + cdp[1][0]*c[1][0] + cdp[1][1]*c[1][1]},
+		},
+	}
+}
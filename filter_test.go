@@ -0,0 +1,133 @@
+package gpx_test
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	gpx "github.com/twpayne/go-gpx"
+)
+
+func TestFilterOutliers(t *testing.T) {
+	t0 := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	newTrkPt := func(seconds int, lat, lon float64) *gpx.WptType {
+		return &gpx.WptType{
+			Lat:  lat,
+			Lon:  lon,
+			Time: t0.Add(time.Duration(seconds) * time.Second),
+		}
+	}
+
+	for i, tc := range []struct {
+		trkPts          []*gpx.WptType
+		options         []gpx.FilterOption
+		expectedIndexes []int
+	}{
+		{
+			trkPts: []*gpx.WptType{
+				newTrkPt(0, 0, 0),
+				newTrkPt(1, 0, 0.0001),
+				newTrkPt(2, 0.01, 0.0002),
+				newTrkPt(3, 0, 0.0003),
+			},
+			expectedIndexes: []int{0, 1, 2, 3},
+		},
+		{
+			trkPts: []*gpx.WptType{
+				newTrkPt(0, 0, 0),
+				newTrkPt(1, 0, 0.0001),
+				newTrkPt(2, 0.01, 0.0002),
+				newTrkPt(3, 0, 0.0003),
+			},
+			options:         []gpx.FilterOption{gpx.WithMaxSpeed(50)},
+			expectedIndexes: []int{0, 1, 3},
+		},
+		{
+			trkPts: []*gpx.WptType{
+				newTrkPt(0, 0, 0),
+				newTrkPt(1, 0, 0.0001),
+				newTrkPt(2, 0, 0.0004),
+				newTrkPt(3, 0, 0.0002),
+			},
+			options:         []gpx.FilterOption{gpx.WithMaxSpeed(50), gpx.WithMaxAcceleration(5)},
+			expectedIndexes: []int{0, 1, 3},
+		},
+		{
+			trkPts: []*gpx.WptType{
+				newTrkPt(0, 0, 0),
+				newTrkPt(0, 0, 0.0001),
+				newTrkPt(0, 0, 0),
+			},
+			expectedIndexes: []int{0, 2},
+		},
+		{
+			trkPts: []*gpx.WptType{
+				{HDOP: 1, Sat: 8},
+				{HDOP: 10, Sat: 8},
+				{HDOP: 1, Sat: 3},
+				{Fix: "none"},
+				{Fix: "3d"},
+			},
+			options:         []gpx.FilterOption{gpx.WithMaxHDOP(5), gpx.WithMinSat(4), gpx.WithRequireFix()},
+			expectedIndexes: []int{0, 4},
+		},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			expected := make([]*gpx.WptType, 0, len(tc.expectedIndexes))
+			for _, index := range tc.expectedIndexes {
+				expected = append(expected, tc.trkPts[index])
+			}
+			filtered := (&gpx.TrkSegType{TrkPt: tc.trkPts}).FilterOutliers(tc.options...)
+			assert.Equal(t, expected, filtered.TrkPt)
+		})
+	}
+}
+
+func TestSmooth(t *testing.T) {
+	t0 := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	noise := 5 / metersPerDegree
+	trkPts := make([]*gpx.WptType, 0, 60)
+	for i := range 60 {
+		lat := noise
+		if i%2 == 1 {
+			lat = -noise
+		}
+		trkPts = append(trkPts, &gpx.WptType{
+			Lat:     lat,
+			Lon:     0.0001 * float64(i),
+			Ele:     float64(i),
+			Time:    t0.Add(time.Duration(i) * time.Second),
+			Name:    strconv.Itoa(i),
+			Present: gpx.WptEle,
+		})
+	}
+	trkSeg := &gpx.TrkSegType{TrkPt: trkPts}
+
+	smoothed, err := trkSeg.Smooth()
+	assert.NoError(t, err)
+	assert.Equal(t, len(trkPts), len(smoothed.TrkPt))
+	for i, trkPt := range smoothed.TrkPt {
+		assert.True(t, trkPt != trkPts[i])
+		assert.Equal(t, trkPts[i].Ele, trkPt.Ele)
+		assert.Equal(t, trkPts[i].Time, trkPt.Time)
+		assert.Equal(t, trkPts[i].Name, trkPt.Name)
+		assert.True(t, math.Abs(trkPt.Lat) < noise*0.6, "point %d: %v", i, trkPt.Lat*metersPerDegree)
+		if 2 <= i && i < len(trkPts)-2 {
+			assert.True(t, math.Abs(trkPt.Lat) < noise/4, "point %d: %v", i, trkPt.Lat*metersPerDegree)
+		}
+		assertInDelta(t, trkPts[i].Lon, trkPt.Lon, 1/metersPerDegree)
+	}
+	assert.Equal(t, noise, math.Abs(trkPts[0].Lat))
+
+	trkPts[1].HDOP = 100
+	trkPts[1].Lat = 0.01
+	smoothed, err = trkSeg.Smooth(gpx.WithQualityWeights())
+	assert.NoError(t, err)
+	assert.True(t, math.Abs(smoothed.TrkPt[1].Lat) < noise)
+
+	_, err = (&gpx.TrkSegType{TrkPt: []*gpx.WptType{{}}}).Smooth()
+	assert.Error(t, err)
+}
@@ -0,0 +1,520 @@
+package gpx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// fitEpoch is the FIT epoch, 1989-12-31T00:00:00Z, in seconds since the Unix
+// epoch.
+const fitEpoch = 631065600
+
+// FIT global message numbers.
+const (
+	fitMesgNumFileID      = 0
+	fitMesgNumRecord      = 20
+	fitMesgNumEvent       = 21
+	fitMesgNumCourse      = 31
+	fitMesgNumCoursePoint = 32
+)
+
+// FIT field numbers.
+const (
+	fitFieldTimestamp = 253
+
+	fitFileIDTimeCreated = 4
+
+	fitEventEvent     = 0
+	fitEventEventType = 1
+
+	fitRecordPositionLat      = 0
+	fitRecordPositionLong     = 1
+	fitRecordAltitude         = 2
+	fitRecordHeartRate        = 3
+	fitRecordCadence          = 4
+	fitRecordSpeed            = 6
+	fitRecordPower            = 7
+	fitRecordTemperature      = 13
+	fitRecordEnhancedSpeed    = 73
+	fitRecordEnhancedAltitude = 78
+
+	fitCourseName = 5
+
+	fitCoursePointTimestamp    = 1
+	fitCoursePointPositionLat  = 2
+	fitCoursePointPositionLong = 3
+	fitCoursePointType         = 5
+	fitCoursePointName         = 6
+)
+
+// FIT event and event type values.
+const (
+	fitEventTimer              = 0
+	fitEventTypeStop           = 1
+	fitEventTypeStopAll        = 4
+	fitEventTypeStopDisable    = 8
+	fitEventTypeStopDisableAll = 9
+)
+
+// fitBaseTypeString is the FIT string base type.
+const fitBaseTypeString = 0x07
+
+var (
+	errInvalidFITCRC       = errors.New("invalid FIT CRC")
+	errInvalidFITHeader    = errors.New("invalid FIT header")
+	errUndefinedFITMessage = errors.New("undefined FIT local message type")
+)
+
+// fitCRCTable is the table for calculating FIT CRCs.
+var fitCRCTable = [16]uint16{
+	0x0000, 0xcc01, 0xd801, 0x1400, 0xf001, 0x3c00, 0x2800, 0xe401,
+	0xa001, 0x6c00, 0x7800, 0xb401, 0x5000, 0x9c01, 0x8801, 0x4400,
+}
+
+// A fitBaseType is a FIT base type.
+type fitBaseType struct {
+	size    int
+	signed  bool
+	invalid uint64
+}
+
+// fitBaseTypes are the FIT base types, indexed by base type number. Floating
+// point values are decoded as their bits.
+var fitBaseTypes = []fitBaseType{
+	0x00: {size: 1, invalid: 0xff},                             // enum
+	0x01: {size: 1, signed: true, invalid: 0x7f},               // sint8
+	0x02: {size: 1, invalid: 0xff},                             // uint8
+	0x03: {size: 2, signed: true, invalid: 0x7fff},             // sint16
+	0x04: {size: 2, invalid: 0xffff},                           // uint16
+	0x05: {size: 4, signed: true, invalid: 0x7fffffff},         // sint32
+	0x06: {size: 4, invalid: 0xffffffff},                       // uint32
+	0x07: {size: 1},                                            // string
+	0x08: {size: 4, invalid: 0xffffffff},                       // float32
+	0x09: {size: 8, invalid: 0xffffffffffffffff},               // float64
+	0x0a: {size: 1},                                            // uint8z
+	0x0b: {size: 2},                                            // uint16z
+	0x0c: {size: 4},                                            // uint32z
+	0x0d: {size: 1, invalid: 0xff},                             // byte
+	0x0e: {size: 8, signed: true, invalid: 0x7fffffffffffffff}, // sint64
+	0x0f: {size: 8, invalid: 0xffffffffffffffff},               // uint64
+	0x10: {size: 8},                                            // uint64z
+}
+
+// A fitDefinition is a FIT definition message.
+type fitDefinition struct {
+	globalMesgNum     uint16
+	byteOrder         binary.ByteOrder
+	fields            []fitFieldDefinition
+	developerDataSize int
+}
+
+// A fitFieldDefinition is a field in a FIT definition message.
+type fitFieldDefinition struct {
+	num      uint8
+	size     int
+	baseType uint8
+}
+
+// A fitMessage is a FIT data message. Only valid field values are included in
+// fields.
+type fitMessage struct {
+	globalMesgNum uint16
+	fields        map[uint8]fitValue
+}
+
+// A fitValue is a FIT field value.
+type fitValue struct {
+	i int64
+	s string
+}
+
+// A fitDecoder decodes FIT data messages.
+type fitDecoder struct {
+	data          []byte
+	definitions   [16]*fitDefinition
+	lastTimestamp uint32
+}
+
+// A fitGPXBuilder builds a GPX from FIT data messages.
+type fitGPXBuilder struct {
+	g         *GPX
+	trk       *TrkType
+	trkSeg    *TrkSegType
+	newTrkSeg bool
+	stopTime  int64
+}
+
+// ReadFIT reads a GPX from the FIT activity or course file in r. Chained FIT
+// files are read in sequence. Record messages become the points of a single
+// track, with a new segment started by the first record after the time of
+// each timer stop event, and the course's name becomes the track's name.
+// Heart rate, cadence, speed, and temperature are stored in each point's
+// Garmin TrackPointExtension and power in its Garmin PowerExtension. Course points become waypoints. The file's
+// creation time becomes the metadata time. Records without a position are
+// skipped.
+func ReadFIT(r io.Reader) (*GPX, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, errInvalidFITHeader
+	}
+	b := &fitGPXBuilder{
+		g: &GPX{
+			Version: "1.1",
+		},
+	}
+	for len(data) > 0 {
+		var d *fitDecoder
+		if d, data, err = newFITDecoder(data); err != nil {
+			return nil, err
+		}
+		for len(d.data) > 0 {
+			message, err := d.next()
+			if err != nil {
+				return nil, err
+			}
+			if message == nil {
+				continue
+			}
+			if err := b.addMessage(message); err != nil {
+				return nil, fmt.Errorf("FIT message %d: %w", message.globalMesgNum, err)
+			}
+		}
+	}
+	if b.trk != nil {
+		b.g.Trk = append(b.g.Trk, b.trk)
+	}
+	return b.g, nil
+}
+
+// newFITDecoder returns a new fitDecoder for the first FIT file in data, after
+// checking its header and CRC, and the data that follows the file.
+func newFITDecoder(data []byte) (*fitDecoder, []byte, error) {
+	if len(data) < 12 {
+		return nil, nil, errInvalidFITHeader
+	}
+	headerSize := int(data[0])
+	if headerSize < 12 || len(data) < headerSize || !bytes.Equal(data[8:12], []byte(".FIT")) {
+		return nil, nil, errInvalidFITHeader
+	}
+	if headerSize >= 14 {
+		if headerCRC := binary.LittleEndian.Uint16(data[12:14]); headerCRC != 0 && headerCRC != fitCRC(data[:12]) {
+			return nil, nil, errInvalidFITCRC
+		}
+	}
+	dataSize := int(binary.LittleEndian.Uint32(data[4:8]))
+	end := headerSize + dataSize
+	if len(data) < end+2 {
+		return nil, nil, io.ErrUnexpectedEOF
+	}
+	if binary.LittleEndian.Uint16(data[end:end+2]) != fitCRC(data[:end]) {
+		return nil, nil, errInvalidFITCRC
+	}
+	d := &fitDecoder{
+		data: data[headerSize:end],
+	}
+	return d, data[end+2:], nil
+}
+
+// next decodes the next record from d. It returns the data message, or nil if
+// the record is a definition message.
+func (d *fitDecoder) next() (*fitMessage, error) {
+	header, err := d.read(1)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case header[0]&0x80 != 0:
+		// A compressed timestamp header contains the local message type and
+		// the five least significant bits of the timestamp.
+		localMesgType := header[0] >> 5 & 0x03
+		timeOffset := uint32(header[0] & 0x1f)
+		timestamp := d.lastTimestamp&^0x1f | timeOffset
+		if timeOffset < d.lastTimestamp&0x1f {
+			timestamp += 0x20
+		}
+		d.lastTimestamp = timestamp
+		message, err := d.decodeDataMessage(localMesgType)
+		if err != nil {
+			return nil, err
+		}
+		message.fields[fitFieldTimestamp] = fitValue{i: int64(timestamp)}
+		return message, nil
+	case header[0]&0x40 != 0:
+		return nil, d.decodeDefinitionMessage(header[0]&0x0f, header[0]&0x20 != 0)
+	default:
+		message, err := d.decodeDataMessage(header[0] & 0x0f)
+		if err != nil {
+			return nil, err
+		}
+		if timestamp, ok := message.fields[fitFieldTimestamp]; ok {
+			d.lastTimestamp = uint32(timestamp.i)
+		}
+		return message, nil
+	}
+}
+
+// decodeDefinitionMessage decodes a definition message for localMesgType.
+func (d *fitDecoder) decodeDefinitionMessage(localMesgType uint8, developerData bool) error {
+	fixed, err := d.read(5)
+	if err != nil {
+		return err
+	}
+	definition := &fitDefinition{
+		byteOrder: binary.LittleEndian,
+	}
+	if fixed[1] == 1 {
+		definition.byteOrder = binary.BigEndian
+	}
+	definition.globalMesgNum = definition.byteOrder.Uint16(fixed[2:4])
+	fieldDefinitions, err := d.read(3 * int(fixed[4]))
+	if err != nil {
+		return err
+	}
+	definition.fields = make([]fitFieldDefinition, 0, fixed[4])
+	for i := 0; i < len(fieldDefinitions); i += 3 {
+		definition.fields = append(definition.fields, fitFieldDefinition{
+			num:      fieldDefinitions[i],
+			size:     int(fieldDefinitions[i+1]),
+			baseType: fieldDefinitions[i+2] & 0x1f,
+		})
+	}
+	if developerData {
+		numDeveloperFields, err := d.read(1)
+		if err != nil {
+			return err
+		}
+		developerFieldDefinitions, err := d.read(3 * int(numDeveloperFields[0]))
+		if err != nil {
+			return err
+		}
+		for i := 0; i < len(developerFieldDefinitions); i += 3 {
+			definition.developerDataSize += int(developerFieldDefinitions[i+1])
+		}
+	}
+	d.definitions[localMesgType] = definition
+	return nil
+}
+
+// decodeDataMessage decodes a data message of localMesgType.
+func (d *fitDecoder) decodeDataMessage(localMesgType uint8) (*fitMessage, error) {
+	definition := d.definitions[localMesgType]
+	if definition == nil {
+		return nil, fmt.Errorf("%d: %w", localMesgType, errUndefinedFITMessage)
+	}
+	message := &fitMessage{
+		globalMesgNum: definition.globalMesgNum,
+		fields:        make(map[uint8]fitValue, len(definition.fields)),
+	}
+	for _, field := range definition.fields {
+		data, err := d.read(field.size)
+		if err != nil {
+			return nil, err
+		}
+		if value, ok := field.decode(data, definition.byteOrder); ok {
+			message.fields[field.num] = value
+		}
+	}
+	if _, err := d.read(definition.developerDataSize); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// read returns the next n bytes from d.
+func (d *fitDecoder) read(n int) ([]byte, error) {
+	if len(d.data) < n {
+		return nil, io.ErrUnexpectedEOF
+	}
+	data := d.data[:n]
+	d.data = d.data[n:]
+	return data, nil
+}
+
+// decode decodes data as the value of f. It returns false if the value is
+// invalid or is an array.
+func (f *fitFieldDefinition) decode(data []byte, byteOrder binary.ByteOrder) (fitValue, bool) {
+	if f.baseType == fitBaseTypeString {
+		if i := bytes.IndexByte(data, 0); i >= 0 {
+			data = data[:i]
+		}
+		return fitValue{s: string(data)}, len(data) > 0
+	}
+	if int(f.baseType) >= len(fitBaseTypes) {
+		return fitValue{}, false
+	}
+	baseType := fitBaseTypes[f.baseType]
+	if len(data) != baseType.size {
+		return fitValue{}, false
+	}
+	var u uint64
+	switch baseType.size {
+	case 1:
+		u = uint64(data[0])
+	case 2:
+		u = uint64(byteOrder.Uint16(data))
+	case 4:
+		u = uint64(byteOrder.Uint32(data))
+	case 8:
+		u = byteOrder.Uint64(data)
+	}
+	if u == baseType.invalid {
+		return fitValue{}, false
+	}
+	i := int64(u)
+	if baseType.signed {
+		shift := 64 - 8*baseType.size
+		i = int64(u<<shift) >> shift
+	}
+	return fitValue{i: i}, true
+}
+
+// addMessage adds message to b.
+func (b *fitGPXBuilder) addMessage(message *fitMessage) error {
+	switch message.globalMesgNum {
+	case fitMesgNumFileID:
+		if timeCreated, ok := message.fields[fitFileIDTimeCreated]; ok {
+			b.g.Metadata = &MetadataType{
+				Time: fitTime(timeCreated.i),
+			}
+		}
+	case fitMesgNumCourse:
+		if name, ok := message.fields[fitCourseName]; ok {
+			b.track().Name = name.s
+		}
+	case fitMesgNumEvent:
+		event, eventOK := message.fields[fitEventEvent]
+		eventType, eventTypeOK := message.fields[fitEventEventType]
+		if eventOK && eventTypeOK && event.i == fitEventTimer {
+			switch eventType.i {
+			case fitEventTypeStop, fitEventTypeStopAll, fitEventTypeStopDisable, fitEventTypeStopDisableAll:
+				b.newTrkSeg = true
+				b.stopTime = message.fields[fitFieldTimestamp].i
+			}
+		}
+	case fitMesgNumRecord:
+		_, latOK := message.fields[fitRecordPositionLat]
+		_, lonOK := message.fields[fitRecordPositionLong]
+		if !latOK || !lonOK {
+			return nil
+		}
+		trkPt, err := message.recordWptType()
+		if err != nil {
+			return err
+		}
+		// Devices write a record at the time of a stop event after the
+		// event, so only later records start a new segment.
+		timestamp, timestampOK := message.fields[fitFieldTimestamp]
+		if b.trkSeg == nil || b.newTrkSeg && (!timestampOK || timestamp.i > b.stopTime) {
+			b.trkSeg = &TrkSegType{}
+			b.track().TrkSeg = append(b.track().TrkSeg, b.trkSeg)
+			b.newTrkSeg = false
+		}
+		b.trkSeg.TrkPt = append(b.trkSeg.TrkPt, trkPt)
+	case fitMesgNumCoursePoint:
+		lat, latOK := message.fields[fitCoursePointPositionLat]
+		lon, lonOK := message.fields[fitCoursePointPositionLong]
+		if !latOK || !lonOK {
+			return nil
+		}
+		wpt := &WptType{
+			Lat:  fitSemicirclesToDegrees(lat.i),
+			Lon:  fitSemicirclesToDegrees(lon.i),
+			Name: message.fields[fitCoursePointName].s,
+		}
+		if timestamp, ok := message.fields[fitCoursePointTimestamp]; ok {
+			wpt.Time = fitTime(timestamp.i)
+		}
+		if coursePointType, ok := message.fields[fitCoursePointType]; ok && coursePointType.i < int64(len(tcxCoursePointTypes)) {
+			wpt.Type = tcxCoursePointTypes[coursePointType.i]
+		}
+		b.g.Wpt = append(b.g.Wpt, wpt)
+	}
+	return nil
+}
+
+// track returns b's track, creating it if needed.
+func (b *fitGPXBuilder) track() *TrkType {
+	if b.trk == nil {
+		b.trk = &TrkType{}
+	}
+	return b.trk
+}
+
+// recordWptType returns a new WptType from the record message m, which must
+// have a position.
+func (m *fitMessage) recordWptType() (*WptType, error) {
+	w := &WptType{
+		Lat: fitSemicirclesToDegrees(m.fields[fitRecordPositionLat].i),
+		Lon: fitSemicirclesToDegrees(m.fields[fitRecordPositionLong].i),
+	}
+	if timestamp, ok := m.fields[fitFieldTimestamp]; ok {
+		w.Time = fitTime(timestamp.i)
+	}
+	if altitude, ok := m.fields[fitRecordEnhancedAltitude]; ok {
+		w.Ele = float64(altitude.i)/5 - 500
+		w.Present |= WptEle
+	} else if altitude, ok := m.fields[fitRecordAltitude]; ok {
+		w.Ele = float64(altitude.i)/5 - 500
+		w.Present |= WptEle
+	}
+	tpe := &TrackPointExtension{
+		Version: 2,
+	}
+	if heartRate, ok := m.fields[fitRecordHeartRate]; ok {
+		tpe.HR = int(heartRate.i)
+		tpe.Present |= TrackPointExtensionHR
+	}
+	if cadence, ok := m.fields[fitRecordCadence]; ok {
+		tpe.Cad = int(cadence.i)
+		tpe.Present |= TrackPointExtensionCad
+	}
+	if speed, ok := m.fields[fitRecordEnhancedSpeed]; ok {
+		tpe.Speed = float64(speed.i) / 1000
+		tpe.Present |= TrackPointExtensionSpeed
+	} else if speed, ok := m.fields[fitRecordSpeed]; ok {
+		tpe.Speed = float64(speed.i) / 1000
+		tpe.Present |= TrackPointExtensionSpeed
+	}
+	if temperature, ok := m.fields[fitRecordTemperature]; ok {
+		tpe.ATemp = float64(temperature.i)
+		tpe.Present |= TrackPointExtensionATemp
+	}
+	if power, ok := m.fields[fitRecordPower]; ok {
+		if err := w.SetPowerExtension(&PowerExtension{PowerInWatts: int(power.i)}); err != nil {
+			return nil, err
+		}
+	}
+	if tpe.Present != 0 {
+		if err := w.SetTrackPointExtension(tpe); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// fitCRC returns the FIT CRC of data.
+func fitCRC(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc = crc>>4 ^ fitCRCTable[crc&0xf] ^ fitCRCTable[b&0xf]
+		crc = crc>>4 ^ fitCRCTable[crc&0xf] ^ fitCRCTable[b>>4]
+	}
+	return crc
+}
+
+// fitSemicirclesToDegrees converts semicircles to degrees.
+func fitSemicirclesToDegrees(semicircles int64) float64 {
+	return float64(semicircles) * 180 / (1 << 31)
+}
+
+// fitTime returns the time of the FIT timestamp t.
+func fitTime(t int64) time.Time {
+	return time.Unix(fitEpoch+t, 0).UTC()
+}
@@ -0,0 +1,235 @@
+package gpx_test
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	gpx "github.com/twpayne/go-gpx"
+)
+
+func TestReadFIT(t *testing.T) {
+	t0 := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	semicirclesToDegrees := func(semicircles int64) float64 {
+		return float64(semicircles) * 180 / (1 << 31)
+	}
+	newTrkPt := func(lat, lon int64, ele float64, dt time.Duration, tpe *gpx.TrackPointExtension, pe *gpx.PowerExtension) *gpx.WptType {
+		trkPt := &gpx.WptType{
+			Lat:     semicirclesToDegrees(lat),
+			Lon:     semicirclesToDegrees(lon),
+			Ele:     ele,
+			Time:    t0.Add(dt),
+			Present: gpx.WptEle,
+		}
+		if pe != nil {
+			assert.NoError(t, trkPt.SetPowerExtension(pe))
+		}
+		assert.NoError(t, trkPt.SetTrackPointExtension(tpe))
+		return trkPt
+	}
+
+	for _, tc := range []struct {
+		name     string
+		expected *gpx.GPX
+	}{
+		{
+			name: "activity.fit",
+			expected: &gpx.GPX{
+				Version: "1.1",
+				Metadata: &gpx.MetadataType{
+					Time: t0,
+				},
+				Trk: []*gpx.TrkType{
+					{
+						TrkSeg: []*gpx.TrkSegType{
+							{
+								TrkPt: []*gpx.WptType{
+									newTrkPt(554766609, 77548021, 372, 0, &gpx.TrackPointExtension{Version: 2, ATemp: 21, HR: 120, Cad: 80, Speed: 8.5}, &gpx.PowerExtension{PowerInWatts: 250}),
+									newTrkPt(554778540, 77559951, 373, time.Second, &gpx.TrackPointExtension{Version: 2, HR: 121}, nil),
+								},
+							},
+							{
+								TrkPt: []*gpx.WptType{
+									newTrkPt(554790470, 77571882, 374, 28*time.Second, &gpx.TrackPointExtension{Version: 2, HR: 130}, nil),
+									newTrkPt(554802400, 77583812, 375, 33*time.Second, &gpx.TrackPointExtension{Version: 2, HR: 131}, nil),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "course.fit",
+			expected: &gpx.GPX{
+				Version: "1.1",
+				Metadata: &gpx.MetadataType{
+					Time: t0,
+				},
+				Wpt: []*gpx.WptType{
+					{Lat: semicirclesToDegrees(554826261), Lon: semicirclesToDegrees(77607673), Time: t0.Add(30 * time.Second), Name: "Top", Type: "Summit"},
+					{Lat: semicirclesToDegrees(554885914), Lon: semicirclesToDegrees(77667325), Time: t0.Add(time.Minute), Name: "Segment"},
+				},
+				Trk: []*gpx.TrkType{
+					{
+						Name: "Lake loop",
+						TrkSeg: []*gpx.TrkSegType{
+							{
+								TrkPt: []*gpx.WptType{
+									{Lat: semicirclesToDegrees(554766609), Lon: semicirclesToDegrees(77548021), Ele: 372, Time: t0, Present: gpx.WptEle},
+									{Lat: semicirclesToDegrees(554885914), Lon: semicirclesToDegrees(77667325), Ele: 380, Time: t0.Add(time.Minute), Present: gpx.WptEle},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := os.Open("testdata/fit/" + tc.name)
+			assert.NoError(t, err)
+			defer f.Close()
+			actual, err := gpx.ReadFIT(f)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+// TestReadFITDevice reads a ride recorded by a Garmin Edge 200, taken from the
+// python-fitparse test files. The expected values were decoded with
+// github.com/tormoder/fit.
+func TestReadFITDevice(t *testing.T) {
+	f, err := os.Open("testdata/fit/2015-10-13-08-43-15.fit")
+	assert.NoError(t, err)
+	defer f.Close()
+	g, err := gpx.ReadFIT(f)
+	assert.NoError(t, err)
+	assert.Equal(t, &gpx.MetadataType{Time: time.Date(2015, 10, 13, 15, 43, 14, 0, time.UTC)}, g.Metadata)
+	assert.Equal(t, 1, len(g.Trk))
+	trkSegLens := make([]int, 0, len(g.Trk[0].TrkSeg))
+	for _, trkSeg := range g.Trk[0].TrkSeg {
+		trkSegLens = append(trkSegLens, len(trkSeg.TrkPt))
+	}
+	assert.Equal(t, []int{3, 41, 86, 81, 6, 4}, trkSegLens)
+
+	for _, tc := range []struct {
+		name          string
+		trkPt         *gpx.WptType
+		expectedLat   float64
+		expectedLon   float64
+		expectedEle   float64
+		expectedTime  time.Time
+		expectedSpeed float64
+	}{
+		{
+			name:          "first",
+			trkPt:         g.Trk[0].TrkSeg[0].TrkPt[0],
+			expectedLat:   45.593000119552016,
+			expectedLon:   -122.72254739888012,
+			expectedEle:   35.8,
+			expectedTime:  time.Date(2015, 10, 13, 15, 43, 15, 0, time.UTC),
+			expectedSpeed: 0.775,
+		},
+		{
+			name:          "record_100",
+			trkPt:         g.Trk[0].TrkSeg[2].TrkPt[56],
+			expectedLat:   45.565614346414804,
+			expectedLon:   -122.69748902879655,
+			expectedEle:   58,
+			expectedTime:  time.Date(2015, 10, 13, 15, 56, 45, 0, time.UTC),
+			expectedSpeed: 6.914,
+		},
+		{
+			name:         "last",
+			trkPt:        g.Trk[0].TrkSeg[5].TrkPt[3],
+			expectedLat:  45.52210480906069,
+			expectedLon:  -122.67575575970113,
+			expectedEle:  17.6,
+			expectedTime: time.Date(2015, 10, 13, 16, 10, 18, 0, time.UTC),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedLat, tc.trkPt.Lat)
+			assert.Equal(t, tc.expectedLon, tc.trkPt.Lon)
+			assert.True(t, math.Abs(tc.expectedEle-tc.trkPt.Ele) < 1e-9)
+			assert.Equal(t, tc.expectedTime, tc.trkPt.Time)
+			tpe, err := tc.trkPt.TrackPointExtension()
+			assert.NoError(t, err)
+			assert.NotZero(t, tpe)
+			assert.True(t, tpe.Has(gpx.TrackPointExtensionSpeed))
+			assert.Equal(t, tc.expectedSpeed, tpe.Speed)
+		})
+	}
+}
+
+func TestReadFITErrors(t *testing.T) {
+	data, err := os.ReadFile("testdata/fit/course.fit")
+	assert.NoError(t, err)
+
+	for _, tc := range []struct {
+		name          string
+		data          func() []byte
+		expectedError string
+	}{
+		{
+			name: "chained",
+			data: func() []byte {
+				return append(bytes.Clone(data), data...)
+			},
+		},
+		{
+			name: "empty",
+			data: func() []byte {
+				return nil
+			},
+			expectedError: "invalid FIT header",
+		},
+		{
+			name: "invalid_header",
+			data: func() []byte {
+				return []byte("not a FIT file")
+			},
+			expectedError: "invalid FIT header",
+		},
+		{
+			name: "invalid_header_crc",
+			data: func() []byte {
+				data := bytes.Clone(data)
+				data[12]++
+				return data
+			},
+			expectedError: "invalid FIT CRC",
+		},
+		{
+			name: "invalid_crc",
+			data: func() []byte {
+				data := bytes.Clone(data)
+				data[len(data)-3]++
+				return data
+			},
+			expectedError: "invalid FIT CRC",
+		},
+		{
+			name: "truncated",
+			data: func() []byte {
+				return data[:len(data)-1]
+			},
+			expectedError: "unexpected EOF",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := gpx.ReadFIT(bytes.NewReader(tc.data()))
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
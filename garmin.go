@@ -0,0 +1,660 @@
+package gpx
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Garmin extension namespaces.
+const (
+	TrackPointExtensionV1Namespace = "http://www.garmin.com/xmlschemas/TrackPointExtension/v1"
+	TrackPointExtensionV2Namespace = "http://www.garmin.com/xmlschemas/TrackPointExtension/v2"
+)
+
+var trackPointExtensionNamespaces = []string{
+	TrackPointExtensionV1Namespace,
+	TrackPointExtensionV2Namespace,
+}
+
+// A TrackPointExtension is a Garmin TrackPointExtension. Version is 1 or 2; a
+// zero Version is written as version 2. Speed, Course, and Bearing are only
+// present in version 2. Present records which fields are present, so that
+// fields with a zero value, such as a temperature of 0°C, are preserved.
+// Non-zero fields are always considered present.
+type TrackPointExtension struct {
+	Version int                      `xml:"-"`
+	ATemp   float64                  `xml:"atemp"`
+	WTemp   float64                  `xml:"wtemp"`
+	Depth   float64                  `xml:"depth"`
+	HR      int                      `xml:"hr"`
+	Cad     int                      `xml:"cad"`
+	Speed   float64                  `xml:"speed"`
+	Course  float64                  `xml:"course"`
+	Bearing float64                  `xml:"bearing"`
+	Present TrackPointExtensionField `xml:"-"`
+}
+
+// A TrackPointExtensionField identifies a field of a TrackPointExtension.
+type TrackPointExtensionField uint16
+
+// Fields of a TrackPointExtension.
+const (
+	TrackPointExtensionATemp TrackPointExtensionField = 1 << iota
+	TrackPointExtensionWTemp
+	TrackPointExtensionDepth
+	TrackPointExtensionHR
+	TrackPointExtensionCad
+	TrackPointExtensionSpeed
+	TrackPointExtensionCourse
+	TrackPointExtensionBearing
+)
+
+// Has returns whether tpe has the field f, either because f is set in
+// tpe.Present or because its value is non-zero.
+func (tpe *TrackPointExtension) Has(f TrackPointExtensionField) bool {
+	if tpe.Present&f != 0 {
+		return true
+	}
+	switch f {
+	case TrackPointExtensionATemp:
+		return tpe.ATemp != 0
+	case TrackPointExtensionWTemp:
+		return tpe.WTemp != 0
+	case TrackPointExtensionDepth:
+		return tpe.Depth != 0
+	case TrackPointExtensionHR:
+		return tpe.HR != 0
+	case TrackPointExtensionCad:
+		return tpe.Cad != 0
+	case TrackPointExtensionSpeed:
+		return tpe.Speed != 0
+	case TrackPointExtensionCourse:
+		return tpe.Course != 0
+	case TrackPointExtensionBearing:
+		return tpe.Bearing != 0
+	}
+	return false
+}
+
+// UnmarshalXML implements xml.Unmarshaler.UnmarshalXML.
+func (tpe *TrackPointExtension) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var e struct {
+		ATemp   *float64 `xml:"atemp"`
+		WTemp   *float64 `xml:"wtemp"`
+		Depth   *float64 `xml:"depth"`
+		HR      *int     `xml:"hr"`
+		Cad     *int     `xml:"cad"`
+		Speed   *float64 `xml:"speed"`
+		Course  *float64 `xml:"course"`
+		Bearing *float64 `xml:"bearing"`
+	}
+	if err := d.DecodeElement(&e, &start); err != nil {
+		return err
+	}
+	for _, field := range []struct {
+		field TrackPointExtensionField
+		src   *float64
+		dst   *float64
+	}{
+		{TrackPointExtensionATemp, e.ATemp, &tpe.ATemp},
+		{TrackPointExtensionWTemp, e.WTemp, &tpe.WTemp},
+		{TrackPointExtensionDepth, e.Depth, &tpe.Depth},
+		{TrackPointExtensionSpeed, e.Speed, &tpe.Speed},
+		{TrackPointExtensionCourse, e.Course, &tpe.Course},
+		{TrackPointExtensionBearing, e.Bearing, &tpe.Bearing},
+	} {
+		if field.src != nil {
+			*field.dst = *field.src
+			tpe.Present |= field.field
+		}
+	}
+	if e.HR != nil {
+		tpe.HR = *e.HR
+		tpe.Present |= TrackPointExtensionHR
+	}
+	if e.Cad != nil {
+		tpe.Cad = *e.Cad
+		tpe.Present |= TrackPointExtensionCad
+	}
+	return nil
+}
+
+// TrackPointExtension returns w's Garmin TrackPointExtension, or nil if w
+// does not have one.
+func (w *WptType) TrackPointExtension() (*TrackPointExtension, error) {
+	tpe := &TrackPointExtension{}
+	namespace, ok, err := w.Extensions.decodeElement(trackPointExtensionNamespaces, "TrackPointExtension", tpe)
+	if err != nil || !ok {
+		return nil, err
+	}
+	if namespace == TrackPointExtensionV1Namespace {
+		tpe.Version = 1
+	} else {
+		tpe.Version = 2
+	}
+	return tpe, nil
+}
+
+// SetTrackPointExtension sets w's Garmin TrackPointExtension to tpe,
+// replacing any existing TrackPointExtension. If tpe is nil then any existing
+// TrackPointExtension is removed.
+func (w *WptType) SetTrackPointExtension(tpe *TrackPointExtension) error {
+	if tpe == nil {
+		return setElement(&w.Extensions, trackPointExtensionNamespaces, "TrackPointExtension", nil)
+	}
+	return setElement(&w.Extensions, trackPointExtensionNamespaces, "TrackPointExtension", tpe.encode)
+}
+
+func (tpe *TrackPointExtension) encode(e *xml.Encoder, extensions *ExtensionsType) error {
+	var prefix string
+	if tpe.Version == 1 {
+		prefix = extensions.prefix(TrackPointExtensionV1Namespace, "gpxtpx")
+	} else {
+		prefix = extensions.prefix(TrackPointExtensionV2Namespace, "gpxtpx")
+	}
+	start := xml.StartElement{Name: xml.Name{Local: prefix + ":TrackPointExtension"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := tpe.maybeEmitFloatField(e, TrackPointExtensionATemp, prefix+":atemp", tpe.ATemp); err != nil {
+		return err
+	}
+	if err := tpe.maybeEmitFloatField(e, TrackPointExtensionWTemp, prefix+":wtemp", tpe.WTemp); err != nil {
+		return err
+	}
+	if err := tpe.maybeEmitFloatField(e, TrackPointExtensionDepth, prefix+":depth", tpe.Depth); err != nil {
+		return err
+	}
+	if err := tpe.maybeEmitIntField(e, TrackPointExtensionHR, prefix+":hr", tpe.HR); err != nil {
+		return err
+	}
+	if err := tpe.maybeEmitIntField(e, TrackPointExtensionCad, prefix+":cad", tpe.Cad); err != nil {
+		return err
+	}
+	if tpe.Version != 1 {
+		if err := tpe.maybeEmitFloatField(e, TrackPointExtensionSpeed, prefix+":speed", tpe.Speed); err != nil {
+			return err
+		}
+		if err := tpe.maybeEmitFloatField(e, TrackPointExtensionCourse, prefix+":course", tpe.Course); err != nil {
+			return err
+		}
+		if err := tpe.maybeEmitFloatField(e, TrackPointExtensionBearing, prefix+":bearing", tpe.Bearing); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+func (tpe *TrackPointExtension) maybeEmitFloatField(e *xml.Encoder, f TrackPointExtensionField, localName string, value float64) error {
+	if !tpe.Has(f) {
+		return nil
+	}
+	return emitFloatElement(e, localName, value)
+}
+
+func (tpe *TrackPointExtension) maybeEmitIntField(e *xml.Encoder, f TrackPointExtensionField, localName string, value int) error {
+	if !tpe.Has(f) {
+		return nil
+	}
+	return emitIntElement(e, localName, value)
+}
+
+// PowerExtensionV1Namespace is the Garmin PowerExtension v1 namespace.
+const PowerExtensionV1Namespace = "http://www.garmin.com/xmlschemas/PowerExtension/v1"
+
+var powerExtensionNamespaces = []string{
+	PowerExtensionV1Namespace,
+}
+
+// A PowerExtension is a Garmin PowerExtension.
+type PowerExtension struct {
+	PowerInWatts int `xml:"PowerInWatts"`
+}
+
+// PowerExtension returns w's Garmin PowerExtension, or nil if w does not have
+// one.
+func (w *WptType) PowerExtension() (*PowerExtension, error) {
+	pe := &PowerExtension{}
+	if _, ok, err := w.Extensions.decodeElement(powerExtensionNamespaces, "PowerExtension", pe); err != nil || !ok {
+		return nil, err
+	}
+	return pe, nil
+}
+
+// SetPowerExtension sets w's Garmin PowerExtension to pe. If pe is nil then
+// any existing PowerExtension is removed.
+func (w *WptType) SetPowerExtension(pe *PowerExtension) error {
+	if pe == nil {
+		return setElement(&w.Extensions, powerExtensionNamespaces, "PowerExtension", nil)
+	}
+	return setElement(&w.Extensions, powerExtensionNamespaces, "PowerExtension", pe.encode)
+}
+
+func (pe *PowerExtension) encode(e *xml.Encoder, extensions *ExtensionsType) error {
+	prefix := extensions.prefix(PowerExtensionV1Namespace, "gpxpx")
+	start := xml.StartElement{Name: xml.Name{Local: prefix + ":PowerExtension"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := emitIntElement(e, prefix+":PowerInWatts", pe.PowerInWatts); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+// GpxExtensionsV3Namespace is the Garmin GpxExtensions v3 namespace.
+const GpxExtensionsV3Namespace = "http://www.garmin.com/xmlschemas/GpxExtensions/v3"
+
+var gpxExtensionsV3Namespaces = []string{
+	GpxExtensionsV3Namespace,
+}
+
+var errInvalidDisplayColor = errors.New("invalid display color")
+
+// A DisplayColor is a Garmin display color.
+type DisplayColor string
+
+// Garmin display colors.
+const (
+	DisplayColorBlack       DisplayColor = "Black"
+	DisplayColorDarkRed     DisplayColor = "DarkRed"
+	DisplayColorDarkGreen   DisplayColor = "DarkGreen"
+	DisplayColorDarkYellow  DisplayColor = "DarkYellow"
+	DisplayColorDarkBlue    DisplayColor = "DarkBlue"
+	DisplayColorDarkMagenta DisplayColor = "DarkMagenta"
+	DisplayColorDarkCyan    DisplayColor = "DarkCyan"
+	DisplayColorLightGray   DisplayColor = "LightGray"
+	DisplayColorDarkGray    DisplayColor = "DarkGray"
+	DisplayColorRed         DisplayColor = "Red"
+	DisplayColorGreen       DisplayColor = "Green"
+	DisplayColorYellow      DisplayColor = "Yellow"
+	DisplayColorBlue        DisplayColor = "Blue"
+	DisplayColorMagenta     DisplayColor = "Magenta"
+	DisplayColorCyan        DisplayColor = "Cyan"
+	DisplayColorWhite       DisplayColor = "White"
+	DisplayColorTransparent DisplayColor = "Transparent"
+)
+
+// displayColorRGBs maps display colors to their RGB values.
+var displayColorRGBs = map[DisplayColor]uint32{
+	DisplayColorBlack:       0x000000,
+	DisplayColorDarkRed:     0x8b0000,
+	DisplayColorDarkGreen:   0x006400,
+	DisplayColorDarkYellow:  0x8b8b00,
+	DisplayColorDarkBlue:    0x00008b,
+	DisplayColorDarkMagenta: 0x8b008b,
+	DisplayColorDarkCyan:    0x008b8b,
+	DisplayColorLightGray:   0xd3d3d3,
+	DisplayColorDarkGray:    0xa9a9a9,
+	DisplayColorRed:         0xff0000,
+	DisplayColorGreen:       0x00ff00,
+	DisplayColorYellow:      0xffff00,
+	DisplayColorBlue:        0x0000ff,
+	DisplayColorMagenta:     0xff00ff,
+	DisplayColorCyan:        0x00ffff,
+	DisplayColorWhite:       0xffffff,
+	DisplayColorTransparent: 0xffffff,
+}
+
+// Valid returns whether c is a valid display color.
+func (c DisplayColor) Valid() bool {
+	_, ok := displayColorRGBs[c]
+	return ok
+}
+
+// An AddressType is a Garmin GpxExtensions v3 address.
+type AddressType struct {
+	StreetAddress []string `xml:"StreetAddress"`
+	City          string   `xml:"City"`
+	State         string   `xml:"State"`
+	Country       string   `xml:"Country"`
+	PostalCode    string   `xml:"PostalCode"`
+}
+
+// A PhoneNumberType is a Garmin GpxExtensions v3 phone number.
+type PhoneNumberType struct {
+	Category string `xml:"Category,attr"`
+	Number   string `xml:",chardata"`
+}
+
+// A WaypointExtension is a Garmin GpxExtensions v3 WaypointExtension.
+// Present records which of Proximity, Temperature, and Depth are present, so
+// that zero values are preserved. Non-zero values are always considered
+// present.
+type WaypointExtension struct {
+	Proximity    float64                `xml:"Proximity"`
+	Temperature  float64                `xml:"Temperature"`
+	Depth        float64                `xml:"Depth"`
+	DisplayMode  string                 `xml:"DisplayMode"`
+	Categories   []string               `xml:"Categories>Category"`
+	Address      *AddressType           `xml:"Address"`
+	PhoneNumbers []*PhoneNumberType     `xml:"PhoneNumber"`
+	Present      WaypointExtensionField `xml:"-"`
+}
+
+// A WaypointExtensionField identifies a numeric field of a WaypointExtension.
+type WaypointExtensionField uint8
+
+// Numeric fields of a WaypointExtension.
+const (
+	WaypointExtensionProximity WaypointExtensionField = 1 << iota
+	WaypointExtensionTemperature
+	WaypointExtensionDepth
+)
+
+// Has returns whether we has the field f, either because f is set in
+// we.Present or because its value is non-zero.
+func (we *WaypointExtension) Has(f WaypointExtensionField) bool {
+	if we.Present&f != 0 {
+		return true
+	}
+	switch f {
+	case WaypointExtensionProximity:
+		return we.Proximity != 0
+	case WaypointExtensionTemperature:
+		return we.Temperature != 0
+	case WaypointExtensionDepth:
+		return we.Depth != 0
+	}
+	return false
+}
+
+// UnmarshalXML implements xml.Unmarshaler.UnmarshalXML.
+func (we *WaypointExtension) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var e struct {
+		Proximity    *float64           `xml:"Proximity"`
+		Temperature  *float64           `xml:"Temperature"`
+		Depth        *float64           `xml:"Depth"`
+		DisplayMode  string             `xml:"DisplayMode"`
+		Categories   []string           `xml:"Categories>Category"`
+		Address      *AddressType       `xml:"Address"`
+		PhoneNumbers []*PhoneNumberType `xml:"PhoneNumber"`
+	}
+	if err := d.DecodeElement(&e, &start); err != nil {
+		return err
+	}
+	for _, field := range []struct {
+		field WaypointExtensionField
+		src   *float64
+		dst   *float64
+	}{
+		{WaypointExtensionProximity, e.Proximity, &we.Proximity},
+		{WaypointExtensionTemperature, e.Temperature, &we.Temperature},
+		{WaypointExtensionDepth, e.Depth, &we.Depth},
+	} {
+		if field.src != nil {
+			*field.dst = *field.src
+			we.Present |= field.field
+		}
+	}
+	we.DisplayMode = e.DisplayMode
+	we.Categories = e.Categories
+	we.Address = e.Address
+	we.PhoneNumbers = e.PhoneNumbers
+	return nil
+}
+
+// A RouteExtension is a Garmin GpxExtensions v3 RouteExtension.
+type RouteExtension struct {
+	IsAutoNamed  bool         `xml:"IsAutoNamed"`
+	DisplayColor DisplayColor `xml:"DisplayColor"`
+}
+
+// An AutoroutePointType is a shaping point calculated by a Garmin device
+// between two route points.
+type AutoroutePointType struct {
+	Lat      float64 `xml:"lat,attr"`
+	Lon      float64 `xml:"lon,attr"`
+	Subclass string  `xml:"Subclass"`
+}
+
+// A RoutePointExtension is a Garmin GpxExtensions v3 RoutePointExtension.
+type RoutePointExtension struct {
+	Subclass string                `xml:"Subclass"`
+	Rpt      []*AutoroutePointType `xml:"rpt"`
+}
+
+// A TrackExtension is a Garmin GpxExtensions v3 TrackExtension.
+type TrackExtension struct {
+	DisplayColor DisplayColor `xml:"DisplayColor"`
+}
+
+// WaypointExtension returns w's Garmin WaypointExtension, or nil if w does
+// not have one.
+func (w *WptType) WaypointExtension() (*WaypointExtension, error) {
+	we := &WaypointExtension{}
+	if _, ok, err := w.Extensions.decodeElement(gpxExtensionsV3Namespaces, "WaypointExtension", we); err != nil || !ok {
+		return nil, err
+	}
+	return we, nil
+}
+
+// SetWaypointExtension sets w's Garmin WaypointExtension to we. If we is nil
+// then any existing WaypointExtension is removed.
+func (w *WptType) SetWaypointExtension(we *WaypointExtension) error {
+	if we == nil {
+		return setElement(&w.Extensions, gpxExtensionsV3Namespaces, "WaypointExtension", nil)
+	}
+	return setElement(&w.Extensions, gpxExtensionsV3Namespaces, "WaypointExtension", we.encode)
+}
+
+// RoutePointExtension returns w's Garmin RoutePointExtension, or nil if w
+// does not have one.
+func (w *WptType) RoutePointExtension() (*RoutePointExtension, error) {
+	rpe := &RoutePointExtension{}
+	if _, ok, err := w.Extensions.decodeElement(gpxExtensionsV3Namespaces, "RoutePointExtension", rpe); err != nil || !ok {
+		return nil, err
+	}
+	return rpe, nil
+}
+
+// SetRoutePointExtension sets w's Garmin RoutePointExtension to rpe. If rpe
+// is nil then any existing RoutePointExtension is removed.
+func (w *WptType) SetRoutePointExtension(rpe *RoutePointExtension) error {
+	if rpe == nil {
+		return setElement(&w.Extensions, gpxExtensionsV3Namespaces, "RoutePointExtension", nil)
+	}
+	return setElement(&w.Extensions, gpxExtensionsV3Namespaces, "RoutePointExtension", rpe.encode)
+}
+
+// RouteExtension returns r's Garmin RouteExtension, or nil if r does not have
+// one.
+func (r *RteType) RouteExtension() (*RouteExtension, error) {
+	re := &RouteExtension{}
+	if _, ok, err := r.Extensions.decodeElement(gpxExtensionsV3Namespaces, "RouteExtension", re); err != nil || !ok {
+		return nil, err
+	}
+	return re, nil
+}
+
+// SetRouteExtension sets r's Garmin RouteExtension to re. If re is nil then
+// any existing RouteExtension is removed.
+func (r *RteType) SetRouteExtension(re *RouteExtension) error {
+	if re == nil {
+		return setElement(&r.Extensions, gpxExtensionsV3Namespaces, "RouteExtension", nil)
+	}
+	if re.DisplayColor != "" && !re.DisplayColor.Valid() {
+		return fmt.Errorf("%s: %w", re.DisplayColor, errInvalidDisplayColor)
+	}
+	return setElement(&r.Extensions, gpxExtensionsV3Namespaces, "RouteExtension", re.encode)
+}
+
+// AutoroutedRtePt returns r's route points with the shaping points from their
+// Garmin RoutePointExtensions inserted after each route point.
+func (r *RteType) AutoroutedRtePt() ([]*WptType, error) {
+	rtePts := make([]*WptType, 0, len(r.RtePt))
+	for _, rtePt := range r.RtePt {
+		rtePts = append(rtePts, rtePt)
+		rpe, err := rtePt.RoutePointExtension()
+		if err != nil {
+			return nil, err
+		}
+		if rpe == nil {
+			continue
+		}
+		for _, rpt := range rpe.Rpt {
+			rtePts = append(rtePts, &WptType{
+				Lat: rpt.Lat,
+				Lon: rpt.Lon,
+			})
+		}
+	}
+	return rtePts, nil
+}
+
+// TrackExtension returns t's Garmin TrackExtension, or nil if t does not have
+// one.
+func (t *TrkType) TrackExtension() (*TrackExtension, error) {
+	te := &TrackExtension{}
+	if _, ok, err := t.Extensions.decodeElement(gpxExtensionsV3Namespaces, "TrackExtension", te); err != nil || !ok {
+		return nil, err
+	}
+	return te, nil
+}
+
+// SetTrackExtension sets t's Garmin TrackExtension to te. If te is nil then
+// any existing TrackExtension is removed.
+func (t *TrkType) SetTrackExtension(te *TrackExtension) error {
+	if te == nil {
+		return setElement(&t.Extensions, gpxExtensionsV3Namespaces, "TrackExtension", nil)
+	}
+	if te.DisplayColor != "" && !te.DisplayColor.Valid() {
+		return fmt.Errorf("%s: %w", te.DisplayColor, errInvalidDisplayColor)
+	}
+	return setElement(&t.Extensions, gpxExtensionsV3Namespaces, "TrackExtension", te.encode)
+}
+
+func (we *WaypointExtension) encode(e *xml.Encoder, extensions *ExtensionsType) error {
+	prefix := extensions.prefix(GpxExtensionsV3Namespace, "gpxx")
+	start := xml.StartElement{Name: xml.Name{Local: prefix + ":WaypointExtension"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, field := range []struct {
+		field     WaypointExtensionField
+		localName string
+		value     float64
+	}{
+		{WaypointExtensionProximity, "Proximity", we.Proximity},
+		{WaypointExtensionTemperature, "Temperature", we.Temperature},
+		{WaypointExtensionDepth, "Depth", we.Depth},
+	} {
+		if we.Has(field.field) {
+			if err := emitFloatElement(e, prefix+":"+field.localName, field.value); err != nil {
+				return err
+			}
+		}
+	}
+	if err := maybeEmitStringElement(e, prefix+":DisplayMode", we.DisplayMode); err != nil {
+		return err
+	}
+	if len(we.Categories) > 0 {
+		categoriesStart := xml.StartElement{Name: xml.Name{Local: prefix + ":Categories"}}
+		if err := e.EncodeToken(categoriesStart); err != nil {
+			return err
+		}
+		for _, category := range we.Categories {
+			if err := emitStringElement(e, prefix+":Category", category); err != nil {
+				return err
+			}
+		}
+		if err := e.EncodeToken(categoriesStart.End()); err != nil {
+			return err
+		}
+	}
+	if a := we.Address; a != nil {
+		addressStart := xml.StartElement{Name: xml.Name{Local: prefix + ":Address"}}
+		if err := e.EncodeToken(addressStart); err != nil {
+			return err
+		}
+		for _, streetAddress := range a.StreetAddress {
+			if err := emitStringElement(e, prefix+":StreetAddress", streetAddress); err != nil {
+				return err
+			}
+		}
+		if err := maybeEmitStringElement(e, prefix+":City", a.City); err != nil {
+			return err
+		}
+		if err := maybeEmitStringElement(e, prefix+":State", a.State); err != nil {
+			return err
+		}
+		if err := maybeEmitStringElement(e, prefix+":Country", a.Country); err != nil {
+			return err
+		}
+		if err := maybeEmitStringElement(e, prefix+":PostalCode", a.PostalCode); err != nil {
+			return err
+		}
+		if err := e.EncodeToken(addressStart.End()); err != nil {
+			return err
+		}
+	}
+	for _, phoneNumber := range we.PhoneNumbers {
+		phoneNumberStart := xml.StartElement{Name: xml.Name{Local: prefix + ":PhoneNumber"}}
+		if phoneNumber.Category != "" {
+			phoneNumberStart.Attr = append(phoneNumberStart.Attr, xml.Attr{
+				Name:  xml.Name{Local: "Category"},
+				Value: phoneNumber.Category,
+			})
+		}
+		if err := e.EncodeElement(phoneNumber.Number, phoneNumberStart); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+func (re *RouteExtension) encode(e *xml.Encoder, extensions *ExtensionsType) error {
+	prefix := extensions.prefix(GpxExtensionsV3Namespace, "gpxx")
+	start := xml.StartElement{Name: xml.Name{Local: prefix + ":RouteExtension"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := emitStringElement(e, prefix+":IsAutoNamed", strconv.FormatBool(re.IsAutoNamed)); err != nil {
+		return err
+	}
+	if err := maybeEmitStringElement(e, prefix+":DisplayColor", string(re.DisplayColor)); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+func (rpe *RoutePointExtension) encode(e *xml.Encoder, extensions *ExtensionsType) error {
+	prefix := extensions.prefix(GpxExtensionsV3Namespace, "gpxx")
+	start := xml.StartElement{Name: xml.Name{Local: prefix + ":RoutePointExtension"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := maybeEmitStringElement(e, prefix+":Subclass", rpe.Subclass); err != nil {
+		return err
+	}
+	for _, rpt := range rpe.Rpt {
+		rptStart := xml.StartElement{
+			Name: xml.Name{Local: prefix + ":rpt"},
+			Attr: (&WptType{Lat: rpt.Lat, Lon: rpt.Lon}).latLonAttrs(),
+		}
+		if err := e.EncodeToken(rptStart); err != nil {
+			return err
+		}
+		if err := maybeEmitStringElement(e, prefix+":Subclass", rpt.Subclass); err != nil {
+			return err
+		}
+		if err := e.EncodeToken(rptStart.End()); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+func (te *TrackExtension) encode(e *xml.Encoder, extensions *ExtensionsType) error {
+	prefix := extensions.prefix(GpxExtensionsV3Namespace, "gpxx")
+	start := xml.StartElement{Name: xml.Name{Local: prefix + ":TrackExtension"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := maybeEmitStringElement(e, prefix+":DisplayColor", string(te.DisplayColor)); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
@@ -0,0 +1,367 @@
+package gpx_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	gpx "github.com/twpayne/go-gpx"
+)
+
+func TestTrackPointExtension(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		data     string
+		expected *gpx.TrackPointExtension
+	}{
+		{
+			name: "none",
+			data: `<gpx version="1.1"><trk><trkseg><trkpt lat="1" lon="2"></trkpt></trkseg></trk></gpx>`,
+		},
+		{
+			name: "v1",
+			data: `<gpx version="1.1" xmlns:ns3="http://www.garmin.com/xmlschemas/TrackPointExtension/v1">` +
+				`<trk><trkseg><trkpt lat="1" lon="2"><extensions>` +
+				`<ns3:TrackPointExtension><ns3:atemp>21.5</ns3:atemp><ns3:hr>131</ns3:hr><ns3:cad>88</ns3:cad></ns3:TrackPointExtension>` +
+				`</extensions></trkpt></trkseg></trk></gpx>`,
+			expected: &gpx.TrackPointExtension{
+				Version: 1,
+				ATemp:   21.5,
+				HR:      131,
+				Cad:     88,
+				Present: gpx.TrackPointExtensionATemp | gpx.TrackPointExtensionHR | gpx.TrackPointExtensionCad,
+			},
+		},
+		{
+			name: "v2",
+			data: `<gpx version="1.1" xmlns:gpxtpx="http://www.garmin.com/xmlschemas/TrackPointExtension/v2">` +
+				`<trk><trkseg><trkpt lat="1" lon="2"><extensions>` +
+				`<foo/><gpxtpx:TrackPointExtension><gpxtpx:wtemp>12</gpxtpx:wtemp><gpxtpx:depth>3.5</gpxtpx:depth><gpxtpx:speed>4.25</gpxtpx:speed><gpxtpx:course>270</gpxtpx:course></gpxtpx:TrackPointExtension>` +
+				`</extensions></trkpt></trkseg></trk></gpx>`,
+			expected: &gpx.TrackPointExtension{
+				Version: 2,
+				WTemp:   12,
+				Depth:   3.5,
+				Speed:   4.25,
+				Course:  270,
+				Present: gpx.TrackPointExtensionWTemp | gpx.TrackPointExtensionDepth | gpx.TrackPointExtensionSpeed | gpx.TrackPointExtensionCourse,
+			},
+		},
+		{
+			name: "trk_namespace",
+			data: `<gpx version="1.1">` +
+				`<trk xmlns:ns3="http://www.garmin.com/xmlschemas/TrackPointExtension/v1"><trkseg><trkpt lat="1" lon="2"><extensions>` +
+				`<ns3:TrackPointExtension><ns3:hr>97</ns3:hr></ns3:TrackPointExtension>` +
+				`</extensions></trkpt></trkseg></trk></gpx>`,
+			expected: &gpx.TrackPointExtension{
+				Version: 1,
+				HR:      97,
+				Present: gpx.TrackPointExtensionHR,
+			},
+		},
+		{
+			name: "trkpt_namespace",
+			data: `<gpx version="1.1">` +
+				`<trk><trkseg><trkpt lat="1" lon="2" xmlns:ns3="http://www.garmin.com/xmlschemas/TrackPointExtension/v1"><extensions>` +
+				`<ns3:TrackPointExtension><ns3:hr>98</ns3:hr></ns3:TrackPointExtension>` +
+				`</extensions></trkpt></trkseg></trk></gpx>`,
+			expected: &gpx.TrackPointExtension{
+				Version: 1,
+				HR:      98,
+				Present: gpx.TrackPointExtensionHR,
+			},
+		},
+		{
+			name: "extensions_namespace",
+			data: `<gpx version="1.1" xmlns:ns3="http://www.garmin.com/xmlschemas/TrackPointExtension/v1">` +
+				`<trk><trkseg><trkpt lat="1" lon="2"><extensions xmlns:ns3="http://www.garmin.com/xmlschemas/TrackPointExtension/v2">` +
+				`<ns3:TrackPointExtension><ns3:hr>99</ns3:hr></ns3:TrackPointExtension>` +
+				`</extensions></trkpt></trkseg></trk></gpx>`,
+			expected: &gpx.TrackPointExtension{
+				Version: 2,
+				HR:      99,
+				Present: gpx.TrackPointExtensionHR,
+			},
+		},
+		{
+			name: "inline_namespace",
+			data: `<gpx version="1.1">` +
+				`<trk><trkseg><trkpt lat="1" lon="2"><extensions>` +
+				`<TrackPointExtension xmlns="http://www.garmin.com/xmlschemas/TrackPointExtension/v1"><hr>99</hr></TrackPointExtension>` +
+				`</extensions></trkpt></trkseg></trk></gpx>`,
+			expected: &gpx.TrackPointExtension{
+				Version: 1,
+				HR:      99,
+				Present: gpx.TrackPointExtensionHR,
+			},
+		},
+		{
+			name: "other_namespace",
+			data: `<gpx version="1.1" xmlns:foo="http://example.com/foo">` +
+				`<trk><trkseg><trkpt lat="1" lon="2"><extensions>` +
+				`<foo:TrackPointExtension><foo:hr>99</foo:hr></foo:TrackPointExtension>` +
+				`</extensions></trkpt></trkseg></trk></gpx>`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g, err := gpx.Read(bytes.NewBufferString(tc.data))
+			assert.NoError(t, err)
+			tpe, err := g.Trk[0].TrkSeg[0].TrkPt[0].TrackPointExtension()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, tpe)
+		})
+	}
+}
+
+func TestSetTrackPointExtension(t *testing.T) {
+	trkPt := &gpx.WptType{
+		Lat: 1,
+		Lon: 2,
+		Extensions: &gpx.ExtensionsType{
+			XML: []byte("<foo/>"),
+		},
+	}
+	tpe := &gpx.TrackPointExtension{
+		Version: 2,
+		HR:      120,
+		Speed:   3.5,
+		Present: gpx.TrackPointExtensionHR | gpx.TrackPointExtensionSpeed,
+	}
+	assert.NoError(t, trkPt.SetTrackPointExtension(&gpx.TrackPointExtension{Version: 1, HR: 100}))
+	assert.Equal(t, map[string]string{"gpxtpx": gpx.TrackPointExtensionV1Namespace}, trkPt.Extensions.Namespaces)
+	assert.NoError(t, trkPt.SetTrackPointExtension(tpe))
+	assert.Equal(t, "<foo/><gpxtpx:TrackPointExtension><gpxtpx:hr>120</gpxtpx:hr><gpxtpx:speed>3.5</gpxtpx:speed></gpxtpx:TrackPointExtension>", string(trkPt.Extensions.XML))
+	assert.Equal(t, map[string]string{"gpxtpx": gpx.TrackPointExtensionV2Namespace}, trkPt.Extensions.Namespaces)
+
+	g := &gpx.GPX{
+		Version: "1.1",
+		Trk: []*gpx.TrkType{
+			{
+				TrkSeg: []*gpx.TrkSegType{
+					{
+						TrkPt: []*gpx.WptType{trkPt},
+					},
+				},
+			},
+		},
+	}
+	sb := &strings.Builder{}
+	assert.NoError(t, g.Write(sb))
+	assert.Contains(t, sb.String(), ` xmlns:gpxtpx="http://www.garmin.com/xmlschemas/TrackPointExtension/v2">`)
+	assert.NotContains(t, sb.String(), gpx.TrackPointExtensionV1Namespace)
+
+	g2, err := gpx.Read(strings.NewReader(sb.String()))
+	assert.NoError(t, err)
+	gotTPE, err := g2.Trk[0].TrkSeg[0].TrkPt[0].TrackPointExtension()
+	assert.NoError(t, err)
+	assert.Equal(t, tpe, gotTPE)
+
+	assert.NoError(t, trkPt.SetTrackPointExtension(nil))
+	assert.Equal(t, "<foo/>", string(trkPt.Extensions.XML))
+}
+
+func TestTrackPointExtensionZeroValues(t *testing.T) {
+	trkPt := &gpx.WptType{Lat: 1, Lon: 2}
+	tpe := &gpx.TrackPointExtension{
+		Version: 2,
+		Present: gpx.TrackPointExtensionATemp | gpx.TrackPointExtensionDepth | gpx.TrackPointExtensionCad | gpx.TrackPointExtensionSpeed | gpx.TrackPointExtensionCourse | gpx.TrackPointExtensionBearing,
+	}
+	assert.NoError(t, trkPt.SetTrackPointExtension(tpe))
+	assert.Equal(t, "<gpxtpx:TrackPointExtension>"+
+		"<gpxtpx:atemp>0</gpxtpx:atemp><gpxtpx:depth>0</gpxtpx:depth><gpxtpx:cad>0</gpxtpx:cad>"+
+		"<gpxtpx:speed>0</gpxtpx:speed><gpxtpx:course>0</gpxtpx:course><gpxtpx:bearing>0</gpxtpx:bearing>"+
+		"</gpxtpx:TrackPointExtension>", string(trkPt.Extensions.XML))
+
+	gotTPE, err := trkPt.TrackPointExtension()
+	assert.NoError(t, err)
+	assert.Equal(t, tpe, gotTPE)
+	assert.True(t, gotTPE.Has(gpx.TrackPointExtensionATemp))
+	assert.False(t, gotTPE.Has(gpx.TrackPointExtensionHR))
+}
+
+func TestPowerExtension(t *testing.T) {
+	g, err := gpx.Read(strings.NewReader(`<gpx version="1.1" xmlns:pwr="http://www.garmin.com/xmlschemas/PowerExtension/v1">` +
+		`<trk><trkseg><trkpt lat="1" lon="2"><extensions>` +
+		`<pwr:PowerExtension><pwr:PowerInWatts>250</pwr:PowerInWatts></pwr:PowerExtension>` +
+		`</extensions></trkpt></trkseg></trk></gpx>`))
+	assert.NoError(t, err)
+	trkPt := g.Trk[0].TrkSeg[0].TrkPt[0]
+	pe, err := trkPt.PowerExtension()
+	assert.NoError(t, err)
+	assert.Equal(t, &gpx.PowerExtension{PowerInWatts: 250}, pe)
+
+	assert.NoError(t, trkPt.SetPowerExtension(&gpx.PowerExtension{PowerInWatts: 300}))
+	assert.Equal(t, "<pwr:PowerExtension><pwr:PowerInWatts>300</pwr:PowerInWatts></pwr:PowerExtension>", string(trkPt.Extensions.XML))
+
+	assert.NoError(t, trkPt.SetPowerExtension(nil))
+	pe, err = trkPt.PowerExtension()
+	assert.NoError(t, err)
+	assert.Zero(t, pe)
+}
+
+func TestGpxExtensionsV3(t *testing.T) {
+	g, err := gpx.Read(strings.NewReader(`<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="Garmin BaseCamp" xmlns="http://www.topografix.com/GPX/1/1" xmlns:gpxx="http://www.garmin.com/xmlschemas/GpxExtensions/v3">
+  <wpt lat="47.1" lon="8.5">
+    <name>Cafe</name>
+    <extensions>
+      <gpxx:WaypointExtension>
+        <gpxx:Proximity>50</gpxx:Proximity>
+        <gpxx:DisplayMode>SymbolAndName</gpxx:DisplayMode>
+        <gpxx:Categories>
+          <gpxx:Category>Food</gpxx:Category>
+          <gpxx:Category>Coffee</gpxx:Category>
+        </gpxx:Categories>
+        <gpxx:Address>
+          <gpxx:StreetAddress>Bahnhofstrasse 1</gpxx:StreetAddress>
+          <gpxx:City>Zürich</gpxx:City>
+          <gpxx:Country>Switzerland</gpxx:Country>
+          <gpxx:PostalCode>8001</gpxx:PostalCode>
+        </gpxx:Address>
+        <gpxx:PhoneNumber Category="Phone">+41 44 000 00 00</gpxx:PhoneNumber>
+      </gpxx:WaypointExtension>
+    </extensions>
+  </wpt>
+  <rte>
+    <name>Route</name>
+    <extensions>
+      <gpxx:RouteExtension>
+        <gpxx:IsAutoNamed>false</gpxx:IsAutoNamed>
+        <gpxx:DisplayColor>Magenta</gpxx:DisplayColor>
+      </gpxx:RouteExtension>
+    </extensions>
+    <rtept lat="47.1" lon="8.5">
+      <extensions>
+        <gpxx:RoutePointExtension>
+          <gpxx:Subclass>000000000000FFFFFFFFFFFFFFFFFFFFFFFF</gpxx:Subclass>
+          <gpxx:rpt lat="47.11" lon="8.51">
+            <gpxx:Subclass>0300ED1E0200F32E0000</gpxx:Subclass>
+          </gpxx:rpt>
+          <gpxx:rpt lat="47.12" lon="8.52"/>
+        </gpxx:RoutePointExtension>
+      </extensions>
+    </rtept>
+    <rtept lat="47.2" lon="8.6"/>
+  </rte>
+  <trk>
+    <extensions>
+      <gpxx:TrackExtension>
+        <gpxx:DisplayColor>DarkBlue</gpxx:DisplayColor>
+      </gpxx:TrackExtension>
+    </extensions>
+  </trk>
+</gpx>`))
+	assert.NoError(t, err)
+
+	we, err := g.Wpt[0].WaypointExtension()
+	assert.NoError(t, err)
+	assert.Equal(t, &gpx.WaypointExtension{
+		Proximity:   50,
+		DisplayMode: "SymbolAndName",
+		Categories:  []string{"Food", "Coffee"},
+		Address: &gpx.AddressType{
+			StreetAddress: []string{"Bahnhofstrasse 1"},
+			City:          "Zürich",
+			Country:       "Switzerland",
+			PostalCode:    "8001",
+		},
+		PhoneNumbers: []*gpx.PhoneNumberType{
+			{
+				Category: "Phone",
+				Number:   "+41 44 000 00 00",
+			},
+		},
+		Present: gpx.WaypointExtensionProximity,
+	}, we)
+
+	re, err := g.Rte[0].RouteExtension()
+	assert.NoError(t, err)
+	assert.Equal(t, &gpx.RouteExtension{DisplayColor: gpx.DisplayColorMagenta}, re)
+
+	rpe, err := g.Rte[0].RtePt[0].RoutePointExtension()
+	assert.NoError(t, err)
+	assert.Equal(t, &gpx.RoutePointExtension{
+		Subclass: "000000000000FFFFFFFFFFFFFFFFFFFFFFFF",
+		Rpt: []*gpx.AutoroutePointType{
+			{Lat: 47.11, Lon: 8.51, Subclass: "0300ED1E0200F32E0000"},
+			{Lat: 47.12, Lon: 8.52},
+		},
+	}, rpe)
+
+	rtePts, err := g.Rte[0].AutoroutedRtePt()
+	assert.NoError(t, err)
+	assert.Equal(t, []*gpx.WptType{
+		g.Rte[0].RtePt[0],
+		{Lat: 47.11, Lon: 8.51},
+		{Lat: 47.12, Lon: 8.52},
+		g.Rte[0].RtePt[1],
+	}, rtePts)
+
+	te, err := g.Trk[0].TrackExtension()
+	assert.NoError(t, err)
+	assert.Equal(t, &gpx.TrackExtension{DisplayColor: gpx.DisplayColorDarkBlue}, te)
+
+	rpe2, err := g.Rte[0].RtePt[1].RoutePointExtension()
+	assert.NoError(t, err)
+	assert.Zero(t, rpe2)
+}
+
+func TestSetGpxExtensionsV3(t *testing.T) {
+	wpt := &gpx.WptType{Lat: 1, Lon: 2}
+	we := &gpx.WaypointExtension{
+		Proximity:  10,
+		Categories: []string{"A"},
+		Address: &gpx.AddressType{
+			StreetAddress: []string{"1 Main St", "Unit 2"},
+			City:          "City",
+		},
+		PhoneNumbers: []*gpx.PhoneNumberType{
+			{Number: "123"},
+		},
+		Present: gpx.WaypointExtensionProximity | gpx.WaypointExtensionTemperature,
+	}
+	assert.NoError(t, wpt.SetWaypointExtension(we))
+	rtePt := &gpx.WptType{Lat: 3, Lon: 4}
+	rpe := &gpx.RoutePointExtension{
+		Subclass: "0000",
+		Rpt: []*gpx.AutoroutePointType{
+			{Lat: 3.5, Lon: 4.5, Subclass: "0001"},
+		},
+	}
+	assert.NoError(t, rtePt.SetRoutePointExtension(rpe))
+	rte := &gpx.RteType{RtePt: []*gpx.WptType{rtePt}}
+	re := &gpx.RouteExtension{IsAutoNamed: true, DisplayColor: gpx.DisplayColorRed}
+	assert.NoError(t, rte.SetRouteExtension(re))
+	trk := &gpx.TrkType{Name: "trk"}
+	te := &gpx.TrackExtension{DisplayColor: gpx.DisplayColorGreen}
+	assert.Error(t, trk.SetTrackExtension(&gpx.TrackExtension{DisplayColor: "Pink"}))
+	assert.NoError(t, trk.SetTrackExtension(te))
+	assert.Equal(t, "<gpxx:TrackExtension><gpxx:DisplayColor>Green</gpxx:DisplayColor></gpxx:TrackExtension>", string(trk.Extensions.XML))
+
+	sb := &strings.Builder{}
+	assert.NoError(t, (&gpx.GPX{
+		Version: "1.1",
+		Wpt:     []*gpx.WptType{wpt},
+		Rte:     []*gpx.RteType{rte},
+		Trk:     []*gpx.TrkType{trk},
+	}).Write(sb))
+	assert.Contains(t, sb.String(), ` xmlns:gpxx="http://www.garmin.com/xmlschemas/GpxExtensions/v3">`)
+	assert.Contains(t, sb.String(), `<gpxx:Proximity>10</gpxx:Proximity><gpxx:Temperature>0</gpxx:Temperature><gpxx:Categories>`)
+
+	g, err := gpx.Read(strings.NewReader(sb.String()))
+	assert.NoError(t, err)
+	gotWE, err := g.Wpt[0].WaypointExtension()
+	assert.NoError(t, err)
+	assert.Equal(t, we, gotWE)
+	gotRPE, err := g.Rte[0].RtePt[0].RoutePointExtension()
+	assert.NoError(t, err)
+	assert.Equal(t, rpe, gotRPE)
+	gotRE, err := g.Rte[0].RouteExtension()
+	assert.NoError(t, err)
+	assert.Equal(t, re, gotRE)
+	gotTE, err := g.Trk[0].TrackExtension()
+	assert.NoError(t, err)
+	assert.Equal(t, te, gotTE)
+}
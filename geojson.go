@@ -0,0 +1,719 @@
+package gpx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+)
+
+var (
+	errInvalidProperty     = errors.New("invalid property")
+	errUnsupportedGeometry = errors.New("unsupported geometry")
+)
+
+// A geoJSONWptProperty maps a WptType field to a GeoJSON property.
+type geoJSONWptProperty struct {
+	key string
+	has func(w *WptType) bool
+	get func(w *WptType) (any, error)
+	set func(w *WptType, value any) error
+}
+
+var geoJSONWptProperties = []geoJSONWptProperty{
+	geoJSONFloatProperty("ele", WptEle, func(w *WptType) *float64 { return &w.Ele }),
+	geoJSONFloatProperty("speed", WptSpeed, func(w *WptType) *float64 { return &w.Speed }),
+	geoJSONFloatProperty("course", WptCourse, func(w *WptType) *float64 { return &w.Course }),
+	{
+		key: "time",
+		has: func(w *WptType) bool {
+			return !w.Time.IsZero()
+		},
+		get: func(w *WptType) (any, error) {
+			return w.Time.Format(time.RFC3339Nano), nil
+		},
+		set: func(w *WptType, value any) error {
+			s, ok := value.(string)
+			if !ok {
+				return errInvalidProperty
+			}
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return err
+			}
+			w.Time = t
+			return nil
+		},
+	},
+	geoJSONFloatProperty("magvar", WptMagVar, func(w *WptType) *float64 { return &w.MagVar }),
+	geoJSONFloatProperty("geoidheight", WptGeoidHeight, func(w *WptType) *float64 { return &w.GeoidHeight }),
+	geoJSONStringProperty("name", func(w *WptType) *string { return &w.Name }),
+	geoJSONStringProperty("cmt", func(w *WptType) *string { return &w.Cmt }),
+	geoJSONStringProperty("desc", func(w *WptType) *string { return &w.Desc }),
+	geoJSONStringProperty("src", func(w *WptType) *string { return &w.Src }),
+	{
+		key: "links",
+		has: func(w *WptType) bool {
+			return len(w.Link) > 0
+		},
+		get: func(w *WptType) (any, error) {
+			return geoJSONLinks(w.Link), nil
+		},
+		set: func(w *WptType, value any) error {
+			links, err := parseGeoJSONLinks(value)
+			w.Link = links
+			return err
+		},
+	},
+	geoJSONStringProperty("sym", func(w *WptType) *string { return &w.Sym }),
+	geoJSONStringProperty("type", func(w *WptType) *string { return &w.Type }),
+	geoJSONStringProperty("fix", func(w *WptType) *string { return &w.Fix }),
+	{
+		key: "sat",
+		has: func(w *WptType) bool {
+			return w.Has(WptSat)
+		},
+		get: func(w *WptType) (any, error) {
+			return w.Sat, nil
+		},
+		set: func(w *WptType, value any) error {
+			sat, ok := geoJSONFloat(value)
+			if !ok {
+				return errInvalidProperty
+			}
+			w.Sat = int(sat)
+			w.Present |= WptSat
+			return nil
+		},
+	},
+	geoJSONFloatProperty("hdop", WptHDOP, func(w *WptType) *float64 { return &w.HDOP }),
+	geoJSONFloatProperty("vdop", WptVDOP, func(w *WptType) *float64 { return &w.VDOP }),
+	geoJSONFloatProperty("pdop", WptPDOP, func(w *WptType) *float64 { return &w.PDOP }),
+	geoJSONFloatProperty("ageofdgpsdata", WptAgeOfDGPSData, func(w *WptType) *float64 { return &w.AgeOfDGPSData }),
+	{
+		key: "dgpsid",
+		has: func(w *WptType) bool {
+			return len(w.DGPSID) > 0
+		},
+		get: func(w *WptType) (any, error) {
+			dgpsIDs := make([]any, 0, len(w.DGPSID))
+			for _, dgpsID := range w.DGPSID {
+				dgpsIDs = append(dgpsIDs, dgpsID)
+			}
+			return dgpsIDs, nil
+		},
+		set: func(w *WptType, value any) error {
+			values, ok := value.([]any)
+			if !ok {
+				return errInvalidProperty
+			}
+			w.DGPSID = make([]int, 0, len(values))
+			for _, value := range values {
+				dgpsID, ok := geoJSONFloat(value)
+				if !ok {
+					return errInvalidProperty
+				}
+				w.DGPSID = append(w.DGPSID, int(dgpsID))
+			}
+			return nil
+		},
+	},
+	{
+		key: "extensions",
+		has: func(w *WptType) bool {
+			return w.Extensions != nil
+		},
+		get: func(w *WptType) (any, error) {
+			return geoJSONExtensions(w.Extensions)
+		},
+		set: func(w *WptType, value any) error {
+			extensions, err := parseGeoJSONExtensions(value)
+			w.Extensions = extensions
+			return err
+		},
+	},
+}
+
+// GeoJSON returns g as a GeoJSON FeatureCollection. Waypoints become Point
+// features, routes become LineString features, and tracks become
+// MultiLineString features, each with a gpxType property of "wpt", "rte", or
+// "trk". All other fields are mapped to properties named after their GPX
+// elements, with links as an array of objects with href, text, and type
+// members, and extensions as an object with xml and namespaces members.
+//
+// Elevations are stored as the third coordinate of points, or of all points in
+// a route or track if they all have elevations. The values of each field of
+// route and track points are stored in arrays, or arrays of arrays for each
+// track segment, in the coordinateProperties property, with times in its
+// times member. Values that are not present are null. The extensions of track
+// segments are stored in the trkSegExtensions property.
+//
+// Metadata and extensions of g itself are not included. options are applied
+// as when writing g, so typed extension values are encoded with the registry
+// passed to WithWriteExtensionRegistry.
+func (g *GPX) GeoJSON(options ...WriteOption) (*geojson.FeatureCollection, error) {
+	g, err := g.withWriteOptions(newWriteOptions(options))
+	if err != nil {
+		return nil, err
+	}
+	fc := &geojson.FeatureCollection{
+		Features: make([]*geojson.Feature, 0, len(g.Wpt)+len(g.Rte)+len(g.Trk)),
+	}
+	for _, wpt := range g.Wpt {
+		layout := geom.XY
+		if wpt.Has(WptEle) {
+			layout = geom.XYZ
+		}
+		properties, err := geoJSONWptPropertyValues(wpt, layout == geom.XY)
+		if err != nil {
+			return nil, err
+		}
+		properties["gpxType"] = "wpt"
+		fc.Features = append(fc.Features, &geojson.Feature{
+			Geometry:   wpt.Geom(layout),
+			Properties: properties,
+		})
+	}
+	for _, rte := range g.Rte {
+		properties, err := rte.geoJSONProperties()
+		if err != nil {
+			return nil, err
+		}
+		properties["gpxType"] = "rte"
+		layout := eleLayout(rte.RtePt)
+		coordinateProperties, err := geoJSONCoordinateProperties(rte.RtePt, layout == geom.XY)
+		if err != nil {
+			return nil, err
+		}
+		if len(coordinateProperties) > 0 {
+			properties["coordinateProperties"] = coordinateProperties
+		}
+		fc.Features = append(fc.Features, &geojson.Feature{
+			Geometry:   rte.Geom(layout),
+			Properties: properties,
+		})
+	}
+	for _, trk := range g.Trk {
+		feature, err := trk.geoJSONFeature()
+		if err != nil {
+			return nil, err
+		}
+		fc.Features = append(fc.Features, feature)
+	}
+	return fc, nil
+}
+
+// NewGPXFromGeoJSON returns a new GPX from the features in fc, reversing
+// GPX.GeoJSON. Point features become waypoints, LineString features become
+// routes if their gpxType property is "rte" and single segment tracks
+// otherwise, and MultiLineString features become tracks. Of options, only
+// WithExtensionRegistry is used.
+func NewGPXFromGeoJSON(fc *geojson.FeatureCollection, options ...ReadOption) (*GPX, error) {
+	g := &GPX{
+		Version: "1.1",
+	}
+	for i, feature := range fc.Features {
+		if err := g.addGeoJSONFeature(feature); err != nil {
+			return nil, fmt.Errorf("feature %d: %w", i, err)
+		}
+	}
+	o := newReadOptions(options)
+	for extensions := range g.allExtensions() {
+		if err := extensions.decodeTyped(o.extensionRegistry); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// addGeoJSONFeature adds feature to g.
+func (g *GPX) addGeoJSONFeature(feature *geojson.Feature) error {
+	gpxType, _ := feature.Properties["gpxType"].(string)
+	switch geometry := feature.Geometry.(type) {
+	case *geom.Point:
+		wpt := NewWptType(geometry)
+		if err := setGeoJSONWptProperties(wpt, feature.Properties); err != nil {
+			return err
+		}
+		g.Wpt = append(g.Wpt, wpt)
+	case *geom.LineString:
+		rte := &RteType{}
+		if err := rte.setGeoJSONProperties(feature.Properties); err != nil {
+			return err
+		}
+		wpts := newWptTypes(geometry)
+		coordinateProperties, _ := feature.Properties["coordinateProperties"].(map[string]any)
+		if err := setGeoJSONCoordinateProperties(wpts, coordinateProperties, -1); err != nil {
+			return err
+		}
+		if gpxType == "rte" {
+			rte.RtePt = wpts
+			g.Rte = append(g.Rte, rte)
+			return nil
+		}
+		trk := rte.trkType()
+		trk.TrkSeg = []*TrkSegType{
+			{
+				TrkPt: wpts,
+			},
+		}
+		g.Trk = append(g.Trk, trk)
+	case *geom.MultiLineString:
+		trk, err := newTrkTypeFromGeoJSON(geometry, feature.Properties)
+		if err != nil {
+			return err
+		}
+		g.Trk = append(g.Trk, trk)
+	default:
+		return fmt.Errorf("%T: %w", geometry, errUnsupportedGeometry)
+	}
+	return nil
+}
+
+// geoJSONProperties returns the GeoJSON properties of r's fields, excluding
+// its points.
+func (r *RteType) geoJSONProperties() (map[string]any, error) {
+	properties := make(map[string]any)
+	for key, value := range map[string]string{
+		"name": r.Name,
+		"cmt":  r.Cmt,
+		"desc": r.Desc,
+		"src":  r.Src,
+		"type": r.Type,
+	} {
+		if value != "" {
+			properties[key] = value
+		}
+	}
+	if links := geoJSONLinks(r.Link); links != nil {
+		properties["links"] = links
+	}
+	if r.Number != 0 {
+		properties["number"] = r.Number
+	}
+	if r.Extensions != nil {
+		extensions, err := geoJSONExtensions(r.Extensions)
+		if err != nil {
+			return nil, err
+		}
+		properties["extensions"] = extensions
+	}
+	return properties, nil
+}
+
+// setGeoJSONProperties sets r's fields, excluding its points, from
+// properties.
+func (r *RteType) setGeoJSONProperties(properties map[string]any) error {
+	for key, field := range map[string]*string{
+		"name": &r.Name,
+		"cmt":  &r.Cmt,
+		"desc": &r.Desc,
+		"src":  &r.Src,
+		"type": &r.Type,
+	} {
+		switch value := properties[key].(type) {
+		case nil:
+		case string:
+			*field = value
+		default:
+			return fmt.Errorf("%s: %w", key, errInvalidProperty)
+		}
+	}
+	if value, ok := properties["links"]; ok && value != nil {
+		links, err := parseGeoJSONLinks(value)
+		if err != nil {
+			return fmt.Errorf("links: %w", err)
+		}
+		r.Link = links
+	}
+	if value, ok := properties["number"]; ok && value != nil {
+		number, ok := geoJSONFloat(value)
+		if !ok {
+			return fmt.Errorf("number: %w", errInvalidProperty)
+		}
+		r.Number = int(number)
+	}
+	if value, ok := properties["extensions"]; ok && value != nil {
+		extensions, err := parseGeoJSONExtensions(value)
+		if err != nil {
+			return fmt.Errorf("extensions: %w", err)
+		}
+		r.Extensions = extensions
+	}
+	return nil
+}
+
+// trkType returns a new TrkType with r's fields, excluding its points.
+func (r *RteType) trkType() *TrkType {
+	return &TrkType{
+		Name:       r.Name,
+		Cmt:        r.Cmt,
+		Desc:       r.Desc,
+		Src:        r.Src,
+		Link:       r.Link,
+		Number:     r.Number,
+		Type:       r.Type,
+		Extensions: r.Extensions,
+	}
+}
+
+// geoJSONFeature returns t as a GeoJSON feature.
+func (t *TrkType) geoJSONFeature() (*geojson.Feature, error) {
+	header := &RteType{
+		Name:       t.Name,
+		Cmt:        t.Cmt,
+		Desc:       t.Desc,
+		Src:        t.Src,
+		Link:       t.Link,
+		Number:     t.Number,
+		Type:       t.Type,
+		Extensions: t.Extensions,
+	}
+	properties, err := header.geoJSONProperties()
+	if err != nil {
+		return nil, err
+	}
+	properties["gpxType"] = "trk"
+	var trkPts []*WptType
+	for _, trkSeg := range t.TrkSeg {
+		trkPts = append(trkPts, trkSeg.TrkPt...)
+	}
+	layout := eleLayout(trkPts)
+	segCoordinateProperties := make([]map[string]any, 0, len(t.TrkSeg))
+	keys := make(map[string]struct{})
+	trkSegExtensions := make([]any, 0, len(t.TrkSeg))
+	hasTrkSegExtensions := false
+	for _, trkSeg := range t.TrkSeg {
+		coordinateProperties, err := geoJSONCoordinateProperties(trkSeg.TrkPt, layout == geom.XY)
+		if err != nil {
+			return nil, err
+		}
+		for key := range coordinateProperties {
+			keys[key] = struct{}{}
+		}
+		segCoordinateProperties = append(segCoordinateProperties, coordinateProperties)
+		if trkSeg.Extensions == nil {
+			trkSegExtensions = append(trkSegExtensions, nil)
+			continue
+		}
+		extensions, err := geoJSONExtensions(trkSeg.Extensions)
+		if err != nil {
+			return nil, err
+		}
+		trkSegExtensions = append(trkSegExtensions, extensions)
+		hasTrkSegExtensions = true
+	}
+	if len(keys) > 0 {
+		coordinateProperties := make(map[string]any, len(keys))
+		for key := range keys {
+			values := make([]any, 0, len(t.TrkSeg))
+			for i, trkSeg := range t.TrkSeg {
+				if segValues, ok := segCoordinateProperties[i][key]; ok {
+					values = append(values, segValues)
+				} else {
+					values = append(values, make([]any, len(trkSeg.TrkPt)))
+				}
+			}
+			coordinateProperties[key] = values
+		}
+		properties["coordinateProperties"] = coordinateProperties
+	}
+	if hasTrkSegExtensions {
+		properties["trkSegExtensions"] = trkSegExtensions
+	}
+	return &geojson.Feature{
+		Geometry:   t.Geom(layout),
+		Properties: properties,
+	}, nil
+}
+
+// newTrkTypeFromGeoJSON returns a new TrkType from a GeoJSON MultiLineString
+// feature's geometry and properties.
+func newTrkTypeFromGeoJSON(geometry *geom.MultiLineString, properties map[string]any) (*TrkType, error) {
+	header := &RteType{}
+	if err := header.setGeoJSONProperties(properties); err != nil {
+		return nil, err
+	}
+	trk := header.trkType()
+	coordinateProperties, _ := properties["coordinateProperties"].(map[string]any)
+	trkSegExtensions, _ := properties["trkSegExtensions"].([]any)
+	for i := range geometry.NumLineStrings() {
+		trkSeg := &TrkSegType{
+			TrkPt: newWptTypes(geometry.LineString(i)),
+		}
+		if err := setGeoJSONCoordinateProperties(trkSeg.TrkPt, coordinateProperties, i); err != nil {
+			return nil, err
+		}
+		if i < len(trkSegExtensions) && trkSegExtensions[i] != nil {
+			extensions, err := parseGeoJSONExtensions(trkSegExtensions[i])
+			if err != nil {
+				return nil, fmt.Errorf("trkSegExtensions: %w", err)
+			}
+			trkSeg.Extensions = extensions
+		}
+		trk.TrkSeg = append(trk.TrkSeg, trkSeg)
+	}
+	return trk, nil
+}
+
+// geoJSONCoordinateKey returns the coordinateProperties key for key.
+func geoJSONCoordinateKey(key string) string {
+	if key == "time" {
+		return "times"
+	}
+	return key
+}
+
+// geoJSONCoordinateProperties returns the values of each property of wpts
+// that is present in any of wpts, keyed by coordinateProperties key.
+func geoJSONCoordinateProperties(wpts []*WptType, includeEle bool) (map[string]any, error) {
+	coordinateProperties := make(map[string]any)
+	for _, property := range geoJSONWptProperties {
+		if property.key == "ele" && !includeEle {
+			continue
+		}
+		values := make([]any, 0, len(wpts))
+		present := false
+		for _, wpt := range wpts {
+			if !property.has(wpt) {
+				values = append(values, nil)
+				continue
+			}
+			value, err := property.get(wpt)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+			present = true
+		}
+		if present {
+			coordinateProperties[geoJSONCoordinateKey(property.key)] = values
+		}
+	}
+	return coordinateProperties, nil
+}
+
+// geoJSONExtensions returns x as a GeoJSON property value.
+func geoJSONExtensions(x *ExtensionsType) (map[string]any, error) {
+	if len(x.Typed) > 0 {
+		return nil, fmt.Errorf("%T: %w", x.Typed[0], errUnregisteredExtension)
+	}
+	namespaces := make(map[string]any, len(x.Namespaces))
+	for prefix, namespace := range x.Namespaces {
+		namespaces[prefix] = namespace
+	}
+	extensions := map[string]any{
+		"xml": string(x.XML),
+	}
+	if len(namespaces) > 0 {
+		extensions["namespaces"] = namespaces
+	}
+	return extensions, nil
+}
+
+// geoJSONFloat returns value as a float64, if it is a number.
+func geoJSONFloat(value any) (float64, bool) {
+	switch value := value.(type) {
+	case float64:
+		return value, true
+	case int:
+		return float64(value), true
+	case json.Number:
+		f, err := value.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// geoJSONFloatProperty returns a geoJSONWptProperty for the optional float
+// field f.
+func geoJSONFloatProperty(key string, f WptField, field func(*WptType) *float64) geoJSONWptProperty {
+	return geoJSONWptProperty{
+		key: key,
+		has: func(w *WptType) bool {
+			return w.Has(f)
+		},
+		get: func(w *WptType) (any, error) {
+			return *field(w), nil
+		},
+		set: func(w *WptType, value any) error {
+			v, ok := geoJSONFloat(value)
+			if !ok {
+				return errInvalidProperty
+			}
+			*field(w) = v
+			w.Present |= f
+			return nil
+		},
+	}
+}
+
+// geoJSONLinks returns links as a GeoJSON property value, or nil if links is
+// empty.
+func geoJSONLinks(links []*LinkType) any {
+	if len(links) == 0 {
+		return nil
+	}
+	values := make([]any, 0, len(links))
+	for _, link := range links {
+		value := map[string]any{
+			"href": link.HREF,
+		}
+		if link.Text != "" {
+			value["text"] = link.Text
+		}
+		if link.Type != "" {
+			value["type"] = link.Type
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+// geoJSONStringProperty returns a geoJSONWptProperty for the string field.
+func geoJSONStringProperty(key string, field func(*WptType) *string) geoJSONWptProperty {
+	return geoJSONWptProperty{
+		key: key,
+		has: func(w *WptType) bool {
+			return *field(w) != ""
+		},
+		get: func(w *WptType) (any, error) {
+			return *field(w), nil
+		},
+		set: func(w *WptType, value any) error {
+			s, ok := value.(string)
+			if !ok {
+				return errInvalidProperty
+			}
+			*field(w) = s
+			return nil
+		},
+	}
+}
+
+// geoJSONWptPropertyValues returns the GeoJSON properties of w, excluding its
+// position.
+func geoJSONWptPropertyValues(w *WptType, includeEle bool) (map[string]any, error) {
+	properties := make(map[string]any)
+	for _, property := range geoJSONWptProperties {
+		if property.key == "ele" && !includeEle {
+			continue
+		}
+		if !property.has(w) {
+			continue
+		}
+		value, err := property.get(w)
+		if err != nil {
+			return nil, err
+		}
+		properties[property.key] = value
+	}
+	return properties, nil
+}
+
+// parseGeoJSONExtensions returns the ExtensionsType represented by value.
+func parseGeoJSONExtensions(value any) (*ExtensionsType, error) {
+	object, ok := value.(map[string]any)
+	if !ok {
+		return nil, errInvalidProperty
+	}
+	data, ok := object["xml"].(string)
+	if !ok {
+		return nil, errInvalidProperty
+	}
+	x := &ExtensionsType{
+		XML: []byte(data),
+	}
+	if namespaces, ok := object["namespaces"].(map[string]any); ok {
+		x.Namespaces = make(map[string]string, len(namespaces))
+		for prefix, namespace := range namespaces {
+			s, ok := namespace.(string)
+			if !ok {
+				return nil, errInvalidProperty
+			}
+			x.Namespaces[prefix] = s
+		}
+	}
+	return x, nil
+}
+
+// parseGeoJSONLinks returns the links represented by value.
+func parseGeoJSONLinks(value any) ([]*LinkType, error) {
+	values, ok := value.([]any)
+	if !ok {
+		return nil, errInvalidProperty
+	}
+	links := make([]*LinkType, 0, len(values))
+	for _, value := range values {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil, errInvalidProperty
+		}
+		link := &LinkType{}
+		for key, field := range map[string]*string{
+			"href": &link.HREF,
+			"text": &link.Text,
+			"type": &link.Type,
+		} {
+			switch value := object[key].(type) {
+			case nil:
+			case string:
+				*field = value
+			default:
+				return nil, errInvalidProperty
+			}
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
+
+// setGeoJSONCoordinateProperties sets the fields of wpts from
+// coordinateProperties. If seg is not negative then each value of
+// coordinateProperties is an array of arrays and the seg-th array is used.
+func setGeoJSONCoordinateProperties(wpts []*WptType, coordinateProperties map[string]any, seg int) error {
+	for _, property := range geoJSONWptProperties {
+		key := geoJSONCoordinateKey(property.key)
+		value, ok := coordinateProperties[key]
+		if !ok || value == nil {
+			continue
+		}
+		values, ok := value.([]any)
+		if ok && seg >= 0 {
+			if seg >= len(values) {
+				return fmt.Errorf("coordinateProperties.%s: %w", key, errInvalidProperty)
+			}
+			values, ok = values[seg].([]any)
+		}
+		if !ok || len(values) != len(wpts) {
+			return fmt.Errorf("coordinateProperties.%s: %w", key, errInvalidProperty)
+		}
+		for i, value := range values {
+			if value == nil {
+				continue
+			}
+			if err := property.set(wpts[i], value); err != nil {
+				return fmt.Errorf("coordinateProperties.%s: %w", key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// setGeoJSONWptProperties sets the fields of w from properties.
+func setGeoJSONWptProperties(w *WptType, properties map[string]any) error {
+	for _, property := range geoJSONWptProperties {
+		value, ok := properties[property.key]
+		if !ok || value == nil {
+			continue
+		}
+		if err := property.set(w, value); err != nil {
+			return fmt.Errorf("%s: %w", property.key, err)
+		}
+	}
+	return nil
+}
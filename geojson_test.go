@@ -0,0 +1,170 @@
+package gpx_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	gpx "github.com/twpayne/go-gpx"
+)
+
+func TestGeoJSON(t *testing.T) {
+	t0 := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	trackPointExtensions := &gpx.ExtensionsType{
+		XML: []byte(`<gpxtpx:TrackPointExtension><gpxtpx:hr>120</gpxtpx:hr></gpxtpx:TrackPointExtension>`),
+		Namespaces: map[string]string{
+			"gpxtpx": gpx.TrackPointExtensionV1Namespace,
+		},
+	}
+	g := &gpx.GPX{
+		Version: "1.1",
+		Wpt: []*gpx.WptType{
+			{
+				Lat:     46.5,
+				Lon:     6.5,
+				Ele:     0,
+				Time:    t0,
+				Name:    "summit",
+				Desc:    "the top",
+				Link:    []*gpx.LinkType{{HREF: "https://example.com", Text: "example"}},
+				Sym:     "Summit",
+				Sat:     0,
+				DGPSID:  []int{1, 2},
+				Present: gpx.WptEle | gpx.WptSat,
+			},
+			{
+				Lat:  46.6,
+				Lon:  6.6,
+				Name: "hut",
+			},
+		},
+		Rte: []*gpx.RteType{
+			{
+				Name:   "rte",
+				Number: 2,
+				RtePt: []*gpx.WptType{
+					{Lat: 1, Lon: 2, Name: "start"},
+					{Lat: 3, Lon: 4, Ele: 100, Present: gpx.WptEle},
+				},
+			},
+		},
+		Trk: []*gpx.TrkType{
+			{
+				Name: "trk",
+				Type: "hiking",
+				TrkSeg: []*gpx.TrkSegType{
+					{
+						TrkPt: []*gpx.WptType{
+							{Lat: 1, Lon: 2, Ele: 10, Time: t0, Present: gpx.WptEle},
+							{Lat: 1.1, Lon: 2.1, Ele: 11, Time: t0.Add(time.Second), Extensions: trackPointExtensions, Present: gpx.WptEle},
+						},
+					},
+					{
+						TrkPt: []*gpx.WptType{
+							{Lat: 1.2, Lon: 2.2, Ele: 12, HDOP: 1.5, Present: gpx.WptEle | gpx.WptHDOP},
+						},
+						Extensions: &gpx.ExtensionsType{
+							XML: []byte(`<note>segment</note>`),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	fc, err := g.GeoJSON()
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(fc.Features))
+	assert.Equal(t, geom.XYZ, fc.Features[0].Geometry.Layout())
+	assert.Equal(t, "wpt", fc.Features[0].Properties["gpxType"])
+	assert.Equal(t, geom.XY, fc.Features[1].Geometry.Layout())
+	assert.Equal(t, geom.XY, fc.Features[2].Geometry.Layout())
+	assert.Equal(t, "rte", fc.Features[2].Properties["gpxType"])
+	assert.Equal(t, geom.XYZ, fc.Features[3].Geometry.Layout())
+	assert.Equal(t, "trk", fc.Features[3].Properties["gpxType"])
+	coordinateProperties, ok := fc.Features[3].Properties["coordinateProperties"].(map[string]any)
+	assert.True(t, ok)
+	assert.Equal[any](t, []any{
+		[]any{t0.Format(time.RFC3339Nano), t0.Add(time.Second).Format(time.RFC3339Nano)},
+		[]any{nil},
+	}, coordinateProperties["times"])
+	_, ok = coordinateProperties["ele"]
+	assert.False(t, ok)
+
+	data, err := json.Marshal(fc)
+	assert.NoError(t, err)
+	var actualFC geojson.FeatureCollection
+	assert.NoError(t, json.Unmarshal(data, &actualFC))
+	actual, err := gpx.NewGPXFromGeoJSON(&actualFC)
+	assert.NoError(t, err)
+	assert.Equal(t, g, actual)
+}
+
+func TestNewGPXFromGeoJSON(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		data          string
+		expected      *gpx.GPX
+		expectedError string
+	}{
+		{
+			name: "linestring",
+			data: `{"type":"FeatureCollection","features":[` +
+				`{"type":"Feature","geometry":{"type":"LineString","coordinates":[[2,1],[4,3]]},"properties":{"name":"walk","coordinateProperties":{"times":["2024-06-01T10:00:00Z",null]}}}` +
+				`]}`,
+			expected: &gpx.GPX{
+				Version: "1.1",
+				Trk: []*gpx.TrkType{
+					{
+						Name: "walk",
+						TrkSeg: []*gpx.TrkSegType{
+							{
+								TrkPt: []*gpx.WptType{
+									{Lat: 1, Lon: 2, Time: time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)},
+									{Lat: 3, Lon: 4},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid_property",
+			data: `{"type":"FeatureCollection","features":[` +
+				`{"type":"Feature","geometry":{"type":"Point","coordinates":[2,1]},"properties":{"name":1}}` +
+				`]}`,
+			expectedError: "feature 0: name: invalid property",
+		},
+		{
+			name: "invalid_coordinate_properties",
+			data: `{"type":"FeatureCollection","features":[` +
+				`{"type":"Feature","geometry":{"type":"LineString","coordinates":[[2,1],[4,3]]},"properties":{"coordinateProperties":{"times":[null]}}}` +
+				`]}`,
+			expectedError: "feature 0: coordinateProperties.times: invalid property",
+		},
+		{
+			name: "unsupported_geometry",
+			data: `{"type":"FeatureCollection","features":[` +
+				`{"type":"Feature","geometry":{"type":"MultiPoint","coordinates":[[2,1]]},"properties":null}` +
+				`]}`,
+			expectedError: "feature 0: *geom.MultiPoint: unsupported geometry",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var fc geojson.FeatureCollection
+			assert.NoError(t, json.Unmarshal([]byte(tc.data), &fc))
+			actual, err := gpx.NewGPXFromGeoJSON(&fc)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
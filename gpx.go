@@ -8,24 +8,29 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	geom "github.com/twpayne/go-geom"
-	"golang.org/x/net/html/charset"
 )
 
 const (
 	http  = "http://"
 	https = "https://"
+
+	xsiNamespace = http + "www.w3.org/2001/XMLSchema-instance"
 )
 
-var timeLayouts = []string{
+var defaultTimeLayouts = []string{
 	time.RFC3339Nano,
 	"2006-01-02T15:04:05.999999999",
 }
 
+var defaultReadOptions = readOptions{}
+
 // StartElement is the XML start element for GPX files.
 var StartElement = xml.StartElement{
 	Name: xml.Name{Local: "gpx"},
@@ -37,7 +42,12 @@ var copyrightYearLayouts = []string{
 	"2006-07:00",
 }
 
-var errNoTimeLayout = errors.New("no time layout")
+var (
+	errInvalidLatLon     = errors.New("invalid lat/lon")
+	errMissingLatLon     = errors.New("missing lat/lon")
+	errTooManyPoints     = errors.New("too many points")
+	errUnexpectedElement = errors.New("unexpected element")
+)
 
 // A BoundsType is a boundsType.
 type BoundsType struct {
@@ -54,9 +64,14 @@ type CopyrightType struct {
 	License string `xml:"license,omitempty"`
 }
 
-// An ExtensionsType contains elements from another schema.
+// An ExtensionsType contains elements from another schema. Namespaces maps
+// the namespace prefixes used in XML to their namespaces. Typed contains the
+// decoded values of elements in namespaces registered in an ExtensionRegistry,
+// which are not included in XML.
 type ExtensionsType struct {
-	XML []byte `xml:",innerxml"`
+	XML        []byte            `xml:",innerxml"`
+	Namespaces map[string]string `xml:"-"`
+	Typed      []any             `xml:"-"`
 }
 
 // A GPX is a gpxType.
@@ -138,7 +153,9 @@ type TrkType struct {
 	TrkSeg     []*TrkSegType   `xml:"trkseg,omitempty"`
 }
 
-// A WptType is a wptType.
+// A WptType is a wptType. Present records which optional numeric fields are
+// present, so that fields with a zero value, such as an elevation at sea
+// level, are preserved. Non-zero fields are always considered present.
 type WptType struct {
 	Lat           float64         `xml:"lat,omitempty"`
 	Lon           float64         `xml:"lon,omitempty"`
@@ -163,8 +180,26 @@ type WptType struct {
 	AgeOfDGPSData float64         `xml:"ageofdgpsdata,omitempty"`
 	DGPSID        []int           `xml:"dgpsid,omitempty"`
 	Extensions    *ExtensionsType `xml:"extensions,omitempty"`
+	Present       WptField        `xml:"-"`
 }
 
+// A WptField identifies an optional numeric field of a WptType.
+type WptField uint16
+
+// Optional numeric fields of a WptType.
+const (
+	WptEle WptField = 1 << iota
+	WptSpeed
+	WptCourse
+	WptMagVar
+	WptGeoidHeight
+	WptSat
+	WptHDOP
+	WptVDOP
+	WptPDOP
+	WptAgeOfDGPSData
+)
+
 // UnmarshalXML implements xml.Unmarshaler.UnmarshalXML.
 func (c *CopyrightType) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	alias := struct {
@@ -197,36 +232,276 @@ func (c *CopyrightType) UnmarshalXML(d *xml.Decoder, start xml.StartElement) err
 	return fmt.Errorf("couldn't parse Copyright year: %s", *alias.Year)
 }
 
-type ReadOption func()
+// readOptions is the configuration of a single decode.
+type readOptions struct {
+	timeLayouts       []string
+	strict            bool
+	maxPoints         int
+	extensionsHandler func(*ExtensionsType) error
+	extensionRegistry *ExtensionRegistry
+}
+
+// A ReadOption sets an option for a single decode.
+type ReadOption func(*readOptions)
+
+// A WriteOption sets an option for a single write.
+type WriteOption func(*writeOptions)
+
+// writeOptions is the configuration of a single write.
+type writeOptions struct {
+	refreshBounds     bool
+	time              time.Time
+	extensionRegistry *ExtensionRegistry
+}
 
 // Read reads a new GPX from r.
 func Read(r io.Reader, options ...ReadOption) (*GPX, error) {
-	for _, option := range options {
-		option()
+	d := NewDecoder(r, options...)
+	gpx, err := d.Root()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		token, err := d.Next()
+		switch {
+		case errors.Is(err, io.EOF):
+			return gpx, nil
+		case err != nil:
+			return nil, err
+		}
+		switch token.Type {
+		case MetadataToken:
+			gpx.Metadata = token.Metadata
+		case WptToken:
+			gpx.Wpt = append(gpx.Wpt, token.Wpt)
+		case RteToken:
+			gpx.Rte = append(gpx.Rte, token.Rte)
+		case RtePtToken:
+			rte := gpx.Rte[token.RteIndex]
+			rte.RtePt = append(rte.RtePt, token.Wpt)
+		case TrkToken:
+			gpx.Trk = append(gpx.Trk, token.Trk)
+		case TrkSegToken:
+			trk := gpx.Trk[token.TrkIndex]
+			trk.TrkSeg = append(trk.TrkSeg, token.TrkSeg)
+		case TrkPtToken:
+			trkSeg := gpx.Trk[token.TrkIndex].TrkSeg[token.TrkSegIndex]
+			trkSeg.TrkPt = append(trkSeg.TrkPt, token.Wpt)
+		case ExtensionsToken:
+			gpx.Extensions = token.Extensions
+		}
 	}
-	gpx := &GPX{}
-	d := xml.NewDecoder(r)
-	d.CharsetReader = charset.NewReaderLabel
-	return gpx, d.Decode(gpx)
 }
 
 // WithTimeLayout applies a custom time layout for the decoding of the GPX source.
+// The custom layout is tried before the default layouts.
 func WithTimeLayout(layout string) ReadOption {
-	return func() {
-		timeLayouts = []string{layout}
-	}
+	return WithTimeLayouts([]string{layout})
 }
 
 // WithTimeLayouts applies a custom time layouts for the decoding of the GPX
-// source.
+// source. The custom layouts are tried before the default layouts for every
+// time element.
 func WithTimeLayouts(layouts []string) ReadOption {
-	return func() {
-		timeLayouts = layouts
+	return func(o *readOptions) {
+		o.timeLayouts = layouts
 	}
 }
 
+// WithStrict rejects points without valid lat and lon attributes and
+// unexpected child elements of gpx, rte, trk, and trkseg elements.
+func WithStrict() ReadOption {
+	return func(o *readOptions) {
+		o.strict = true
+	}
+}
+
+// WithMaxPoints limits the total number of waypoints, route points, and track
+// points that will be read to maxPoints.
+func WithMaxPoints(maxPoints int) ReadOption {
+	return func(o *readOptions) {
+		o.maxPoints = maxPoints
+	}
+}
+
+// WithExtensionsHandler calls f with every extensions element as it is read.
+// If f returns an error then reading stops and the error is returned.
+func WithExtensionsHandler(f func(*ExtensionsType) error) ReadOption {
+	return func(o *readOptions) {
+		o.extensionsHandler = f
+	}
+}
+
+// WithRefreshBounds sets the metadata bounds to the bounds of all points when
+// writing.
+func WithRefreshBounds() WriteOption {
+	return func(o *writeOptions) {
+		o.refreshBounds = true
+	}
+}
+
+// WithRefreshTime sets the metadata time to t, typically time.Now(), when
+// writing.
+func WithRefreshTime(t time.Time) WriteOption {
+	return func(o *writeOptions) {
+		o.time = t
+	}
+}
+
+func newWriteOptions(options []WriteOption) *writeOptions {
+	o := &writeOptions{}
+	for _, option := range options {
+		option(o)
+	}
+	return o
+}
+
+func newReadOptions(options []ReadOption) *readOptions {
+	o := defaultReadOptions
+	for _, option := range options {
+		option(&o)
+	}
+	return &o
+}
+
 // MarshalXML implements xml.Marshaler.MarshalXML.
 func (g *GPX) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+	start := g.startElement()
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := g.encodeBody(e); err != nil {
+		return err
+	}
+	if err := g.encodeExtensions(e); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+// Write writes g to w. If g's version is 1.0 then g is written in GPX 1.0
+// form, all Extensions are omitted, and the course and speed of waypoints and
+// route points are omitted.
+func (g *GPX) Write(w io.Writer, options ...WriteOption) error {
+	g, err := g.withWriteOptions(newWriteOptions(options))
+	if err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).EncodeElement(g, StartElement)
+}
+
+// WriteIndent writes g to w.
+func (g *GPX) WriteIndent(w io.Writer, prefix, indent string, options ...WriteOption) error {
+	g, err := g.withWriteOptions(newWriteOptions(options))
+	if err != nil {
+		return err
+	}
+	e := xml.NewEncoder(w)
+	e.Indent(prefix, indent)
+	return e.EncodeElement(g, StartElement)
+}
+
+// withWriteOptions returns g with o applied. If o changes g then a modified
+// copy is returned and g is not changed.
+func (g *GPX) withWriteOptions(o *writeOptions) (*GPX, error) {
+	if o.extensionRegistry != nil {
+		var err error
+		if g, err = g.withEncodedTyped(o.extensionRegistry); err != nil {
+			return nil, err
+		}
+	}
+	if !o.refreshBounds && o.time.IsZero() {
+		return g, nil
+	}
+	var metadata MetadataType
+	if g.Metadata != nil {
+		metadata = *g.Metadata
+	}
+	if o.refreshBounds {
+		metadata.Bounds = g.Bounds()
+	}
+	if !o.time.IsZero() {
+		metadata.Time = o.time
+	}
+	gCopy := *g
+	gCopy.Metadata = &metadata
+	return &gCopy, nil
+}
+
+// encodeBody writes g's metadata, waypoints, routes, and tracks. If g's
+// version is 1.0 then they are written in GPX 1.0 form without their
+// extensions. GPX 1.0 allows elements from other namespaces in place of
+// extensions elements, but they are not written.
+func (g *GPX) encodeBody(e *xml.Encoder) error {
+	if g.Version != "1.0" {
+		if err := e.EncodeElement(g.Metadata, xml.StartElement{Name: xml.Name{Local: "metadata"}}); err != nil {
+			return err
+		}
+		if err := e.EncodeElement(g.Wpt, xml.StartElement{Name: xml.Name{Local: "wpt"}}); err != nil {
+			return err
+		}
+		if err := e.EncodeElement(g.Rte, xml.StartElement{Name: xml.Name{Local: "rte"}}); err != nil {
+			return err
+		}
+		return e.EncodeElement(g.Trk, xml.StartElement{Name: xml.Name{Local: "trk"}})
+	}
+	if g.Metadata != nil {
+		if err := g.Metadata.encodeGPX10(e); err != nil {
+			return err
+		}
+	}
+	for _, wpt := range g.Wpt {
+		if err := wpt.encodeGPX10(e, "wpt"); err != nil {
+			return err
+		}
+	}
+	for _, rte := range g.Rte {
+		if err := rte.encodeGPX10(e); err != nil {
+			return err
+		}
+	}
+	for _, trk := range g.Trk {
+		if err := trk.encodeGPX10(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeExtensions writes g's extensions, if any. GPX 1.0 does not have
+// extensions.
+func (g *GPX) encodeExtensions(e *xml.Encoder) error {
+	if g.Extensions == nil || g.Version == "1.0" {
+		return nil
+	}
+	return e.EncodeElement(g.Extensions, xml.StartElement{Name: xml.Name{Local: "extensions"}})
+}
+
+// setRootAttrs sets g's namespace declarations and schema locations from the
+// attributes of the root element, excluding those that are always written.
+func (g *GPX) setRootAttrs(attrs []xml.Attr) {
+	for _, attr := range attrs {
+		switch {
+		case attr.Name.Space == "xmlns" && attr.Name.Local == "xsi" && attr.Value == xsiNamespace:
+		case attr.Name.Space == "xmlns":
+			if g.XMLAttrs == nil {
+				g.XMLAttrs = make(map[string]string)
+			}
+			g.XMLAttrs["xmlns:"+attr.Name.Local] = attr.Value
+		case attr.Name.Space == xsiNamespace && attr.Name.Local == "schemaLocation":
+			fields := strings.Fields(attr.Value)
+			for i := 0; i+1 < len(fields); i += 2 {
+				if strings.HasPrefix(fields[i], http+"www.topografix.com/GPX/1/") {
+					continue
+				}
+				g.XMLSchemaLocations = append(g.XMLSchemaLocations, fields[i], fields[i+1])
+			}
+		}
+	}
+}
+
+// startElement returns the root element of g, including its attributes.
+func (g *GPX) startElement() xml.StartElement {
 	baseURL := "www.topografix.com/GPX/" + strings.Join(strings.Split(g.Version, "."), "/")
 	xmlSchemaLocations := append([]string{
 		http + baseURL,
@@ -243,7 +518,7 @@ func (g *GPX) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
 		},
 		{
 			Name:  xml.Name{Local: "xmlns:xsi"},
-			Value: http + "www.w3.org/2001/XMLSchema-instance",
+			Value: xsiNamespace,
 		},
 		{
 			Name:  xml.Name{Local: "xmlns"},
@@ -254,48 +529,33 @@ func (g *GPX) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
 			Value: strings.Join(xmlSchemaLocations, " "),
 		},
 	}
-	for k, v := range g.XMLAttrs {
+	xmlAttrs := maps.Clone(g.XMLAttrs)
+	for prefix, namespace := range g.extensionNamespaces() {
+		if _, ok := xmlAttrs["xmlns:"+prefix]; !ok {
+			if xmlAttrs == nil {
+				xmlAttrs = make(map[string]string)
+			}
+			xmlAttrs["xmlns:"+prefix] = namespace
+		}
+	}
+	for _, k := range slices.Sorted(maps.Keys(xmlAttrs)) {
 		attr = append(attr, xml.Attr{
 			Name:  xml.Name{Local: k},
-			Value: v,
+			Value: xmlAttrs[k],
 		})
 	}
-	start := xml.StartElement{
+	return xml.StartElement{
 		Name: xml.Name{Local: "gpx"},
 		Attr: attr,
 	}
-	if err := e.EncodeToken(start); err != nil {
-		return err
-	}
-	if err := e.EncodeElement(g.Metadata, xml.StartElement{Name: xml.Name{Local: "metadata"}}); err != nil {
-		return err
-	}
-	if err := e.EncodeElement(g.Wpt, xml.StartElement{Name: xml.Name{Local: "wpt"}}); err != nil {
-		return err
-	}
-	if err := e.EncodeElement(g.Rte, xml.StartElement{Name: xml.Name{Local: "rte"}}); err != nil {
-		return err
-	}
-	if err := e.EncodeElement(g.Trk, xml.StartElement{Name: xml.Name{Local: "trk"}}); err != nil {
-		return err
-	}
-	return e.EncodeToken(start.End())
-}
-
-// Write writes g to w.
-func (g *GPX) Write(w io.Writer) error {
-	return xml.NewEncoder(w).EncodeElement(g, StartElement)
-}
-
-// WriteIndent writes g to w.
-func (g *GPX) WriteIndent(w io.Writer, prefix, indent string) error {
-	e := xml.NewEncoder(w)
-	e.Indent(prefix, indent)
-	return e.EncodeElement(g, StartElement)
 }
 
 // UnmarshalXML implements xml.Unmarshaler.UnmarshalXML.
 func (m *MetadataType) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return m.decodeXML(d, start, &defaultReadOptions)
+}
+
+func (m *MetadataType) decodeXML(d *xml.Decoder, start xml.StartElement, o *readOptions) error {
 	var e struct {
 		Name       string          `xml:"name"`
 		Desc       string          `xml:"desc"`
@@ -321,7 +581,7 @@ func (m *MetadataType) UnmarshalXML(d *xml.Decoder, start xml.StartElement) erro
 		Extensions: e.Extensions,
 	}
 	if e.Time != "" {
-		t, err := parseTime(e.Time)
+		t, err := o.parseTime(e.Time)
 		if err != nil {
 			return err
 		}
@@ -401,6 +661,7 @@ func NewWptType(g *geom.Point) *WptType {
 	}
 	if zIndex := layout.ZIndex(); zIndex != -1 {
 		w.Ele = flatCoords[zIndex]
+		w.Present |= WptEle
 	}
 	if mIndex := layout.MIndex(); mIndex != -1 {
 		w.Time = MToTime(flatCoords[mIndex])
@@ -413,38 +674,62 @@ func (w *WptType) Geom(layout geom.Layout) *geom.Point {
 	return geom.NewPointFlat(layout, w.appendFlatCoords(make([]float64, 0, layout.Stride()), layout))
 }
 
+// Has returns whether w has the optional field f, either because f is set in
+// w.Present or because its value is non-zero.
+func (w *WptType) Has(f WptField) bool {
+	if w.Present&f != 0 {
+		return true
+	}
+	switch f {
+	case WptEle:
+		return w.Ele != 0
+	case WptSpeed:
+		return w.Speed != 0
+	case WptCourse:
+		return w.Course != 0
+	case WptMagVar:
+		return w.MagVar != 0
+	case WptGeoidHeight:
+		return w.GeoidHeight != 0
+	case WptSat:
+		return w.Sat != 0
+	case WptHDOP:
+		return w.HDOP != 0
+	case WptVDOP:
+		return w.VDOP != 0
+	case WptPDOP:
+		return w.PDOP != 0
+	case WptAgeOfDGPSData:
+		return w.AgeOfDGPSData != 0
+	default:
+		return false
+	}
+}
+
 // MarshalXML implements xml.Marshaler.MarshalXML.
 func (w *WptType) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
-	latAttr := xml.Attr{
-		Name:  xml.Name{Local: "lat"},
-		Value: strconv.FormatFloat(w.Lat, 'f', -1, 64),
-	}
-	lonAttr := xml.Attr{
-		Name:  xml.Name{Local: "lon"},
-		Value: strconv.FormatFloat(w.Lon, 'f', -1, 64),
-	}
-	start.Attr = append(start.Attr, latAttr, lonAttr)
+	start.Attr = append(start.Attr, w.latLonAttrs()...)
 	if err := e.EncodeToken(start); err != nil {
 		return err
 	}
-	if err := maybeEmitFloatElement(e, "ele", w.Ele); err != nil {
+	if err := w.maybeEmitFloatField(e, WptEle, "ele", w.Ele); err != nil {
 		return err
 	}
-	if err := maybeEmitFloatElement(e, "speed", w.Speed); err != nil {
+	if err := w.maybeEmitFloatField(e, WptSpeed, "speed", w.Speed); err != nil {
 		return err
 	}
-	if err := maybeEmitFloatElement(e, "course", w.Course); err != nil {
+	if err := w.maybeEmitFloatField(e, WptCourse, "course", w.Course); err != nil {
 		return err
 	}
 	if !w.Time.IsZero() {
-		if err := maybeEmitStringElement(e, "time", w.Time.UTC().Format(timeLayouts[0])); err != nil {
+		if err := maybeEmitStringElement(e, "time", w.Time.UTC().Format(time.RFC3339Nano)); err != nil {
 			return err
 		}
 	}
-	if err := maybeEmitFloatElement(e, "magvar", w.MagVar); err != nil {
+	if err := w.maybeEmitFloatField(e, WptMagVar, "magvar", w.MagVar); err != nil {
 		return err
 	}
-	if err := maybeEmitFloatElement(e, "geoidheight", w.GeoidHeight); err != nil {
+	if err := w.maybeEmitFloatField(e, WptGeoidHeight, "geoidheight", w.GeoidHeight); err != nil {
 		return err
 	}
 	if err := maybeEmitStringElement(e, "name", w.Name); err != nil {
@@ -473,19 +758,19 @@ func (w *WptType) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 	if err := maybeEmitStringElement(e, "fix", w.Fix); err != nil {
 		return err
 	}
-	if err := maybeEmitIntElement(e, "sat", w.Sat); err != nil {
+	if err := w.maybeEmitIntField(e, WptSat, "sat", w.Sat); err != nil {
 		return err
 	}
-	if err := maybeEmitFloatElement(e, "hdop", w.HDOP); err != nil {
+	if err := w.maybeEmitFloatField(e, WptHDOP, "hdop", w.HDOP); err != nil {
 		return err
 	}
-	if err := maybeEmitFloatElement(e, "vdop", w.VDOP); err != nil {
+	if err := w.maybeEmitFloatField(e, WptVDOP, "vdop", w.VDOP); err != nil {
 		return err
 	}
-	if err := maybeEmitFloatElement(e, "pdop", w.PDOP); err != nil {
+	if err := w.maybeEmitFloatField(e, WptPDOP, "pdop", w.PDOP); err != nil {
 		return err
 	}
-	if err := maybeEmitFloatElement(e, "ageofdgpsdata", w.AgeOfDGPSData); err != nil {
+	if err := w.maybeEmitFloatField(e, WptAgeOfDGPSData, "ageofdgpsdata", w.AgeOfDGPSData); err != nil {
 		return err
 	}
 	for _, dgpsid := range w.DGPSID {
@@ -503,60 +788,97 @@ func (w *WptType) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 
 // UnmarshalXML implements xml.Unmarshaler.UnmarshalXML.
 func (w *WptType) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return w.decodeXML(d, start, &defaultReadOptions)
+}
+
+func (w *WptType) decodeXML(d *xml.Decoder, start xml.StartElement, o *readOptions) error {
 	var e struct {
-		Lat           float64         `xml:"lat,attr"`
-		Lon           float64         `xml:"lon,attr"`
-		Ele           float64         `xml:"ele"`
-		Speed         float64         `xml:"speed"`
-		Course        float64         `xml:"course"`
+		Lat           *float64        `xml:"lat,attr"`
+		Lon           *float64        `xml:"lon,attr"`
+		Ele           *float64        `xml:"ele"`
+		Speed         *float64        `xml:"speed"`
+		Course        *float64        `xml:"course"`
 		Time          string          `xml:"time"`
-		MagVar        float64         `xml:"magvar"`
-		GeoidHeight   float64         `xml:"geoidheight"`
+		MagVar        *float64        `xml:"magvar"`
+		GeoidHeight   *float64        `xml:"geoidheight"`
 		Name          string          `xml:"name"`
 		Cmt           string          `xml:"cmt"`
 		Desc          string          `xml:"desc"`
 		Src           string          `xml:"src"`
+		URL           string          `xml:"url"`
+		URLName       string          `xml:"urlname"`
 		Link          []*LinkType     `xml:"link"`
 		Sym           string          `xml:"sym"`
 		Type          string          `xml:"type"`
 		Fix           string          `xml:"fix"`
-		Sat           int             `xml:"sat"`
-		HDOP          float64         `xml:"hdop"`
-		VDOP          float64         `xml:"vdop"`
-		PDOP          float64         `xml:"pdop"`
-		AgeOfDGPSData float64         `xml:"ageofdgpsdata"`
+		Sat           *int            `xml:"sat"`
+		HDOP          *float64        `xml:"hdop"`
+		VDOP          *float64        `xml:"vdop"`
+		PDOP          *float64        `xml:"pdop"`
+		AgeOfDGPSData *float64        `xml:"ageofdgpsdata"`
 		DGPSID        []int           `xml:"dgpsid"`
 		Extensions    *ExtensionsType `xml:"extensions"`
 	}
 	if err := d.DecodeElement(&e, &start); err != nil {
 		return err
 	}
+	switch {
+	case !o.strict:
+	case e.Lat == nil || e.Lon == nil:
+		return errMissingLatLon
+	case *e.Lat < -90 || 90 < *e.Lat || *e.Lon < -180 || 180 < *e.Lon:
+		return fmt.Errorf("%f,%f: %w", *e.Lat, *e.Lon, errInvalidLatLon)
+	}
 	wt := WptType{
-		Lat:           e.Lat,
-		Lon:           e.Lon,
-		Ele:           e.Ele,
-		Speed:         e.Speed,
-		Course:        e.Course,
-		MagVar:        e.MagVar,
-		GeoidHeight:   e.GeoidHeight,
-		Name:          e.Name,
-		Cmt:           e.Cmt,
-		Desc:          e.Desc,
-		Src:           e.Src,
-		Link:          e.Link,
-		Sym:           e.Sym,
-		Type:          e.Type,
-		Fix:           e.Fix,
-		Sat:           e.Sat,
-		HDOP:          e.HDOP,
-		VDOP:          e.VDOP,
-		PDOP:          e.PDOP,
-		AgeOfDGPSData: e.AgeOfDGPSData,
-		DGPSID:        e.DGPSID,
-		Extensions:    e.Extensions,
+		Name:       e.Name,
+		Cmt:        e.Cmt,
+		Desc:       e.Desc,
+		Src:        e.Src,
+		Link:       e.Link,
+		Sym:        e.Sym,
+		Type:       e.Type,
+		Fix:        e.Fix,
+		DGPSID:     e.DGPSID,
+		Extensions: e.Extensions,
+	}
+	if e.URL != "" || e.URLName != "" {
+		wt.Link = append(wt.Link, &LinkType{
+			HREF: e.URL,
+			Text: e.URLName,
+		})
+	}
+	if e.Lat != nil {
+		wt.Lat = *e.Lat
+	}
+	if e.Lon != nil {
+		wt.Lon = *e.Lon
+	}
+	for _, field := range []struct {
+		field WptField
+		src   *float64
+		dst   *float64
+	}{
+		{WptEle, e.Ele, &wt.Ele},
+		{WptSpeed, e.Speed, &wt.Speed},
+		{WptCourse, e.Course, &wt.Course},
+		{WptMagVar, e.MagVar, &wt.MagVar},
+		{WptGeoidHeight, e.GeoidHeight, &wt.GeoidHeight},
+		{WptHDOP, e.HDOP, &wt.HDOP},
+		{WptVDOP, e.VDOP, &wt.VDOP},
+		{WptPDOP, e.PDOP, &wt.PDOP},
+		{WptAgeOfDGPSData, e.AgeOfDGPSData, &wt.AgeOfDGPSData},
+	} {
+		if field.src != nil {
+			*field.dst = *field.src
+			wt.Present |= field.field
+		}
+	}
+	if e.Sat != nil {
+		wt.Sat = *e.Sat
+		wt.Present |= WptSat
 	}
 	if e.Time != "" {
-		t, err := parseTime(e.Time)
+		t, err := o.parseTime(e.Time)
 		if err != nil {
 			return err
 		}
@@ -566,6 +888,33 @@ func (w *WptType) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	return nil
 }
 
+func (w *WptType) maybeEmitFloatField(e *xml.Encoder, f WptField, localName string, value float64) error {
+	if !w.Has(f) {
+		return nil
+	}
+	return emitFloatElement(e, localName, value)
+}
+
+func (w *WptType) maybeEmitIntField(e *xml.Encoder, f WptField, localName string, value int) error {
+	if !w.Has(f) {
+		return nil
+	}
+	return emitIntElement(e, localName, value)
+}
+
+func (w *WptType) latLonAttrs() []xml.Attr {
+	return []xml.Attr{
+		{
+			Name:  xml.Name{Local: "lat"},
+			Value: strconv.FormatFloat(w.Lat, 'f', -1, 64),
+		},
+		{
+			Name:  xml.Name{Local: "lon"},
+			Value: strconv.FormatFloat(w.Lon, 'f', -1, 64),
+		},
+	}
+}
+
 func (w *WptType) appendFlatCoords(flatCoords []float64, layout geom.Layout) []float64 {
 	switch layout {
 	case geom.NoLayout:
@@ -599,6 +948,10 @@ func TimeToM(t time.Time) float64 {
 	return float64(t.UnixNano()) / float64(time.Second)
 }
 
+func emitFloatElement(e *xml.Encoder, localName string, value float64) error {
+	return emitStringElement(e, localName, strconv.FormatFloat(value, 'f', -1, 64))
+}
+
 func emitIntElement(e *xml.Encoder, localName string, value int) error {
 	return emitStringElement(e, localName, strconv.Itoa(value))
 }
@@ -611,7 +964,7 @@ func maybeEmitFloatElement(e *xml.Encoder, localName string, value float64) erro
 	if value == 0 {
 		return nil
 	}
-	return emitStringElement(e, localName, strconv.FormatFloat(value, 'f', -1, 64))
+	return emitFloatElement(e, localName, value)
 }
 
 func maybeEmitIntElement(e *xml.Encoder, localName string, value int) error {
@@ -628,6 +981,20 @@ func maybeEmitStringElement(e *xml.Encoder, localName, value string) error {
 	return emitStringElement(e, localName, value)
 }
 
+// eleLayout returns the layout to use for wpts: XYZ if they all have
+// elevations, otherwise XY.
+func eleLayout(wpts []*WptType) geom.Layout {
+	if len(wpts) == 0 {
+		return geom.XY
+	}
+	for _, wpt := range wpts {
+		if !wpt.Has(WptEle) {
+			return geom.XY
+		}
+	}
+	return geom.XYZ
+}
+
 func newWptTypes(g *geom.LineString) []*WptType {
 	flatCoords := g.FlatCoords()
 	layout := g.Layout()
@@ -643,6 +1010,7 @@ func newWptTypes(g *geom.LineString) []*WptType {
 		}
 		if zIndex != -1 {
 			wpt.Ele = flatCoords[start+zIndex]
+			wpt.Present |= WptEle
 		}
 		if mIndex != -1 {
 			wpt.Time = MToTime(flatCoords[start+mIndex])
@@ -653,14 +1021,25 @@ func newWptTypes(g *geom.LineString) []*WptType {
 	return wpts
 }
 
-func parseTime(value string) (time.Time, error) {
-	firstErr := errNoTimeLayout
-	for i, timeLayout := range timeLayouts {
-		switch t, err := time.Parse(timeLayout, value); {
-		case err == nil:
-			return t, nil
-		case i == 0:
-			firstErr = err
+// parseDefaultTime parses value with the default time layouts.
+func parseDefaultTime(value string) (time.Time, error) {
+	o := defaultReadOptions
+	return o.parseTime(strings.TrimSpace(value))
+}
+
+// parseTime parses value with o's custom time layouts followed by the default
+// time layouts. If no layout matches then it returns the error from the first
+// layout.
+func (o *readOptions) parseTime(value string) (time.Time, error) {
+	var firstErr error
+	for _, timeLayouts := range [][]string{o.timeLayouts, defaultTimeLayouts} {
+		for _, timeLayout := range timeLayouts {
+			switch t, err := time.Parse(timeLayout, value); {
+			case err == nil:
+				return t, nil
+			case firstErr == nil:
+				firstErr = err
+			}
 		}
 	}
 	return time.Time{}, firstErr
@@ -0,0 +1,301 @@
+package gpx
+
+import (
+	"encoding/xml"
+	"strings"
+	"time"
+)
+
+const gpx10Namespace = "http://www.topografix.com/GPX/1/0"
+
+// decodeGPX10Element decodes start, a GPX 1.0 metadata child element of the
+// gpx element, into m.
+func (m *MetadataType) decodeGPX10Element(d *xml.Decoder, start xml.StartElement, o *readOptions) error {
+	switch start.Name.Local {
+	case "name":
+		return d.DecodeElement(&m.Name, &start)
+	case "desc":
+		return d.DecodeElement(&m.Desc, &start)
+	case "author":
+		if m.Author == nil {
+			m.Author = &PersonType{}
+		}
+		return d.DecodeElement(&m.Author.Name, &start)
+	case "email":
+		var email string
+		if err := d.DecodeElement(&email, &start); err != nil {
+			return err
+		}
+		if m.Author == nil {
+			m.Author = &PersonType{}
+		}
+		m.Author.Email = newEmailType(email)
+		return nil
+	case "url":
+		var url string
+		if err := d.DecodeElement(&url, &start); err != nil {
+			return err
+		}
+		m.Link = appendURL(m.Link, url)
+		return nil
+	case "urlname":
+		var urlName string
+		if err := d.DecodeElement(&urlName, &start); err != nil {
+			return err
+		}
+		m.Link = appendURLName(m.Link, urlName)
+		return nil
+	case "time":
+		var value string
+		if err := d.DecodeElement(&value, &start); err != nil {
+			return err
+		}
+		t, err := o.parseTime(value)
+		if err != nil {
+			return err
+		}
+		m.Time = t
+		return nil
+	case "keywords":
+		return d.DecodeElement(&m.Keywords, &start)
+	case "bounds":
+		m.Bounds = &BoundsType{}
+		return d.DecodeElement(m.Bounds, &start)
+	default:
+		return d.Skip()
+	}
+}
+
+// encodeGPX10 writes m as the children of a GPX 1.0 gpx element.
+func (m *MetadataType) encodeGPX10(e *xml.Encoder) error {
+	if err := maybeEmitStringElement(e, "name", m.Name); err != nil {
+		return err
+	}
+	if err := maybeEmitStringElement(e, "desc", m.Desc); err != nil {
+		return err
+	}
+	if m.Author != nil {
+		if err := maybeEmitStringElement(e, "author", m.Author.Name); err != nil {
+			return err
+		}
+		if m.Author.Email != nil {
+			if err := emitStringElement(e, "email", m.Author.Email.String()); err != nil {
+				return err
+			}
+		}
+	}
+	if err := emitURL(e, m.Link); err != nil {
+		return err
+	}
+	if !m.Time.IsZero() {
+		if err := emitStringElement(e, "time", m.Time.UTC().Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+	}
+	if err := maybeEmitStringElement(e, "keywords", m.Keywords); err != nil {
+		return err
+	}
+	if m.Bounds != nil {
+		if err := e.EncodeElement(m.Bounds, xml.StartElement{Name: xml.Name{Local: "bounds"}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeGPX10 writes r as a GPX 1.0 rte element.
+func (r *RteType) encodeGPX10(e *xml.Encoder) error {
+	start := xml.StartElement{Name: xml.Name{Local: "rte"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := encodeGPX10Header(e, r.Name, r.Cmt, r.Desc, r.Src, r.Link, r.Number); err != nil {
+		return err
+	}
+	for _, rtePt := range r.RtePt {
+		if err := rtePt.encodeGPX10(e, "rtept"); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// encodeGPX10 writes t as a GPX 1.0 trk element.
+func (t *TrkType) encodeGPX10(e *xml.Encoder) error {
+	start := xml.StartElement{Name: xml.Name{Local: "trk"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := t.encodeGPX10Header(e); err != nil {
+		return err
+	}
+	for _, trkSeg := range t.TrkSeg {
+		if err := trkSeg.encodeGPX10(e); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// encodeGPX10Header writes all of t's GPX 1.0 child elements except its
+// segments.
+func (t *TrkType) encodeGPX10Header(e *xml.Encoder) error {
+	return encodeGPX10Header(e, t.Name, t.Cmt, t.Desc, t.Src, t.Link, t.Number)
+}
+
+// encodeGPX10 writes ts as a GPX 1.0 trkseg element.
+func (ts *TrkSegType) encodeGPX10(e *xml.Encoder) error {
+	start := xml.StartElement{Name: xml.Name{Local: "trkseg"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, trkPt := range ts.TrkPt {
+		if err := trkPt.encodeGPX10(e, "trkpt"); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// encodeGPX10 writes w as a GPX 1.0 wptType element called localName. Course
+// and speed are only written for trkpt elements as GPX 1.0 does not allow
+// them elsewhere.
+func (w *WptType) encodeGPX10(e *xml.Encoder, localName string) error {
+	start := xml.StartElement{
+		Name: xml.Name{Local: localName},
+		Attr: w.latLonAttrs(),
+	}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := w.maybeEmitFloatField(e, WptEle, "ele", w.Ele); err != nil {
+		return err
+	}
+	if !w.Time.IsZero() {
+		if err := emitStringElement(e, "time", w.Time.UTC().Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+	}
+	if localName == "trkpt" {
+		if err := w.maybeEmitFloatField(e, WptCourse, "course", w.Course); err != nil {
+			return err
+		}
+		if err := w.maybeEmitFloatField(e, WptSpeed, "speed", w.Speed); err != nil {
+			return err
+		}
+	}
+	if err := w.maybeEmitFloatField(e, WptMagVar, "magvar", w.MagVar); err != nil {
+		return err
+	}
+	if err := w.maybeEmitFloatField(e, WptGeoidHeight, "geoidheight", w.GeoidHeight); err != nil {
+		return err
+	}
+	if err := maybeEmitStringElement(e, "name", w.Name); err != nil {
+		return err
+	}
+	if err := maybeEmitStringElement(e, "cmt", w.Cmt); err != nil {
+		return err
+	}
+	if err := maybeEmitStringElement(e, "desc", w.Desc); err != nil {
+		return err
+	}
+	if err := maybeEmitStringElement(e, "src", w.Src); err != nil {
+		return err
+	}
+	if err := emitURL(e, w.Link); err != nil {
+		return err
+	}
+	if err := maybeEmitStringElement(e, "sym", w.Sym); err != nil {
+		return err
+	}
+	if err := maybeEmitStringElement(e, "type", w.Type); err != nil {
+		return err
+	}
+	if err := maybeEmitStringElement(e, "fix", w.Fix); err != nil {
+		return err
+	}
+	if err := w.maybeEmitIntField(e, WptSat, "sat", w.Sat); err != nil {
+		return err
+	}
+	if err := w.maybeEmitFloatField(e, WptHDOP, "hdop", w.HDOP); err != nil {
+		return err
+	}
+	if err := w.maybeEmitFloatField(e, WptVDOP, "vdop", w.VDOP); err != nil {
+		return err
+	}
+	if err := w.maybeEmitFloatField(e, WptPDOP, "pdop", w.PDOP); err != nil {
+		return err
+	}
+	if err := w.maybeEmitFloatField(e, WptAgeOfDGPSData, "ageofdgpsdata", w.AgeOfDGPSData); err != nil {
+		return err
+	}
+	for _, dgpsid := range w.DGPSID {
+		if err := emitIntElement(e, "dgpsid", dgpsid); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// String returns e as an email address.
+func (e *EmailType) String() string {
+	return e.Name + "@" + e.Domain
+}
+
+func encodeGPX10Header(e *xml.Encoder, name, cmt, desc, src string, link []*LinkType, number int) error {
+	if err := maybeEmitStringElement(e, "name", name); err != nil {
+		return err
+	}
+	if err := maybeEmitStringElement(e, "cmt", cmt); err != nil {
+		return err
+	}
+	if err := maybeEmitStringElement(e, "desc", desc); err != nil {
+		return err
+	}
+	if err := maybeEmitStringElement(e, "src", src); err != nil {
+		return err
+	}
+	if err := emitURL(e, link); err != nil {
+		return err
+	}
+	return maybeEmitIntElement(e, "number", number)
+}
+
+// emitURL writes the first of links as GPX 1.0 url and urlname elements.
+// GPX 1.0 only allows a single URL per element.
+func emitURL(e *xml.Encoder, links []*LinkType) error {
+	if len(links) == 0 {
+		return nil
+	}
+	if err := maybeEmitStringElement(e, "url", links[0].HREF); err != nil {
+		return err
+	}
+	return maybeEmitStringElement(e, "urlname", links[0].Text)
+}
+
+// appendURL appends a link for a GPX 1.0 url element to links.
+func appendURL(links []*LinkType, url string) []*LinkType {
+	if n := len(links); n > 0 && links[n-1].HREF == "" {
+		links[n-1].HREF = url
+		return links
+	}
+	return append(links, &LinkType{HREF: url})
+}
+
+// appendURLName sets the text of the last link in links from a GPX 1.0
+// urlname element.
+func appendURLName(links []*LinkType, urlName string) []*LinkType {
+	if n := len(links); n > 0 && links[n-1].Text == "" {
+		links[n-1].Text = urlName
+		return links
+	}
+	return append(links, &LinkType{Text: urlName})
+}
+
+func newEmailType(email string) *EmailType {
+	name, domain, _ := strings.Cut(email, "@")
+	return &EmailType{
+		Name:   name,
+		Domain: domain,
+	}
+}
@@ -0,0 +1,240 @@
+package gpx_test
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	gpx "github.com/twpayne/go-gpx"
+)
+
+func TestGPX10RoundTrip(t *testing.T) {
+	for i, tc := range []struct {
+		data string
+		gpx  *gpx.GPX
+	}{
+		{
+			data: "<gpx" +
+				" version=\"1.0\"" +
+				" creator=\"Groundspeak Pocket Query\"" +
+				" xmlns:xsi=\"http://www.w3.org/2001/XMLSchema-instance\"" +
+				" xmlns=\"http://www.topografix.com/GPX/1/0\"" +
+				" xsi:schemaLocation=\"http://www.topografix.com/GPX/1/0 https://www.topografix.com/GPX/1/0/gpx.xsd\">\n" +
+				"\t<name>My Finds Pocket Query</name>\n" +
+				"\t<desc>Geocache file generated by Groundspeak</desc>\n" +
+				"\t<author>Groundspeak</author>\n" +
+				"\t<email>contact@geocaching.com</email>\n" +
+				"\t<url>https://www.geocaching.com</url>\n" +
+				"\t<urlname>Geocaching</urlname>\n" +
+				"\t<time>2024-12-01T10:57:35Z</time>\n" +
+				"\t<keywords>cache, geocache, groundspeak</keywords>\n" +
+				"\t<bounds minlat=\"-50.1445\" minlon=\"-165.631467\" maxlat=\"68.4373\" maxlon=\"159.9576\"></bounds>\n" +
+				"\t<wpt lat=\"49.48435\" lon=\"10.977\">\n" +
+				"\t\t<time>2008-07-13T00:00:00Z</time>\n" +
+				"\t\t<name>GC1E4ZY</name>\n" +
+				"\t\t<url>https://coord.info/GC1E4ZY</url>\n" +
+				"\t\t<urlname>Grüner Park</urlname>\n" +
+				"\t\t<sym>Geocache Found</sym>\n" +
+				"\t</wpt>\n" +
+				"\t<rte>\n" +
+				"\t\t<name>rte</name>\n" +
+				"\t\t<url>https://example.com/rte</url>\n" +
+				"\t\t<number>1</number>\n" +
+				"\t\t<rtept lat=\"1\" lon=\"2\"></rtept>\n" +
+				"\t</rte>\n" +
+				"\t<trk>\n" +
+				"\t\t<name>trk</name>\n" +
+				"\t\t<trkseg>\n" +
+				"\t\t\t<trkpt lat=\"47.644548\" lon=\"-122.326897\">\n" +
+				"\t\t\t\t<ele>4.46</ele>\n" +
+				"\t\t\t\t<time>2009-10-17T18:37:26Z</time>\n" +
+				"\t\t\t\t<course>90</course>\n" +
+				"\t\t\t\t<speed>1.5</speed>\n" +
+				"\t\t\t</trkpt>\n" +
+				"\t\t</trkseg>\n" +
+				"\t</trk>\n" +
+				"</gpx>",
+			gpx: &gpx.GPX{
+				Version: "1.0",
+				Creator: "Groundspeak Pocket Query",
+				Metadata: &gpx.MetadataType{
+					Name: "My Finds Pocket Query",
+					Desc: "Geocache file generated by Groundspeak",
+					Author: &gpx.PersonType{
+						Name: "Groundspeak",
+						Email: &gpx.EmailType{
+							Name:   "contact",
+							Domain: "geocaching.com",
+						},
+					},
+					Link: []*gpx.LinkType{
+						{
+							HREF: "https://www.geocaching.com",
+							Text: "Geocaching",
+						},
+					},
+					Time:     time.Date(2024, 12, 1, 10, 57, 35, 0, time.UTC),
+					Keywords: "cache, geocache, groundspeak",
+					Bounds: &gpx.BoundsType{
+						MinLat: -50.1445,
+						MinLon: -165.631467,
+						MaxLat: 68.4373,
+						MaxLon: 159.9576,
+					},
+				},
+				Wpt: []*gpx.WptType{
+					{
+						Lat:  49.48435,
+						Lon:  10.977,
+						Time: time.Date(2008, 7, 13, 0, 0, 0, 0, time.UTC),
+						Name: "GC1E4ZY",
+						Link: []*gpx.LinkType{
+							{
+								HREF: "https://coord.info/GC1E4ZY",
+								Text: "Grüner Park",
+							},
+						},
+						Sym: "Geocache Found",
+					},
+				},
+				Rte: []*gpx.RteType{
+					{
+						Name: "rte",
+						Link: []*gpx.LinkType{
+							{
+								HREF: "https://example.com/rte",
+							},
+						},
+						Number: 1,
+						RtePt: []*gpx.WptType{
+							{
+								Lat: 1,
+								Lon: 2,
+							},
+						},
+					},
+				},
+				Trk: []*gpx.TrkType{
+					{
+						Name: "trk",
+						TrkSeg: []*gpx.TrkSegType{
+							{
+								TrkPt: []*gpx.WptType{
+									{
+										Lat:     47.644548,
+										Lon:     -122.326897,
+										Ele:     4.46,
+										Time:    time.Date(2009, 10, 17, 18, 37, 26, 0, time.UTC),
+										Course:  90,
+										Speed:   1.5,
+										Present: gpx.WptEle | gpx.WptSpeed | gpx.WptCourse,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			got, err := gpx.Read(bytes.NewBufferString(tc.data))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.gpx, got)
+			sb := &strings.Builder{}
+			assert.NoError(t, tc.gpx.WriteIndent(sb, "", "\t"))
+			assert.Equal(t, strings.Split(tc.data, "\n"), strings.Split(sb.String(), "\n"))
+		})
+	}
+}
+
+func TestGPX10Write(t *testing.T) {
+	g := &gpx.GPX{
+		Version: "1.0",
+		Metadata: &gpx.MetadataType{
+			Copyright: &gpx.CopyrightType{
+				Author: "author",
+			},
+		},
+		Wpt: []*gpx.WptType{
+			{
+				Lat: 1,
+				Lon: 2,
+				Extensions: &gpx.ExtensionsType{
+					XML: []byte("<foo/>"),
+				},
+			},
+		},
+		Trk: []*gpx.TrkType{
+			{
+				Type: "type",
+				TrkSeg: []*gpx.TrkSegType{
+					{
+						Extensions: &gpx.ExtensionsType{
+							XML: []byte("<foo/>"),
+						},
+					},
+				},
+			},
+		},
+	}
+	sb := &strings.Builder{}
+	assert.NoError(t, g.Write(sb))
+	for _, s := range []string{"metadata", "copyright", "extensions", "foo", "type"} {
+		assert.NotContains(t, sb.String(), "<"+s)
+	}
+}
+
+func TestGPX10WriteCourseSpeed(t *testing.T) {
+	newWpt := func() *gpx.WptType {
+		return &gpx.WptType{
+			Lat:     1,
+			Lon:     2,
+			Course:  90,
+			Speed:   5,
+			Present: gpx.WptCourse | gpx.WptSpeed,
+		}
+	}
+	g := &gpx.GPX{
+		Version: "1.0",
+		Wpt:     []*gpx.WptType{newWpt()},
+		Rte: []*gpx.RteType{
+			{
+				RtePt: []*gpx.WptType{newWpt()},
+			},
+		},
+		Trk: []*gpx.TrkType{
+			{
+				TrkSeg: []*gpx.TrkSegType{
+					{
+						TrkPt: []*gpx.WptType{newWpt()},
+					},
+				},
+			},
+		},
+	}
+	sb := &strings.Builder{}
+	assert.NoError(t, g.Write(sb))
+	for _, s := range []string{
+		`<wpt lat="1" lon="2"></wpt>`,
+		`<rtept lat="1" lon="2"></rtept>`,
+		`<trkpt lat="1" lon="2"><course>90</course><speed>5</speed></trkpt>`,
+	} {
+		assert.Contains(t, sb.String(), s)
+	}
+}
+
+func TestGPX10Namespace(t *testing.T) {
+	g, err := gpx.Read(bytes.NewBufferString(`<gpx xmlns="http://www.topografix.com/GPX/1/0"><time>2002-02-27T17:18:33Z</time></gpx>`))
+	assert.NoError(t, err)
+	assert.Equal(t, &gpx.GPX{
+		Version: "1.0",
+		Metadata: &gpx.MetadataType{
+			Time: time.Date(2002, 2, 27, 17, 18, 33, 0, time.UTC),
+		},
+	}, g)
+}
@@ -3,6 +3,7 @@ package gpx_test
 import (
 	"bytes"
 	"encoding/xml"
+	"errors"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -76,9 +77,10 @@ func TestWpt(t *testing.T) {
 				"\t<ele>44.586548</ele>\n" +
 				"</wpt>",
 			wpt: &gpx.WptType{
-				Lat: 42.438878,
-				Lon: -71.119277,
-				Ele: 44.586548,
+				Lat:     42.438878,
+				Lon:     -71.119277,
+				Ele:     44.586548,
+				Present: gpx.WptEle,
 			},
 			layout: geom.XYZ,
 			g:      geom.NewPoint(geom.XYZ).MustSetCoords([]float64{-71.119277, 42.438878, 44.586548}),
@@ -105,14 +107,15 @@ func TestWpt(t *testing.T) {
 				"\t<type><![CDATA[Crossing]]></type>\n" +
 				"</wpt>\n",
 			wpt: &gpx.WptType{
-				Lat:  42.438878,
-				Lon:  -71.119277,
-				Ele:  44.586548,
-				Time: time.Date(2001, 11, 28, 21, 5, 28, 0, time.UTC),
-				Name: "5066",
-				Desc: "5066",
-				Sym:  "Crossing",
-				Type: "Crossing",
+				Lat:     42.438878,
+				Lon:     -71.119277,
+				Ele:     44.586548,
+				Time:    time.Date(2001, 11, 28, 21, 5, 28, 0, time.UTC),
+				Name:    "5066",
+				Desc:    "5066",
+				Sym:     "Crossing",
+				Type:    "Crossing",
+				Present: gpx.WptEle,
 			},
 			layout:        geom.XYZM,
 			g:             geom.NewPoint(geom.XYZM).MustSetCoords([]float64{-71.119277, 42.438878, 44.586548, 1006981528}),
@@ -170,11 +173,37 @@ func TestWpt(t *testing.T) {
 				PDOP:          6.6,
 				AgeOfDGPSData: 7.7,
 				DGPSID:        []int{8},
+				Present:       gpx.WptEle | gpx.WptMagVar | gpx.WptGeoidHeight | gpx.WptSat | gpx.WptHDOP | gpx.WptVDOP | gpx.WptPDOP | gpx.WptAgeOfDGPSData,
 			},
 			layout:    geom.XYZM,
 			g:         geom.NewPoint(geom.XYZM).MustSetCoords([]float64{-71.119277, 42.438878, 44.586548, 1006981528}),
 			noTestNew: true,
 		},
+		{
+			data: "<wpt lat=\"0\" lon=\"0\">\n" +
+				"\t<ele>0</ele>\n" +
+				"</wpt>",
+			wpt: &gpx.WptType{
+				Present: gpx.WptEle,
+			},
+			layout: geom.XYZ,
+			g:      geom.NewPoint(geom.XYZ).MustSetCoords([]float64{0, 0, 0}),
+		},
+		{
+			data: "<wpt lat=\"51.5\" lon=\"0\">\n" +
+				"\t<ele>0</ele>\n" +
+				"\t<speed>0</speed>\n" +
+				"\t<course>0</course>\n" +
+				"\t<sat>0</sat>\n" +
+				"</wpt>",
+			wpt: &gpx.WptType{
+				Lat:     51.5,
+				Present: gpx.WptEle | gpx.WptSpeed | gpx.WptCourse | gpx.WptSat,
+			},
+			layout:    geom.XYZ,
+			g:         geom.NewPoint(geom.XYZ).MustSetCoords([]float64{0, 51.5, 0}),
+			noTestNew: true,
+		},
 	} {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
 			var gotWpt gpx.WptType
@@ -243,14 +272,16 @@ func TestRte(t *testing.T) {
 			rte: &gpx.RteType{
 				RtePt: []*gpx.WptType{
 					{
-						Lat: 42.43095,
-						Lon: -71.107628,
-						Ele: 23.4696,
+						Lat:     42.43095,
+						Lon:     -71.107628,
+						Ele:     23.4696,
+						Present: gpx.WptEle,
 					},
 					{
-						Lat: 42.43124,
-						Lon: -71.109236,
-						Ele: 26.56189,
+						Lat:     42.43124,
+						Lon:     -71.109236,
+						Ele:     26.56189,
+						Present: gpx.WptEle,
 					},
 				},
 			},
@@ -307,16 +338,18 @@ func TestRte(t *testing.T) {
 			rte: &gpx.RteType{
 				RtePt: []*gpx.WptType{
 					{
-						Lat:  42.43095,
-						Lon:  -71.107628,
-						Ele:  23.4696,
-						Time: time.Date(2001, 6, 2, 0, 18, 15, 0, time.UTC),
+						Lat:     42.43095,
+						Lon:     -71.107628,
+						Ele:     23.4696,
+						Time:    time.Date(2001, 6, 2, 0, 18, 15, 0, time.UTC),
+						Present: gpx.WptEle,
 					},
 					{
-						Lat:  42.43124,
-						Lon:  -71.109236,
-						Ele:  26.56189,
-						Time: time.Date(2001, 11, 7, 23, 53, 41, 0, time.UTC),
+						Lat:     42.43124,
+						Lon:     -71.109236,
+						Ele:     26.56189,
+						Time:    time.Date(2001, 11, 7, 23, 53, 41, 0, time.UTC),
+						Present: gpx.WptEle,
 					},
 				},
 			},
@@ -357,25 +390,27 @@ func TestRte(t *testing.T) {
 				Number: 1,
 				RtePt: []*gpx.WptType{
 					{
-						Lat:  42.43095,
-						Lon:  -71.107628,
-						Ele:  23.4696,
-						Time: time.Date(2001, 6, 2, 0, 18, 15, 0, time.UTC),
-						Name: "BELLEVUE",
-						Cmt:  "BELLEVUE",
-						Desc: "Bellevue Parking Lot",
-						Sym:  "Parking Area",
-						Type: "Parking",
+						Lat:     42.43095,
+						Lon:     -71.107628,
+						Ele:     23.4696,
+						Time:    time.Date(2001, 6, 2, 0, 18, 15, 0, time.UTC),
+						Name:    "BELLEVUE",
+						Cmt:     "BELLEVUE",
+						Desc:    "Bellevue Parking Lot",
+						Sym:     "Parking Area",
+						Type:    "Parking",
+						Present: gpx.WptEle,
 					},
 					{
-						Lat:  42.43124,
-						Lon:  -71.109236,
-						Ele:  26.56189,
-						Time: time.Date(2001, 11, 7, 23, 53, 41, 0, time.UTC),
-						Name: "GATE6",
-						Desc: "Gate 6",
-						Sym:  "Trailhead",
-						Type: "Trail Head",
+						Lat:     42.43124,
+						Lon:     -71.109236,
+						Ele:     26.56189,
+						Time:    time.Date(2001, 11, 7, 23, 53, 41, 0, time.UTC),
+						Name:    "GATE6",
+						Desc:    "Gate 6",
+						Sym:     "Trailhead",
+						Type:    "Trail Head",
+						Present: gpx.WptEle,
 					},
 				},
 			},
@@ -441,22 +476,25 @@ func TestTrk(t *testing.T) {
 					{
 						TrkPt: []*gpx.WptType{
 							{
-								Lat:  47.644548,
-								Lon:  -122.326897,
-								Ele:  4.46,
-								Time: time.Date(2009, 10, 17, 18, 37, 26, 0, time.UTC),
+								Lat:     47.644548,
+								Lon:     -122.326897,
+								Ele:     4.46,
+								Time:    time.Date(2009, 10, 17, 18, 37, 26, 0, time.UTC),
+								Present: gpx.WptEle,
 							},
 							{
-								Lat:  47.644548,
-								Lon:  -122.326897,
-								Ele:  4.94,
-								Time: time.Date(2009, 10, 17, 18, 37, 31, 0, time.UTC),
+								Lat:     47.644548,
+								Lon:     -122.326897,
+								Ele:     4.94,
+								Time:    time.Date(2009, 10, 17, 18, 37, 31, 0, time.UTC),
+								Present: gpx.WptEle,
 							},
 							{
-								Lat:  47.644548,
-								Lon:  -122.326897,
-								Ele:  6.87,
-								Time: time.Date(2009, 10, 17, 18, 37, 34, 0, time.UTC),
+								Lat:     47.644548,
+								Lon:     -122.326897,
+								Ele:     6.87,
+								Time:    time.Date(2009, 10, 17, 18, 37, 34, 0, time.UTC),
+								Present: gpx.WptEle,
 							},
 						},
 					},
@@ -534,14 +572,15 @@ func TestRoundTrip(t *testing.T) {
 				Creator: "ExpertGPS 1.1 - http://www.topografix.com",
 				Wpt: []*gpx.WptType{
 					{
-						Lat:  42.438878,
-						Lon:  -71.119277,
-						Ele:  44.586548,
-						Time: time.Date(2001, 11, 28, 21, 5, 28, 0, time.UTC),
-						Name: "5066",
-						Desc: "5066",
-						Sym:  "Crossing",
-						Type: "Crossing",
+						Lat:     42.438878,
+						Lon:     -71.119277,
+						Ele:     44.586548,
+						Time:    time.Date(2001, 11, 28, 21, 5, 28, 0, time.UTC),
+						Name:    "5066",
+						Desc:    "5066",
+						Sym:     "Crossing",
+						Type:    "Crossing",
+						Present: gpx.WptEle,
 					},
 				},
 			},
@@ -586,29 +625,90 @@ func TestRoundTrip(t *testing.T) {
 						Number: 1,
 						RtePt: []*gpx.WptType{
 							{
-								Lat:  42.43095,
-								Lon:  -71.107628,
-								Ele:  23.4696,
-								Time: time.Date(2001, 6, 2, 0, 18, 15, 0, time.UTC),
-								Name: "BELLEVUE",
-								Cmt:  "BELLEVUE",
-								Desc: "Bellevue Parking Lot",
-								Sym:  "Parking Area",
-								Type: "Parking",
+								Lat:     42.43095,
+								Lon:     -71.107628,
+								Ele:     23.4696,
+								Time:    time.Date(2001, 6, 2, 0, 18, 15, 0, time.UTC),
+								Name:    "BELLEVUE",
+								Cmt:     "BELLEVUE",
+								Desc:    "Bellevue Parking Lot",
+								Sym:     "Parking Area",
+								Type:    "Parking",
+								Present: gpx.WptEle,
 							},
 							{
-								Lat:  42.43124,
-								Lon:  -71.109236,
-								Ele:  26.56189,
-								Time: time.Date(2001, 11, 7, 23, 53, 41, 0, time.UTC),
-								Name: "GATE6",
-								Desc: "Gate 6",
-								Sym:  "Trailhead",
-								Type: "Trail Head",
+								Lat:     42.43124,
+								Lon:     -71.109236,
+								Ele:     26.56189,
+								Time:    time.Date(2001, 11, 7, 23, 53, 41, 0, time.UTC),
+								Name:    "GATE6",
+								Desc:    "Gate 6",
+								Sym:     "Trailhead",
+								Type:    "Trail Head",
+								Present: gpx.WptEle,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			data: "<gpx" +
+				" version=\"1.1\"" +
+				" creator=\"Garmin Connect\"" +
+				" xmlns:xsi=\"http://www.w3.org/2001/XMLSchema-instance\"" +
+				" xmlns=\"http://www.topografix.com/GPX/1/1\"" +
+				" xsi:schemaLocation=\"http://www.topografix.com/GPX/1/1 https://www.topografix.com/GPX/1/1/gpx.xsd http://www.garmin.com/xmlschemas/TrackPointExtension/v1 http://www.garmin.com/xmlschemas/TrackPointExtensionv1.xsd\"" +
+				" xmlns:gpxtpx=\"http://www.garmin.com/xmlschemas/TrackPointExtension/v1\"" +
+				" xmlns:gpxx=\"http://www.garmin.com/xmlschemas/GpxExtensions/v3\">\n" +
+				"\t<trk>\n" +
+				"\t\t<trkseg>\n" +
+				"\t\t\t<trkpt lat=\"47.644548\" lon=\"-122.326897\">\n" +
+				"\t\t\t\t<extensions><gpxtpx:TrackPointExtension><gpxtpx:hr>120</gpxtpx:hr></gpxtpx:TrackPointExtension></extensions>\n" +
+				"\t\t\t</trkpt>\n" +
+				"\t\t</trkseg>\n" +
+				"\t</trk>\n" +
+				"\t<extensions><gpxx:Creator>creator</gpxx:Creator></extensions>\n" +
+				"</gpx>",
+			gpx: &gpx.GPX{
+				XMLSchemaLocations: []string{
+					"http://www.garmin.com/xmlschemas/TrackPointExtension/v1",
+					"http://www.garmin.com/xmlschemas/TrackPointExtensionv1.xsd",
+				},
+				XMLAttrs: map[string]string{
+					"xmlns:gpxtpx": "http://www.garmin.com/xmlschemas/TrackPointExtension/v1",
+					"xmlns:gpxx":   "http://www.garmin.com/xmlschemas/GpxExtensions/v3",
+				},
+				Version: "1.1",
+				Creator: "Garmin Connect",
+				Trk: []*gpx.TrkType{
+					{
+						TrkSeg: []*gpx.TrkSegType{
+							{
+								TrkPt: []*gpx.WptType{
+									{
+										Lat: 47.644548,
+										Lon: -122.326897,
+										Extensions: &gpx.ExtensionsType{
+											XML: []byte("<gpxtpx:TrackPointExtension><gpxtpx:hr>120</gpxtpx:hr></gpxtpx:TrackPointExtension>"),
+											Namespaces: map[string]string{
+												"gpxtpx": "http://www.garmin.com/xmlschemas/TrackPointExtension/v1",
+												"gpxx":   "http://www.garmin.com/xmlschemas/GpxExtensions/v3",
+											},
+										},
+									},
+								},
 							},
 						},
 					},
 				},
+				Extensions: &gpx.ExtensionsType{
+					XML: []byte("<gpxx:Creator>creator</gpxx:Creator>"),
+					Namespaces: map[string]string{
+						"gpxtpx": "http://www.garmin.com/xmlschemas/TrackPointExtension/v1",
+						"gpxx":   "http://www.garmin.com/xmlschemas/GpxExtensions/v3",
+					},
+				},
 			},
 		},
 	} {
@@ -653,7 +753,7 @@ func TestParseExamples(t *testing.T) {
 	err := fs.WalkDir(os.DirFS(dir), ".", func(filename string, d fs.DirEntry, err error) error {
 		assert.NoError(t, err)
 
-		if d.IsDir() {
+		if d.IsDir() || filepath.Ext(filename) != ".gpx" {
 			return nil
 		}
 		f, err := os.Open(filepath.Join(dir, filename))
@@ -741,9 +841,6 @@ func TestCopyright(t *testing.T) {
 }
 
 func TestWithTimeLayout(t *testing.T) {
-	// Cleanup
-	defer gpx.WithTimeLayout(time.RFC3339Nano)()
-
 	// Arrange
 	testGPX := bytes.NewBufferString(`<?xml version="1.0" encoding="utf-8"?>
 <gpx xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:xsd="http://www.w3.org/2001/XMLSchema" version="1.0" xsi:schemaLocation="http://www.topografix.com/GPX/1/0 http://www.topografix.com/GPX/1/0/gpx.xsd http://www.groundspeak.com/cache/1/0/1 http://www.groundspeak.com/cache/1/0/1/cache.xsd" creator="Groundspeak Pocket Query" xmlns="http://www.topografix.com/GPX/1/0">
@@ -772,3 +869,125 @@ func TestWithTimeLayout(t *testing.T) {
 	// Assert - We don't care about the actual result since we just want to know if the timestamp parsing works.
 	assert.NoError(t, err)
 }
+
+func TestWithTimeLayoutDoesNotLeak(t *testing.T) {
+	data := `<gpx version="1.1"><wpt lat="1" lon="2"><time>28/11/2001 21:05:28</time></wpt></gpx>`
+
+	g, err := gpx.Read(bytes.NewBufferString(data), gpx.WithTimeLayout("02/01/2006 15:04:05"))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2001, 11, 28, 21, 5, 28, 0, time.UTC), g.Wpt[0].Time)
+
+	_, err = gpx.Read(bytes.NewBufferString(data))
+	assert.Error(t, err)
+}
+
+func TestWithTimeLayoutFallback(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data string
+	}{
+		{
+			name: "gpx10",
+			data: `<gpx version="1.0">` +
+				`<time>2001-11-28T21:05:28Z</time>` +
+				`<wpt lat="1" lon="2"><time>28/11/2001 21:05:28</time></wpt>` +
+				`<trk><trkseg><trkpt lat="1" lon="2"><time>2001-11-28T21:05:28Z</time></trkpt></trkseg></trk>` +
+				`</gpx>`,
+		},
+		{
+			name: "gpx11",
+			data: `<gpx version="1.1">` +
+				`<metadata><time>2001-11-28T21:05:28Z</time></metadata>` +
+				`<wpt lat="1" lon="2"><time>28/11/2001 21:05:28</time></wpt>` +
+				`<trk><trkseg><trkpt lat="1" lon="2"><time>2001-11-28T21:05:28Z</time></trkpt></trkseg></trk>` +
+				`</gpx>`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g, err := gpx.Read(bytes.NewBufferString(tc.data), gpx.WithTimeLayout("02/01/2006 15:04:05"))
+			assert.NoError(t, err)
+			expected := time.Date(2001, 11, 28, 21, 5, 28, 0, time.UTC)
+			assert.Equal(t, expected, g.Metadata.Time)
+			assert.Equal(t, expected, g.Wpt[0].Time)
+			assert.Equal(t, expected, g.Trk[0].TrkSeg[0].TrkPt[0].Time)
+		})
+	}
+}
+
+func TestReadOptions(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		data        string
+		options     []gpx.ReadOption
+		expectedErr bool
+	}{
+		{
+			name: "missing_lat_lon",
+			data: `<gpx version="1.1"><wpt lon="2"></wpt></gpx>`,
+		},
+		{
+			name:        "missing_lat_lon_strict",
+			data:        `<gpx version="1.1"><wpt lon="2"></wpt></gpx>`,
+			options:     []gpx.ReadOption{gpx.WithStrict()},
+			expectedErr: true,
+		},
+		{
+			name:        "invalid_lat_lon_strict",
+			data:        `<gpx version="1.1"><wpt lat="91" lon="2"></wpt></gpx>`,
+			options:     []gpx.ReadOption{gpx.WithStrict()},
+			expectedErr: true,
+		},
+		{
+			name: "unexpected_element",
+			data: `<gpx version="1.1"><trk><trkseg><foo/></trkseg></trk></gpx>`,
+		},
+		{
+			name:        "unexpected_element_strict",
+			data:        `<gpx version="1.1"><trk><trkseg><foo/></trkseg></trk></gpx>`,
+			options:     []gpx.ReadOption{gpx.WithStrict()},
+			expectedErr: true,
+		},
+		{
+			name:    "max_points",
+			data:    `<gpx version="1.1"><wpt lat="1" lon="2"></wpt><rte><rtept lat="1" lon="2"></rtept></rte></gpx>`,
+			options: []gpx.ReadOption{gpx.WithMaxPoints(2)},
+		},
+		{
+			name:        "too_many_points",
+			data:        `<gpx version="1.1"><wpt lat="1" lon="2"></wpt><rte><rtept lat="1" lon="2"></rtept></rte></gpx>`,
+			options:     []gpx.ReadOption{gpx.WithMaxPoints(1)},
+			expectedErr: true,
+		},
+		{
+			name: "extensions_handler",
+			data: `<gpx version="1.1"><wpt lat="1" lon="2"><extensions><foo/></extensions></wpt></gpx>`,
+			options: []gpx.ReadOption{
+				gpx.WithExtensionsHandler(func(extensions *gpx.ExtensionsType) error {
+					if string(extensions.XML) != "<foo/>" {
+						return errors.New("unexpected extensions")
+					}
+					return nil
+				}),
+			},
+		},
+		{
+			name: "extensions_handler_error",
+			data: `<gpx version="1.1"><trk><extensions><foo/></extensions></trk></gpx>`,
+			options: []gpx.ReadOption{
+				gpx.WithExtensionsHandler(func(*gpx.ExtensionsType) error {
+					return errors.New("error")
+				}),
+			},
+			expectedErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := gpx.Read(bytes.NewBufferString(tc.data), tc.options...)
+			if tc.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,395 @@
+package gpx
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IGCExtensionV1Namespace is the namespace of IGCExtensions. It is a private
+// namespace with no published schema that is only understood by this package,
+// and it may change.
+const IGCExtensionV1Namespace = "https://github.com/twpayne/go-gpx/xmlschemas/IGCExtension/v1"
+
+var igcExtensionNamespaces = []string{
+	IGCExtensionV1Namespace,
+}
+
+var (
+	errInvalidIGCRecord = errors.New("invalid IGC record")
+	errMissingIGCDate   = errors.New("missing IGC date")
+)
+
+// An IGCAltitude selects which IGC altitude is used for elevations.
+type IGCAltitude int
+
+// IGC altitudes.
+const (
+	// IGCAltitudePressure uses the pressure altitude, relative to the ICAO
+	// standard atmosphere.
+	IGCAltitudePressure IGCAltitude = iota
+	// IGCAltitudeGNSS uses the GNSS altitude, relative to the WGS84
+	// ellipsoid.
+	IGCAltitudeGNSS
+)
+
+// An IGCExtension contains the altitudes of an IGC fix that are not used for
+// the point's elevation. Zero altitudes are not recorded.
+type IGCExtension struct {
+	PressureAltitude int `xml:"PressureAltitude"`
+	GNSSAltitude     int `xml:"GNSSAltitude"`
+}
+
+// An IGCOption sets an option for ReadIGC.
+type IGCOption func(*igcOptions)
+
+type igcOptions struct {
+	altitude IGCAltitude
+}
+
+// WithIGCAltitude sets which altitude is used for elevations. The default is
+// IGCAltitudePressure.
+func WithIGCAltitude(altitude IGCAltitude) IGCOption {
+	return func(o *igcOptions) {
+		o.altitude = altitude
+	}
+}
+
+// ReadIGC reads a GPX from the IGC flight log in r. The pilot becomes the
+// metadata author, and the glider ID, glider type, and flight recorder type
+// become the track's name, type, and source. Other H records, including the
+// competition ID, competition class, and co-pilot, are ignored. Each B record
+// becomes a track point of a single track segment, with the selected altitude
+// as its elevation and the other altitude stored in its IGCExtension. If the
+// selected altitude of a fix is zero, meaning that it was not recorded, then
+// the other altitude is used instead. Fixes marked as 2D have a 2d fix, others
+// a 3d fix. A task declared in C records becomes a route.
+func ReadIGC(r io.Reader, options ...IGCOption) (*GPX, error) {
+	var o igcOptions
+	for _, option := range options {
+		option(&o)
+	}
+	g := &GPX{
+		Version: "1.1",
+	}
+	trk := &TrkType{}
+	trkSeg := &TrkSegType{}
+	var rte *RteType
+	var date time.Time
+	var lastTimeOfDay time.Duration
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		var err error
+		switch line[0] {
+		case 'B':
+			if date.IsZero() {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, errMissingIGCDate)
+			}
+			var trkPt *WptType
+			var timeOfDay time.Duration
+			if trkPt, timeOfDay, err = parseIGCBRecord(line, o.altitude); err != nil {
+				break
+			}
+			if timeOfDay < lastTimeOfDay {
+				date = date.AddDate(0, 0, 1)
+			}
+			lastTimeOfDay = timeOfDay
+			trkPt.Time = date.Add(timeOfDay)
+			trkSeg.TrkPt = append(trkSeg.TrkPt, trkPt)
+		case 'C':
+			switch {
+			case rte == nil:
+				if len(line) < 25 {
+					err = errInvalidIGCRecord
+					break
+				}
+				rte = &RteType{
+					Name: strings.TrimSpace(line[25:]),
+				}
+				g.Rte = append(g.Rte, rte)
+			default:
+				var rtePt *WptType
+				if rtePt, err = parseIGCCRecord(line); err == nil && (rtePt.Lat != 0 || rtePt.Lon != 0) {
+					rte.RtePt = append(rte.RtePt, rtePt)
+				}
+			}
+		case 'H':
+			if len(line) < 5 {
+				err = errInvalidIGCRecord
+				break
+			}
+			value := line[5:]
+			if _, v, ok := strings.Cut(value, ":"); ok {
+				value = v
+			}
+			value = strings.TrimSpace(value)
+			switch line[2:5] {
+			case "DTE":
+				if len(value) < 6 {
+					err = errInvalidIGCRecord
+					break
+				}
+				if date, err = time.Parse("020106", value[:6]); err != nil {
+					err = errInvalidIGCRecord
+				}
+			case "FTY":
+				trk.Src = value
+			case "GID":
+				trk.Name = value
+			case "GTY":
+				trk.Type = value
+			case "PLT":
+				if value != "" {
+					g.Metadata = &MetadataType{
+						Author: &PersonType{
+							Name: value,
+						},
+					}
+				}
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(trkSeg.TrkPt) > 0 {
+		trk.TrkSeg = append(trk.TrkSeg, trkSeg)
+	}
+	g.Trk = append(g.Trk, trk)
+	return g, nil
+}
+
+// IGCExtension returns w's IGCExtension, or nil if w does not have one.
+func (w *WptType) IGCExtension() (*IGCExtension, error) {
+	ie := &IGCExtension{}
+	if _, ok, err := w.Extensions.decodeElement(igcExtensionNamespaces, "IGCExtension", ie); err != nil || !ok {
+		return nil, err
+	}
+	return ie, nil
+}
+
+// SetIGCExtension sets w's IGCExtension to ie. If ie is nil then any existing
+// IGCExtension is removed.
+func (w *WptType) SetIGCExtension(ie *IGCExtension) error {
+	if ie == nil {
+		return setElement(&w.Extensions, igcExtensionNamespaces, "IGCExtension", nil)
+	}
+	return setElement(&w.Extensions, igcExtensionNamespaces, "IGCExtension", ie.encode)
+}
+
+// WriteIGC writes t to w as an IGC flight log. The track's name, type, and
+// source are written as the glider ID, glider type, and flight recorder type.
+// Each track point becomes a B record, with its elevation as the GNSS altitude
+// and the pressure altitude from its IGCExtension, or, if its IGCExtension
+// contains a GNSS altitude, with its elevation as the pressure altitude. All
+// track points must have a time. The A record names a generic flight recorder
+// and no G record is written, so the output is unsigned and fails IGC
+// security validation.
+func (t *TrkType) WriteIGC(w io.Writer) error {
+	var trkPts []*WptType
+	for _, trkSeg := range t.TrkSeg {
+		trkPts = append(trkPts, trkSeg.TrkPt...)
+	}
+	for _, trkPt := range trkPts {
+		if trkPt.Time.IsZero() {
+			return errMissingTime
+		}
+	}
+	lines := []string{
+		"AXXXGPX",
+	}
+	if len(trkPts) > 0 {
+		lines = append(lines, "HFDTE"+trkPts[0].Time.UTC().Format("020106"))
+	}
+	lines = append(lines, "HFDTMGPSDATUM:WGS-1984")
+	for _, header := range []struct {
+		code  string
+		value string
+	}{
+		{"HFGIDGLIDERID:", t.Name},
+		{"HFGTYGLIDERTYPE:", t.Type},
+		{"HFFTYFRTYPE:", t.Src},
+	} {
+		if header.value != "" {
+			lines = append(lines, header.code+header.value)
+		}
+	}
+	for _, trkPt := range trkPts {
+		line, err := trkPt.igcBRecord()
+		if err != nil {
+			return err
+		}
+		lines = append(lines, line)
+	}
+	bw := bufio.NewWriter(w)
+	for _, line := range lines {
+		if _, err := bw.WriteString(line + "\r\n"); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// igcBRecord returns w as an IGC B record.
+func (w *WptType) igcBRecord() (string, error) {
+	ie, err := w.IGCExtension()
+	if err != nil {
+		return "", err
+	}
+	if ie == nil {
+		ie = &IGCExtension{}
+	}
+	pressureAltitude, gnssAltitude := ie.PressureAltitude, int(math.Round(w.Ele))
+	if ie.GNSSAltitude != 0 {
+		pressureAltitude, gnssAltitude = int(math.Round(w.Ele)), ie.GNSSAltitude
+	}
+	validity := 'A'
+	if w.Fix == "2d" {
+		validity = 'V'
+	}
+	t := w.Time.UTC()
+	return fmt.Sprintf("B%02d%02d%02d%s%s%c%05d%05d",
+		t.Hour(), t.Minute(), t.Second(),
+		formatIGCAngle(w.Lat, 2, 'N', 'S'),
+		formatIGCAngle(w.Lon, 3, 'E', 'W'),
+		validity, pressureAltitude, gnssAltitude,
+	), nil
+}
+
+func (ie *IGCExtension) encode(e *xml.Encoder, extensions *ExtensionsType) error {
+	prefix := extensions.prefix(IGCExtensionV1Namespace, "igc")
+	start := xml.StartElement{Name: xml.Name{Local: prefix + ":IGCExtension"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := maybeEmitIntElement(e, prefix+":PressureAltitude", ie.PressureAltitude); err != nil {
+		return err
+	}
+	if err := maybeEmitIntElement(e, prefix+":GNSSAltitude", ie.GNSSAltitude); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+// formatIGCAngle formats angle in IGC degrees and thousandths of minutes
+// format with degreeDigits digits of degrees.
+func formatIGCAngle(angle float64, degreeDigits int, positive, negative byte) string {
+	hemisphere := positive
+	if angle < 0 {
+		hemisphere = negative
+	}
+	milliMinutes := int(math.Round(math.Abs(angle) * 60000))
+	return fmt.Sprintf("%0*d%05d%c", degreeDigits, milliMinutes/60000, milliMinutes%60000, hemisphere)
+}
+
+// parseIGCAngle parses s in IGC degrees and thousandths of minutes format with
+// degreeDigits digits of degrees.
+func parseIGCAngle(s string, degreeDigits int, positive, negative byte) (float64, error) {
+	degrees, err := strconv.Atoi(s[:degreeDigits])
+	if err != nil {
+		return 0, errInvalidIGCRecord
+	}
+	milliMinutes, err := strconv.Atoi(s[degreeDigits : degreeDigits+5])
+	if err != nil {
+		return 0, errInvalidIGCRecord
+	}
+	angle := float64(degrees) + float64(milliMinutes)/60000
+	switch s[degreeDigits+5] {
+	case positive:
+		return angle, nil
+	case negative:
+		return -angle, nil
+	default:
+		return 0, errInvalidIGCRecord
+	}
+}
+
+// parseIGCBRecord parses the IGC B record line. It returns the fix, without
+// its time, and the time of day.
+func parseIGCBRecord(line string, altitude IGCAltitude) (*WptType, time.Duration, error) {
+	if len(line) < 35 {
+		return nil, 0, errInvalidIGCRecord
+	}
+	t, err := time.Parse("150405", line[1:7])
+	if err != nil {
+		return nil, 0, errInvalidIGCRecord
+	}
+	timeOfDay := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	lat, err := parseIGCAngle(line[7:15], 2, 'N', 'S')
+	if err != nil {
+		return nil, 0, err
+	}
+	lon, err := parseIGCAngle(line[15:24], 3, 'E', 'W')
+	if err != nil {
+		return nil, 0, err
+	}
+	pressureAltitude, err := strconv.Atoi(line[25:30])
+	if err != nil {
+		return nil, 0, errInvalidIGCRecord
+	}
+	gnssAltitude, err := strconv.Atoi(line[30:35])
+	if err != nil {
+		return nil, 0, errInvalidIGCRecord
+	}
+	trkPt := &WptType{
+		Lat:     lat,
+		Lon:     lon,
+		Fix:     "3d",
+		Present: WptEle,
+	}
+	if line[24] == 'V' {
+		trkPt.Fix = "2d"
+	}
+	var ie *IGCExtension
+	switch {
+	case altitude == IGCAltitudeGNSS && gnssAltitude != 0 || pressureAltitude == 0:
+		trkPt.Ele = float64(gnssAltitude)
+		if pressureAltitude != 0 {
+			ie = &IGCExtension{PressureAltitude: pressureAltitude}
+		}
+	default:
+		trkPt.Ele = float64(pressureAltitude)
+		if gnssAltitude != 0 {
+			ie = &IGCExtension{GNSSAltitude: gnssAltitude}
+		}
+	}
+	if ie != nil {
+		if err := trkPt.SetIGCExtension(ie); err != nil {
+			return nil, 0, err
+		}
+	}
+	return trkPt, timeOfDay, nil
+}
+
+// parseIGCCRecord parses the IGC C record line as a route point.
+func parseIGCCRecord(line string) (*WptType, error) {
+	if len(line) < 18 {
+		return nil, errInvalidIGCRecord
+	}
+	lat, err := parseIGCAngle(line[1:9], 2, 'N', 'S')
+	if err != nil {
+		return nil, err
+	}
+	lon, err := parseIGCAngle(line[9:18], 3, 'E', 'W')
+	if err != nil {
+		return nil, err
+	}
+	return &WptType{
+		Lat:  lat,
+		Lon:  lon,
+		Name: strings.TrimSpace(line[18:]),
+	}, nil
+}
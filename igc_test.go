@@ -0,0 +1,166 @@
+package gpx_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	gpx "github.com/twpayne/go-gpx"
+)
+
+const igcFlight = "AXCTABC\r\n" +
+	"HFDTEDATE:010624,01\r\n" +
+	"HFPLTPILOTINCHARGE:Jane Doe\r\n" +
+	"HFCM2CREW2:\r\n" +
+	"HFGTYGLIDERTYPE:Ozone Rush 5\r\n" +
+	"HFGIDGLIDERID:D-1234\r\n" +
+	"HFFTYFRTYPE:XCTrack\r\n" +
+	"I013638FXA\r\n" +
+	"C010624093000010624000102Annecy triangle\r\n" +
+	"C0000000N00000000E\r\n" +
+	"C4553123N00610456ETakeoff Planfait\r\n" +
+	"C4550000N00620000ETP1\r\n" +
+	"C0000000N00000000E\r\n" +
+	"B1000004553123N00610456EA0120001250025\r\n" +
+	"B1000014553150N00610500EA0121001251025\r\n" +
+	"LXCTcomment\r\n" +
+	"B2359594553150N00610500WV0000001251025\r\n" +
+	"B0000014553150S00610500EA-001500000025\r\n" +
+	"GABCDEF\r\n"
+
+func TestReadIGC(t *testing.T) {
+	igcAngle := func(degrees, milliMinutes int) float64 {
+		return float64(degrees) + float64(milliMinutes)/60000
+	}
+	newTrkPt := func(lat, lon float64, ele float64, tm time.Time, fix string, ie *gpx.IGCExtension) *gpx.WptType {
+		trkPt := &gpx.WptType{Lat: lat, Lon: lon, Ele: ele, Time: tm, Fix: fix, Present: gpx.WptEle}
+		if ie != nil {
+			assert.NoError(t, trkPt.SetIGCExtension(ie))
+		}
+		return trkPt
+	}
+	t0 := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name          string
+		options       []gpx.IGCOption
+		expectedTrkPt []*gpx.WptType
+	}{
+		{
+			name: "pressure",
+			expectedTrkPt: []*gpx.WptType{
+				newTrkPt(igcAngle(45, 53123), igcAngle(6, 10456), 1200, t0, "3d", &gpx.IGCExtension{GNSSAltitude: 1250}),
+				newTrkPt(igcAngle(45, 53150), igcAngle(6, 10500), 1210, t0.Add(time.Second), "3d", &gpx.IGCExtension{GNSSAltitude: 1251}),
+				newTrkPt(igcAngle(45, 53150), -igcAngle(6, 10500), 1251, time.Date(2024, 6, 1, 23, 59, 59, 0, time.UTC), "2d", nil),
+				newTrkPt(-igcAngle(45, 53150), igcAngle(6, 10500), -15, time.Date(2024, 6, 2, 0, 0, 1, 0, time.UTC), "3d", nil),
+			},
+		},
+		{
+			name:    "gnss",
+			options: []gpx.IGCOption{gpx.WithIGCAltitude(gpx.IGCAltitudeGNSS)},
+			expectedTrkPt: []*gpx.WptType{
+				newTrkPt(igcAngle(45, 53123), igcAngle(6, 10456), 1250, t0, "3d", &gpx.IGCExtension{PressureAltitude: 1200}),
+				newTrkPt(igcAngle(45, 53150), igcAngle(6, 10500), 1251, t0.Add(time.Second), "3d", &gpx.IGCExtension{PressureAltitude: 1210}),
+				newTrkPt(igcAngle(45, 53150), -igcAngle(6, 10500), 1251, time.Date(2024, 6, 1, 23, 59, 59, 0, time.UTC), "2d", nil),
+				newTrkPt(-igcAngle(45, 53150), igcAngle(6, 10500), -15, time.Date(2024, 6, 2, 0, 0, 1, 0, time.UTC), "3d", nil),
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g, err := gpx.ReadIGC(strings.NewReader(igcFlight), tc.options...)
+			assert.NoError(t, err)
+			assert.Equal(t, &gpx.GPX{
+				Version: "1.1",
+				Metadata: &gpx.MetadataType{
+					Author: &gpx.PersonType{
+						Name: "Jane Doe",
+					},
+				},
+				Rte: []*gpx.RteType{
+					{
+						Name: "Annecy triangle",
+						RtePt: []*gpx.WptType{
+							{Lat: igcAngle(45, 53123), Lon: igcAngle(6, 10456), Name: "Takeoff Planfait"},
+							{Lat: igcAngle(45, 50000), Lon: igcAngle(6, 20000), Name: "TP1"},
+						},
+					},
+				},
+				Trk: []*gpx.TrkType{
+					{
+						Name: "D-1234",
+						Src:  "XCTrack",
+						Type: "Ozone Rush 5",
+						TrkSeg: []*gpx.TrkSegType{
+							{
+								TrkPt: tc.expectedTrkPt,
+							},
+						},
+					},
+				},
+			}, g)
+		})
+	}
+}
+
+func TestReadIGCErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		data          string
+		expectedError string
+	}{
+		{
+			name:          "missing_date",
+			data:          "AXXXGPX\r\nB1000004553123N00610456EA0120001250\r\n",
+			expectedError: "line 2: missing IGC date",
+		},
+		{
+			name:          "invalid_b_record",
+			data:          "HFDTE010624\r\nB1000004553123X00610456EA0120001250\r\n",
+			expectedError: "line 2: invalid IGC record",
+		},
+		{
+			name:          "short_b_record",
+			data:          "HFDTE010624\r\nB1000004553123N00610456EA01200\r\n",
+			expectedError: "line 2: invalid IGC record",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := gpx.ReadIGC(strings.NewReader(tc.data))
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestWriteIGC(t *testing.T) {
+	g, err := gpx.ReadIGC(strings.NewReader(igcFlight))
+	assert.NoError(t, err)
+
+	var sb strings.Builder
+	assert.NoError(t, g.Trk[0].WriteIGC(&sb))
+	assert.Equal(t, "AXXXGPX\r\n"+
+		"HFDTE010624\r\n"+
+		"HFDTMGPSDATUM:WGS-1984\r\n"+
+		"HFGIDGLIDERID:D-1234\r\n"+
+		"HFGTYGLIDERTYPE:Ozone Rush 5\r\n"+
+		"HFFTYFRTYPE:XCTrack\r\n"+
+		"B1000004553123N00610456EA0120001250\r\n"+
+		"B1000014553150N00610500EA0121001251\r\n"+
+		"B2359594553150N00610500WV0000001251\r\n"+
+		"B0000014553150S00610500EA00000-0015\r\n", sb.String())
+
+	actual, err := gpx.ReadIGC(strings.NewReader(sb.String()))
+	assert.NoError(t, err)
+	assert.Equal(t, g.Trk, actual.Trk)
+
+	assert.EqualError(t, (&gpx.TrkType{
+		TrkSeg: []*gpx.TrkSegType{
+			{
+				TrkPt: []*gpx.WptType{
+					{Lat: 1, Lon: 2},
+				},
+			},
+		},
+	}).WriteIGC(&sb), "missing time")
+}
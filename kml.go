@@ -0,0 +1,712 @@
+package gpx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/twpayne/go-geom"
+)
+
+// KML namespaces.
+const (
+	KMLNamespace   = "http://www.opengis.net/kml/2.2"
+	KMLGxNamespace = "http://www.google.com/kml/ext/2.2"
+)
+
+// kmzDocName is the name of the KML document written to KMZ files.
+const kmzDocName = "doc.kml"
+
+var (
+	errInvalidCoordinates = errors.New("invalid coordinates")
+	errInvalidKMLColor    = errors.New("invalid KML color")
+	errNoKMLDocument      = errors.New("no KML document")
+	errNoKMLGeometry      = errors.New("no KML geometry")
+)
+
+type kmlKML struct {
+	kmlContainer
+}
+
+type kmlContainer struct {
+	Name        string          `xml:"name"`
+	Description string          `xml:"description"`
+	Styles      []*kmlStyle     `xml:"Style"`
+	StyleMaps   []*kmlStyleMap  `xml:"StyleMap"`
+	Placemarks  []*kmlPlacemark `xml:"Placemark"`
+	Documents   []*kmlContainer `xml:"Document"`
+	Folders     []*kmlContainer `xml:"Folder"`
+}
+
+type kmlStyle struct {
+	ID        string `xml:"id,attr"`
+	LineColor string `xml:"LineStyle>color"`
+}
+
+type kmlStyleMap struct {
+	ID    string          `xml:"id,attr"`
+	Pairs []*kmlStylePair `xml:"Pair"`
+}
+
+type kmlStylePair struct {
+	Key      string    `xml:"key"`
+	StyleURL string    `xml:"styleUrl"`
+	Style    *kmlStyle `xml:"Style"`
+}
+
+type kmlPlacemark struct {
+	Name        string `xml:"name"`
+	Description string `xml:"description"`
+	When        string `xml:"TimeStamp>when"`
+	StyleURL    string `xml:"styleUrl"`
+	kmlMultiGeometry
+}
+
+type kmlMultiGeometry struct {
+	Points          []*kmlGeometry      `xml:"Point"`
+	LineStrings     []*kmlGeometry      `xml:"LineString"`
+	LinearRings     []*kmlGeometry      `xml:"LinearRing"`
+	Polygons        []struct{}          `xml:"Polygon"`
+	Models          []struct{}          `xml:"Model"`
+	Tracks          []*kmlTrack         `xml:"http://www.google.com/kml/ext/2.2 Track"`
+	MultiTracks     []*kmlMultiTrack    `xml:"http://www.google.com/kml/ext/2.2 MultiTrack"`
+	MultiGeometries []*kmlMultiGeometry `xml:"MultiGeometry"`
+}
+
+type kmlGeometry struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlTrack struct {
+	When  []string `xml:"when"`
+	Coord []string `xml:"http://www.google.com/kml/ext/2.2 coord"`
+}
+
+type kmlMultiTrack struct {
+	Tracks []*kmlTrack `xml:"http://www.google.com/kml/ext/2.2 Track"`
+}
+
+// ReadKML reads a GPX from the KML document in r. Placemarks with a Point
+// become waypoints, placemarks with a LineString or LinearRing become routes,
+// and placemarks with a gx:Track or gx:MultiTrack become tracks, with a
+// segment for each gx:Track. Each geometry in a MultiGeometry is converted in
+// the same way. Placemarks with no geometry, or with a Polygon or Model, are
+// an error. Placemarks in nested Documents and Folders are included. The
+// document's name and description become the metadata. Line colors that match
+// a Garmin display color are set in the Garmin RouteExtension or
+// TrackExtension. Styles referenced through a StyleMap use the StyleMap's
+// normal style.
+func ReadKML(r io.Reader) (*GPX, error) {
+	var kml kmlKML
+	if err := xml.NewDecoder(r).Decode(&kml); err != nil {
+		return nil, err
+	}
+	displayColors := make(map[string]DisplayColor)
+	var styleMaps []*kmlStyleMap
+	var placemarks []*kmlPlacemark
+	kml.walk(func(c *kmlContainer) {
+		for _, style := range c.Styles {
+			if displayColor, err := parseKMLColor(style.LineColor); err == nil && style.ID != "" {
+				displayColors["#"+style.ID] = displayColor
+			}
+		}
+		styleMaps = append(styleMaps, c.StyleMaps...)
+		placemarks = append(placemarks, c.Placemarks...)
+	})
+	for _, styleMap := range styleMaps {
+		if displayColor := styleMap.displayColor(displayColors); displayColor != "" && styleMap.ID != "" {
+			displayColors["#"+styleMap.ID] = displayColor
+		}
+	}
+	g := &GPX{
+		Version: "1.1",
+	}
+	document := &kml.kmlContainer
+	if len(document.Documents) > 0 {
+		document = document.Documents[0]
+	}
+	if document.Name != "" || document.Description != "" {
+		g.Metadata = &MetadataType{
+			Name: document.Name,
+			Desc: document.Description,
+		}
+	}
+	for i, placemark := range placemarks {
+		if err := g.addKMLPlacemark(placemark, displayColors[placemark.StyleURL]); err != nil {
+			return nil, fmt.Errorf("placemark %d: %w", i, err)
+		}
+	}
+	return g, nil
+}
+
+// ReadKMZ reads a GPX from the KMZ archive in r, which has size bytes. The
+// archive's doc.kml is read if it exists, otherwise its first .kml file is
+// read, as ReadKML.
+func ReadKMZ(r io.ReaderAt, size int64) (*GPX, error) {
+	zipReader, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	var kmlFile *zip.File
+	for _, file := range zipReader.File {
+		switch {
+		case file.Name == kmzDocName:
+			kmlFile = file
+		case kmlFile == nil && strings.EqualFold(path.Ext(file.Name), ".kml"):
+			kmlFile = file
+		}
+	}
+	if kmlFile == nil {
+		return nil, errNoKMLDocument
+	}
+	rc, err := kmlFile.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ReadKML(rc)
+}
+
+// WriteKML writes g to w as a KML document. Waypoints become placemarks with a
+// Point, routes become placemarks with a LineString, and tracks become
+// placemarks with a gx:Track, or a gx:MultiTrack if they have more than one
+// segment. Tracks with points without a time are written as a LineString, or a
+// MultiGeometry of LineStrings, instead, and so are read back as routes.
+// Names, descriptions, and times are kept. Elevations are written, with an
+// absolute altitude mode, if all of a geometry's points have an elevation.
+// Routes and tracks with a Garmin display color are styled with that color.
+func (g *GPX) WriteKML(w io.Writer) error {
+	e := xml.NewEncoder(w)
+	if err := g.encodeKML(e); err != nil {
+		return err
+	}
+	return e.Close()
+}
+
+// WriteKMZ writes g to w as a KMZ archive containing a single KML document,
+// as WriteKML.
+func (g *GPX) WriteKMZ(w io.Writer) error {
+	zipWriter := zip.NewWriter(w)
+	kmlWriter, err := zipWriter.Create(kmzDocName)
+	if err != nil {
+		return err
+	}
+	if err := g.WriteKML(kmlWriter); err != nil {
+		return err
+	}
+	return zipWriter.Close()
+}
+
+// addKMLPlacemark adds placemark to g.
+func (g *GPX) addKMLPlacemark(placemark *kmlPlacemark, displayColor DisplayColor) error {
+	if placemark.empty() {
+		return errNoKMLGeometry
+	}
+	return g.addKMLGeometries(placemark, &placemark.kmlMultiGeometry, displayColor)
+}
+
+// addKMLGeometries adds the geometries in m, with the name, description, and
+// time of placemark, to g.
+func (g *GPX) addKMLGeometries(placemark *kmlPlacemark, m *kmlMultiGeometry, displayColor DisplayColor) error {
+	switch {
+	case len(m.Polygons) > 0:
+		return fmt.Errorf("%s: %w", "Polygon", errUnsupportedGeometry)
+	case len(m.Models) > 0:
+		return fmt.Errorf("%s: %w", "Model", errUnsupportedGeometry)
+	}
+	for _, point := range m.Points {
+		wpts, err := parseKMLCoordinates(point.Coordinates)
+		if err != nil {
+			return err
+		}
+		if len(wpts) != 1 {
+			return errInvalidCoordinates
+		}
+		wpt := wpts[0]
+		wpt.Name = placemark.Name
+		wpt.Desc = placemark.Description
+		if placemark.When != "" {
+			if wpt.Time, err = parseDefaultTime(placemark.When); err != nil {
+				return err
+			}
+		}
+		g.Wpt = append(g.Wpt, wpt)
+	}
+	for _, lineString := range slices.Concat(m.LineStrings, m.LinearRings) {
+		rtePts, err := parseKMLCoordinates(lineString.Coordinates)
+		if err != nil {
+			return err
+		}
+		rte := &RteType{
+			Name:  placemark.Name,
+			Desc:  placemark.Description,
+			RtePt: rtePts,
+		}
+		if displayColor != "" {
+			if err := rte.SetRouteExtension(&RouteExtension{DisplayColor: displayColor}); err != nil {
+				return err
+			}
+		}
+		g.Rte = append(g.Rte, rte)
+	}
+	multiTracks := make([]*kmlMultiTrack, 0, len(m.Tracks)+len(m.MultiTracks))
+	for _, track := range m.Tracks {
+		multiTracks = append(multiTracks, &kmlMultiTrack{Tracks: []*kmlTrack{track}})
+	}
+	multiTracks = append(multiTracks, m.MultiTracks...)
+	for _, multiTrack := range multiTracks {
+		trk := &TrkType{
+			Name: placemark.Name,
+			Desc: placemark.Description,
+		}
+		for _, track := range multiTrack.Tracks {
+			trkSeg, err := track.trkSegType()
+			if err != nil {
+				return err
+			}
+			trk.TrkSeg = append(trk.TrkSeg, trkSeg)
+		}
+		if displayColor != "" {
+			if err := trk.SetTrackExtension(&TrackExtension{DisplayColor: displayColor}); err != nil {
+				return err
+			}
+		}
+		g.Trk = append(g.Trk, trk)
+	}
+	for _, multiGeometry := range m.MultiGeometries {
+		if err := g.addKMLGeometries(placemark, multiGeometry, displayColor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeKML writes g as a KML document to e.
+func (g *GPX) encodeKML(e *xml.Encoder) error {
+	kmlStart := xml.StartElement{
+		Name: xml.Name{Local: "kml"},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "xmlns"}, Value: KMLNamespace},
+			{Name: xml.Name{Local: "xmlns:gx"}, Value: KMLGxNamespace},
+		},
+	}
+	documentStart := xml.StartElement{Name: xml.Name{Local: "Document"}}
+	if err := e.EncodeToken(kmlStart); err != nil {
+		return err
+	}
+	if err := e.EncodeToken(documentStart); err != nil {
+		return err
+	}
+	if g.Metadata != nil {
+		if err := maybeEmitStringElement(e, "name", g.Metadata.Name); err != nil {
+			return err
+		}
+		if err := maybeEmitStringElement(e, "description", g.Metadata.Desc); err != nil {
+			return err
+		}
+	}
+	rteDisplayColors := make([]DisplayColor, 0, len(g.Rte))
+	for _, rte := range g.Rte {
+		re, err := rte.RouteExtension()
+		if err != nil {
+			return err
+		}
+		var displayColor DisplayColor
+		if re != nil {
+			displayColor = re.DisplayColor
+		}
+		rteDisplayColors = append(rteDisplayColors, displayColor)
+	}
+	trkDisplayColors := make([]DisplayColor, 0, len(g.Trk))
+	for _, trk := range g.Trk {
+		te, err := trk.TrackExtension()
+		if err != nil {
+			return err
+		}
+		var displayColor DisplayColor
+		if te != nil {
+			displayColor = te.DisplayColor
+		}
+		trkDisplayColors = append(trkDisplayColors, displayColor)
+	}
+	var displayColors []DisplayColor
+	for _, displayColor := range slices.Concat(rteDisplayColors, trkDisplayColors) {
+		if displayColor.Valid() && !slices.Contains(displayColors, displayColor) {
+			displayColors = append(displayColors, displayColor)
+		}
+	}
+	for _, displayColor := range displayColors {
+		if err := encodeKMLStyle(e, displayColor); err != nil {
+			return err
+		}
+	}
+	for _, wpt := range g.Wpt {
+		if err := wpt.encodeKMLPlacemark(e); err != nil {
+			return err
+		}
+	}
+	for i, rte := range g.Rte {
+		if err := rte.encodeKMLPlacemark(e, rteDisplayColors[i]); err != nil {
+			return err
+		}
+	}
+	for i, trk := range g.Trk {
+		if err := trk.encodeKMLPlacemark(e, trkDisplayColors[i]); err != nil {
+			return err
+		}
+	}
+	if err := e.EncodeToken(documentStart.End()); err != nil {
+		return err
+	}
+	return e.EncodeToken(kmlStart.End())
+}
+
+// walk calls f on k's root container and all its nested Documents and
+// Folders.
+func (k *kmlKML) walk(f func(*kmlContainer)) {
+	var walk func(*kmlContainer)
+	walk = func(c *kmlContainer) {
+		f(c)
+		for _, document := range c.Documents {
+			walk(document)
+		}
+		for _, folder := range c.Folders {
+			walk(folder)
+		}
+	}
+	walk(&k.kmlContainer)
+}
+
+// empty returns whether m contains no geometries.
+func (m *kmlMultiGeometry) empty() bool {
+	return len(m.Points) == 0 &&
+		len(m.LineStrings) == 0 &&
+		len(m.LinearRings) == 0 &&
+		len(m.Polygons) == 0 &&
+		len(m.Models) == 0 &&
+		len(m.Tracks) == 0 &&
+		len(m.MultiTracks) == 0 &&
+		len(m.MultiGeometries) == 0
+}
+
+// displayColor returns the display color of s's normal style, looking up
+// referenced styles in displayColors, or the empty string if there is none.
+func (s *kmlStyleMap) displayColor(displayColors map[string]DisplayColor) DisplayColor {
+	for _, pair := range s.Pairs {
+		if strings.TrimSpace(pair.Key) != "normal" {
+			continue
+		}
+		if pair.Style != nil {
+			if displayColor, err := parseKMLColor(pair.Style.LineColor); err == nil {
+				return displayColor
+			}
+		}
+		return displayColors[strings.TrimSpace(pair.StyleURL)]
+	}
+	return ""
+}
+
+// trkSegType returns t as a TrkSegType.
+func (t *kmlTrack) trkSegType() (*TrkSegType, error) {
+	if len(t.When) != 0 && len(t.When) != len(t.Coord) {
+		return nil, errInvalidCoordinates
+	}
+	trkPts := make([]*WptType, 0, len(t.Coord))
+	for i, coord := range t.Coord {
+		trkPt, err := parseKMLCoord(strings.Fields(coord))
+		if err != nil {
+			return nil, err
+		}
+		if len(t.When) != 0 && t.When[i] != "" {
+			if trkPt.Time, err = parseDefaultTime(t.When[i]); err != nil {
+				return nil, err
+			}
+		}
+		trkPts = append(trkPts, trkPt)
+	}
+	return &TrkSegType{
+		TrkPt: trkPts,
+	}, nil
+}
+
+// encodeKMLPlacemark writes r as a KML placemark with a LineString to e.
+func (r *RteType) encodeKMLPlacemark(e *xml.Encoder, displayColor DisplayColor) error {
+	start := xml.StartElement{Name: xml.Name{Local: "Placemark"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := encodeKMLPlacemarkHeader(e, r.Name, r.Desc, displayColor); err != nil {
+		return err
+	}
+	lineStringStart := xml.StartElement{Name: xml.Name{Local: "LineString"}}
+	if err := e.EncodeToken(lineStringStart); err != nil {
+		return err
+	}
+	if err := encodeKMLCoordinates(e, r.RtePt); err != nil {
+		return err
+	}
+	if err := e.EncodeToken(lineStringStart.End()); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+// encodeKMLPlacemark writes t as a KML placemark to e. If all of t's points
+// have a time then the placemark has a gx:Track or gx:MultiTrack, otherwise it
+// has a LineString or a MultiGeometry of LineStrings.
+func (t *TrkType) encodeKMLPlacemark(e *xml.Encoder, displayColor DisplayColor) error {
+	start := xml.StartElement{Name: xml.Name{Local: "Placemark"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := encodeKMLPlacemarkHeader(e, t.Name, t.Desc, displayColor); err != nil {
+		return err
+	}
+	encodeTrkSeg := (*TrkSegType).encodeKMLTrack
+	multiStart := xml.StartElement{Name: xml.Name{Local: "gx:MultiTrack"}}
+	if slices.ContainsFunc(t.TrkSeg, func(ts *TrkSegType) bool {
+		return slices.ContainsFunc(ts.TrkPt, func(w *WptType) bool {
+			return w.Time.IsZero()
+		})
+	}) {
+		encodeTrkSeg = (*TrkSegType).encodeKMLLineString
+		multiStart = xml.StartElement{Name: xml.Name{Local: "MultiGeometry"}}
+	}
+	if len(t.TrkSeg) == 1 {
+		if err := encodeTrkSeg(t.TrkSeg[0], e); err != nil {
+			return err
+		}
+	} else {
+		if err := e.EncodeToken(multiStart); err != nil {
+			return err
+		}
+		for _, trkSeg := range t.TrkSeg {
+			if err := encodeTrkSeg(trkSeg, e); err != nil {
+				return err
+			}
+		}
+		if err := e.EncodeToken(multiStart.End()); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// encodeKMLLineString writes ts as a LineString to e.
+func (ts *TrkSegType) encodeKMLLineString(e *xml.Encoder) error {
+	start := xml.StartElement{Name: xml.Name{Local: "LineString"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := encodeKMLCoordinates(e, ts.TrkPt); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+// encodeKMLTrack writes ts, whose points must all have a time, as a gx:Track
+// to e.
+func (ts *TrkSegType) encodeKMLTrack(e *xml.Encoder) error {
+	start := xml.StartElement{Name: xml.Name{Local: "gx:Track"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	layout := eleLayout(ts.TrkPt)
+	if layout == geom.XYZ {
+		if err := emitStringElement(e, "altitudeMode", "absolute"); err != nil {
+			return err
+		}
+	}
+	for _, trkPt := range ts.TrkPt {
+		if err := emitStringElement(e, "when", trkPt.Time.Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+	}
+	for _, trkPt := range ts.TrkPt {
+		if err := emitStringElement(e, "gx:coord", strings.Join(trkPt.kmlCoord(layout), " ")); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// encodeKMLPlacemark writes w as a KML placemark with a Point to e.
+func (w *WptType) encodeKMLPlacemark(e *xml.Encoder) error {
+	start := xml.StartElement{Name: xml.Name{Local: "Placemark"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := encodeKMLPlacemarkHeader(e, w.Name, w.Desc, ""); err != nil {
+		return err
+	}
+	if !w.Time.IsZero() {
+		timeStampStart := xml.StartElement{Name: xml.Name{Local: "TimeStamp"}}
+		if err := e.EncodeToken(timeStampStart); err != nil {
+			return err
+		}
+		if err := emitStringElement(e, "when", w.Time.Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+		if err := e.EncodeToken(timeStampStart.End()); err != nil {
+			return err
+		}
+	}
+	pointStart := xml.StartElement{Name: xml.Name{Local: "Point"}}
+	if err := e.EncodeToken(pointStart); err != nil {
+		return err
+	}
+	if err := encodeKMLCoordinates(e, []*WptType{w}); err != nil {
+		return err
+	}
+	if err := e.EncodeToken(pointStart.End()); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+// kmlCoord returns w's longitude, latitude, and, if layout is XYZ, elevation
+// formatted for KML.
+func (w *WptType) kmlCoord(layout geom.Layout) []string {
+	fields := []string{
+		strconv.FormatFloat(w.Lon, 'f', -1, 64),
+		strconv.FormatFloat(w.Lat, 'f', -1, 64),
+	}
+	if layout == geom.XYZ {
+		fields = append(fields, strconv.FormatFloat(w.Ele, 'f', -1, 64))
+	}
+	return fields
+}
+
+// encodeKMLCoordinates writes the altitude mode, if wpts all have elevations,
+// and the coordinates of wpts to e.
+func encodeKMLCoordinates(e *xml.Encoder, wpts []*WptType) error {
+	layout := eleLayout(wpts)
+	if layout == geom.XYZ {
+		if err := emitStringElement(e, "altitudeMode", "absolute"); err != nil {
+			return err
+		}
+	}
+	coordinates := make([]string, 0, len(wpts))
+	for _, wpt := range wpts {
+		coordinates = append(coordinates, strings.Join(wpt.kmlCoord(layout), ","))
+	}
+	return emitStringElement(e, "coordinates", strings.Join(coordinates, " "))
+}
+
+// encodeKMLPlacemarkHeader writes a placemark's name, description, and style
+// to e.
+func encodeKMLPlacemarkHeader(e *xml.Encoder, name, description string, displayColor DisplayColor) error {
+	if err := maybeEmitStringElement(e, "name", name); err != nil {
+		return err
+	}
+	if err := maybeEmitStringElement(e, "description", description); err != nil {
+		return err
+	}
+	if !displayColor.Valid() {
+		return nil
+	}
+	return emitStringElement(e, "styleUrl", "#"+kmlStyleID(displayColor))
+}
+
+// encodeKMLStyle writes a KML style for displayColor to e.
+func encodeKMLStyle(e *xml.Encoder, displayColor DisplayColor) error {
+	start := xml.StartElement{
+		Name: xml.Name{Local: "Style"},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "id"}, Value: kmlStyleID(displayColor)},
+		},
+	}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	lineStyleStart := xml.StartElement{Name: xml.Name{Local: "LineStyle"}}
+	if err := e.EncodeToken(lineStyleStart); err != nil {
+		return err
+	}
+	if err := emitStringElement(e, "color", kmlColor(displayColor)); err != nil {
+		return err
+	}
+	if err := e.EncodeToken(lineStyleStart.End()); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+// kmlColor returns displayColor as a KML aabbggrr color.
+func kmlColor(displayColor DisplayColor) string {
+	rgb := displayColorRGBs[displayColor]
+	alpha := uint32(0xff)
+	if displayColor == DisplayColorTransparent {
+		alpha = 0
+	}
+	return fmt.Sprintf("%02x%02x%02x%02x", alpha, rgb&0xff, rgb>>8&0xff, rgb>>16)
+}
+
+// kmlStyleID returns the ID of the KML style for displayColor.
+func kmlStyleID(displayColor DisplayColor) string {
+	return "DisplayColor" + string(displayColor)
+}
+
+// parseKMLColor returns the display color with the KML aabbggrr color s.
+func parseKMLColor(s string) (DisplayColor, error) {
+	abgr, err := strconv.ParseUint(strings.TrimSpace(s), 16, 32)
+	if err != nil {
+		return "", err
+	}
+	if abgr>>24 == 0 {
+		return DisplayColorTransparent, nil
+	}
+	rgb := uint32(abgr&0xff)<<16 | uint32(abgr&0xff00) | uint32(abgr>>16&0xff)
+	for displayColor, displayColorRGB := range displayColorRGBs {
+		if displayColorRGB == rgb && displayColor != DisplayColorTransparent {
+			return displayColor, nil
+		}
+	}
+	return "", fmt.Errorf("%s: %w", s, errInvalidKMLColor)
+}
+
+// parseKMLCoord returns a new WptType from the longitude, latitude, and
+// optional elevation in fields.
+func parseKMLCoord(fields []string) (*WptType, error) {
+	if len(fields) < 2 || len(fields) > 3 {
+		return nil, errInvalidCoordinates
+	}
+	values := make([]float64, 0, len(fields))
+	for _, field := range fields {
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", field, errInvalidCoordinates)
+		}
+		values = append(values, value)
+	}
+	w := &WptType{
+		Lat: values[1],
+		Lon: values[0],
+	}
+	if len(values) == 3 {
+		w.Ele = values[2]
+		w.Present |= WptEle
+	}
+	return w, nil
+}
+
+// parseKMLCoordinates returns the points in the KML coordinates s.
+func parseKMLCoordinates(s string) ([]*WptType, error) {
+	tuples := strings.Fields(s)
+	wpts := make([]*WptType, 0, len(tuples))
+	for _, tuple := range tuples {
+		wpt, err := parseKMLCoord(strings.Split(tuple, ","))
+		if err != nil {
+			return nil, err
+		}
+		wpts = append(wpts, wpt)
+	}
+	return wpts, nil
+}
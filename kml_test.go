@@ -0,0 +1,315 @@
+package gpx_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	gpx "github.com/twpayne/go-gpx"
+)
+
+func TestKML(t *testing.T) {
+	t0 := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	rte := &gpx.RteType{
+		Name: "planned",
+		RtePt: []*gpx.WptType{
+			{Lat: 46.5, Lon: 6.5},
+			{Lat: 46.6, Lon: 6.6},
+		},
+	}
+	assert.NoError(t, rte.SetRouteExtension(&gpx.RouteExtension{DisplayColor: gpx.DisplayColorDarkRed}))
+	trk := &gpx.TrkType{
+		Name: "ride",
+		Desc: "morning ride",
+		TrkSeg: []*gpx.TrkSegType{
+			{
+				TrkPt: []*gpx.WptType{
+					{Lat: 46.5, Lon: 6.5, Ele: 372, Time: t0, Present: gpx.WptEle},
+					{Lat: 46.51, Lon: 6.51, Ele: 380, Time: t0.Add(time.Minute), Present: gpx.WptEle},
+				},
+			},
+			{
+				TrkPt: []*gpx.WptType{
+					{Lat: 46.52, Lon: 6.52, Time: t0.Add(time.Hour)},
+				},
+			},
+		},
+	}
+	assert.NoError(t, trk.SetTrackExtension(&gpx.TrackExtension{DisplayColor: gpx.DisplayColorBlue}))
+	g := &gpx.GPX{
+		Version: "1.1",
+		Metadata: &gpx.MetadataType{
+			Name: "weekend",
+			Desc: "rides & walks",
+		},
+		Wpt: []*gpx.WptType{
+			{Lat: 46.5, Lon: 6.5, Ele: 0, Time: t0, Name: "start", Desc: "car park", Present: gpx.WptEle},
+		},
+		Rte: []*gpx.RteType{rte},
+		Trk: []*gpx.TrkType{trk},
+	}
+
+	var kml strings.Builder
+	assert.NoError(t, g.WriteKML(&kml))
+	for _, s := range []string{
+		`<kml xmlns="http://www.opengis.net/kml/2.2" xmlns:gx="http://www.google.com/kml/ext/2.2"><Document><name>weekend</name><description>rides &amp; walks</description>`,
+		`<Style id="DisplayColorDarkRed"><LineStyle><color>ff00008b</color></LineStyle></Style>`,
+		`<Style id="DisplayColorBlue"><LineStyle><color>ffff0000</color></LineStyle></Style>`,
+		`<Placemark><name>start</name><description>car park</description><TimeStamp><when>2024-06-01T10:00:00Z</when></TimeStamp><Point><altitudeMode>absolute</altitudeMode><coordinates>6.5,46.5,0</coordinates></Point></Placemark>`,
+		`<Placemark><name>planned</name><styleUrl>#DisplayColorDarkRed</styleUrl><LineString><coordinates>6.5,46.5 6.6,46.6</coordinates></LineString></Placemark>`,
+		`<gx:MultiTrack><gx:Track><altitudeMode>absolute</altitudeMode><when>2024-06-01T10:00:00Z</when><when>2024-06-01T10:01:00Z</when><gx:coord>6.5 46.5 372</gx:coord><gx:coord>6.51 46.51 380</gx:coord></gx:Track>`,
+	} {
+		assert.Contains(t, kml.String(), s)
+	}
+
+	actual, err := gpx.ReadKML(strings.NewReader(kml.String()))
+	assert.NoError(t, err)
+	assert.Equal(t, g, actual)
+
+	var kmz bytes.Buffer
+	assert.NoError(t, g.WriteKMZ(&kmz))
+	actual, err = gpx.ReadKMZ(bytes.NewReader(kmz.Bytes()), int64(kmz.Len()))
+	assert.NoError(t, err)
+	assert.Equal(t, g, actual)
+}
+
+func TestReadKML(t *testing.T) {
+	kml := `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2" xmlns:gx="http://www.google.com/kml/ext/2.2">
+  <Document>
+    <Style id="yellow">
+      <LineStyle>
+        <color>7f00ffff</color>
+      </LineStyle>
+    </Style>
+    <Style id="purple">
+      <LineStyle>
+        <color>ff800080</color>
+      </LineStyle>
+    </Style>
+    <Folder>
+      <name>Tracks</name>
+      <Placemark>
+        <name>walk</name>
+        <styleUrl>#yellow</styleUrl>
+        <gx:Track>
+          <when>2024-06-01T10:00:00Z</when>
+          <when>2024-06-01T10:00:05Z</when>
+          <gx:coord>-122.2 37.4 150</gx:coord>
+          <gx:coord>-122.3 37.5 151</gx:coord>
+        </gx:Track>
+      </Placemark>
+      <Placemark>
+        <name>path</name>
+        <styleUrl>#purple</styleUrl>
+        <LineString>
+          <coordinates>
+            -122.2,37.4
+            -122.3,37.5
+          </coordinates>
+        </LineString>
+      </Placemark>
+    </Folder>
+  </Document>
+</kml>`
+	g, err := gpx.ReadKML(strings.NewReader(kml))
+	assert.NoError(t, err)
+	assert.Zero(t, g.Metadata)
+	assert.Equal(t, 1, len(g.Trk))
+	assert.Equal(t, "walk", g.Trk[0].Name)
+	assert.Equal(t, []*gpx.WptType{
+		{Lat: 37.4, Lon: -122.2, Ele: 150, Time: time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC), Present: gpx.WptEle},
+		{Lat: 37.5, Lon: -122.3, Ele: 151, Time: time.Date(2024, 6, 1, 10, 0, 5, 0, time.UTC), Present: gpx.WptEle},
+	}, g.Trk[0].TrkSeg[0].TrkPt)
+	te, err := g.Trk[0].TrackExtension()
+	assert.NoError(t, err)
+	assert.Equal(t, &gpx.TrackExtension{DisplayColor: gpx.DisplayColorYellow}, te)
+	assert.Equal(t, 1, len(g.Rte))
+	assert.Equal(t, "path", g.Rte[0].Name)
+	assert.Equal(t, 2, len(g.Rte[0].RtePt))
+	assert.Zero(t, g.Rte[0].Extensions)
+
+	_, err = gpx.ReadKML(strings.NewReader(`<kml><Placemark><Point><coordinates>1</coordinates></Point></Placemark></kml>`))
+	assert.EqualError(t, err, "placemark 0: invalid coordinates")
+}
+
+func TestReadKMLGeometries(t *testing.T) {
+	kml := `<kml xmlns="http://www.opengis.net/kml/2.2" xmlns:gx="http://www.google.com/kml/ext/2.2">
+  <Document>
+    <Style id="red">
+      <LineStyle>
+        <color>ff0000ff</color>
+      </LineStyle>
+    </Style>
+    <Style id="green">
+      <LineStyle>
+        <color>ff00ff00</color>
+      </LineStyle>
+    </Style>
+    <StyleMap id="redMap">
+      <Pair>
+        <key>highlight</key>
+        <styleUrl>#green</styleUrl>
+      </Pair>
+      <Pair>
+        <key>normal</key>
+        <styleUrl>#red</styleUrl>
+      </Pair>
+    </StyleMap>
+    <StyleMap id="greenMap">
+      <Pair>
+        <key>normal</key>
+        <Style>
+          <LineStyle>
+            <color>ff00ff00</color>
+          </LineStyle>
+        </Style>
+      </Pair>
+    </StyleMap>
+    <Placemark>
+      <name>lines</name>
+      <styleUrl>#redMap</styleUrl>
+      <MultiGeometry>
+        <Point>
+          <coordinates>6.5,46.5</coordinates>
+        </Point>
+        <LineString>
+          <coordinates>6.5,46.5 6.6,46.6</coordinates>
+        </LineString>
+        <MultiGeometry>
+          <LinearRing>
+            <coordinates>6.5,46.5 6.6,46.6 6.5,46.6 6.5,46.5</coordinates>
+          </LinearRing>
+        </MultiGeometry>
+      </MultiGeometry>
+    </Placemark>
+    <Placemark>
+      <name>walk</name>
+      <styleUrl>#greenMap</styleUrl>
+      <MultiGeometry>
+        <gx:Track>
+          <when>2024-06-01T10:00:00Z</when>
+          <gx:coord>6.5 46.5</gx:coord>
+        </gx:Track>
+      </MultiGeometry>
+    </Placemark>
+  </Document>
+</kml>`
+	g, err := gpx.ReadKML(strings.NewReader(kml))
+	assert.NoError(t, err)
+	assert.Equal(t, []*gpx.WptType{
+		{Lat: 46.5, Lon: 6.5, Name: "lines"},
+	}, g.Wpt)
+	assert.Equal(t, 2, len(g.Rte))
+	for _, rte := range g.Rte {
+		assert.Equal(t, "lines", rte.Name)
+		re, err := rte.RouteExtension()
+		assert.NoError(t, err)
+		assert.Equal(t, &gpx.RouteExtension{DisplayColor: gpx.DisplayColorRed}, re)
+	}
+	assert.Equal(t, 2, len(g.Rte[0].RtePt))
+	assert.Equal(t, 4, len(g.Rte[1].RtePt))
+	assert.Equal(t, 1, len(g.Trk))
+	assert.Equal(t, "walk", g.Trk[0].Name)
+	assert.Equal(t, 1, len(g.Trk[0].TrkSeg))
+	te, err := g.Trk[0].TrackExtension()
+	assert.NoError(t, err)
+	assert.Equal(t, &gpx.TrackExtension{DisplayColor: gpx.DisplayColorGreen}, te)
+}
+
+func TestReadKMLErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		kml           string
+		expectedError string
+	}{
+		{
+			name:          "no_geometry",
+			kml:           `<kml><Placemark><name>empty</name></Placemark></kml>`,
+			expectedError: "placemark 0: no KML geometry",
+		},
+		{
+			name:          "polygon",
+			kml:           `<kml><Placemark><Polygon><outerBoundaryIs><LinearRing><coordinates>0,0 1,0 1,1 0,0</coordinates></LinearRing></outerBoundaryIs></Polygon></Placemark></kml>`,
+			expectedError: "placemark 0: Polygon: unsupported geometry",
+		},
+		{
+			name:          "multi_geometry_polygon",
+			kml:           `<kml><Placemark><MultiGeometry><Point><coordinates>0,0</coordinates></Point><Polygon/></MultiGeometry></Placemark></kml>`,
+			expectedError: "placemark 0: Polygon: unsupported geometry",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := gpx.ReadKML(strings.NewReader(tc.kml))
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestWriteKMLTrkWithoutTimes(t *testing.T) {
+	t0 := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name     string
+		trkSegs  []*gpx.TrkSegType
+		expected string
+	}{
+		{
+			name: "one_segment",
+			trkSegs: []*gpx.TrkSegType{
+				{
+					TrkPt: []*gpx.WptType{
+						{Lat: 46.5, Lon: 6.5, Time: t0},
+						{Lat: 46.6, Lon: 6.6},
+					},
+				},
+			},
+			expected: `<Placemark><name>ride</name><LineString><coordinates>6.5,46.5 6.6,46.6</coordinates></LineString></Placemark>`,
+		},
+		{
+			name: "two_segments",
+			trkSegs: []*gpx.TrkSegType{
+				{
+					TrkPt: []*gpx.WptType{
+						{Lat: 46.5, Lon: 6.5, Time: t0},
+					},
+				},
+				{
+					TrkPt: []*gpx.WptType{
+						{Lat: 46.6, Lon: 6.6},
+					},
+				},
+			},
+			expected: `<Placemark><name>ride</name><MultiGeometry><LineString><coordinates>6.5,46.5</coordinates></LineString><LineString><coordinates>6.6,46.6</coordinates></LineString></MultiGeometry></Placemark>`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g := &gpx.GPX{
+				Version: "1.1",
+				Trk: []*gpx.TrkType{
+					{
+						Name:   "ride",
+						TrkSeg: tc.trkSegs,
+					},
+				},
+			}
+			var kml strings.Builder
+			assert.NoError(t, g.WriteKML(&kml))
+			assert.Contains(t, kml.String(), tc.expected)
+			assert.NotContains(t, kml.String(), "<when>")
+		})
+	}
+}
+
+func TestReadKMZNoKML(t *testing.T) {
+	var kmz bytes.Buffer
+	zipWriter := zip.NewWriter(&kmz)
+	_, err := zipWriter.Create("images/icon.png")
+	assert.NoError(t, err)
+	assert.NoError(t, zipWriter.Close())
+	_, err = gpx.ReadKMZ(bytes.NewReader(kmz.Bytes()), int64(kmz.Len()))
+	assert.EqualError(t, err, "no KML document")
+}
@@ -0,0 +1,327 @@
+package gpx
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// A TrkMergeMode determines how tracks are merged.
+type TrkMergeMode int
+
+// Track merge modes.
+const (
+	// TrkMergeConcatenate keeps all tracks as separate tracks.
+	TrkMergeConcatenate TrkMergeMode = iota
+	// TrkMergeJoin joins all tracks into a single track, keeping each
+	// segment. Segments are ordered by the time of their first point if all
+	// segments have one. The extensions of all tracks are combined.
+	TrkMergeJoin
+	// TrkMergeInterleave joins all tracks into a single track with a single
+	// segment containing all track points ordered by time. Points without a
+	// time are placed first. The extensions of all tracks and of all segments
+	// are combined.
+	TrkMergeInterleave
+)
+
+// A MergeOption sets an option for Merge.
+type MergeOption func(*mergeOptions)
+
+type mergeOptions struct {
+	dedupeWpts     bool
+	maxWptDistance float64
+	trkMergeMode   TrkMergeMode
+}
+
+// WithDedupeWpts drops waypoints that have the same name as an earlier
+// waypoint and are within maxDistance meters of it.
+func WithDedupeWpts(maxDistance float64) MergeOption {
+	return func(o *mergeOptions) {
+		o.dedupeWpts = true
+		o.maxWptDistance = maxDistance
+	}
+}
+
+// WithTrkMergeMode sets how tracks are merged. The default is
+// TrkMergeConcatenate.
+func WithTrkMergeMode(trkMergeMode TrkMergeMode) MergeOption {
+	return func(o *mergeOptions) {
+		o.trkMergeMode = trkMergeMode
+	}
+}
+
+// Merge returns a new GPX containing the waypoints, routes, and tracks of all
+// of gs, skipping nil entries. The version and creator are those of the first
+// GPX. Metadata is
+// merged: the first non-empty name, description, author, and copyright are
+// used, the time is the earliest time, the bounds are the union of all bounds,
+// and keywords and links are combined. Root namespace declarations and schema
+// locations from all of gs are kept. If a GPX binds a prefix that an earlier
+// GPX binds to a different namespace then the prefix is renamed in its root
+// namespace declarations and extensions.
+func Merge(gs []*GPX, options ...MergeOption) (*GPX, error) {
+	var o mergeOptions
+	for _, option := range options {
+		option(&o)
+	}
+	gs = slices.DeleteFunc(slices.Clone(gs), func(g *GPX) bool {
+		return g == nil
+	})
+	result := &GPX{}
+	var metadatas []*MetadataType
+	var trks []*TrkType
+	namespaces := make(map[string]string)
+	for i, g := range gs {
+		g, err := g.withUniquePrefixes(namespaces)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			result.Version = g.Version
+			result.Creator = g.Creator
+		}
+		for key, value := range g.XMLAttrs {
+			if _, ok := result.XMLAttrs[key]; ok {
+				continue
+			}
+			if result.XMLAttrs == nil {
+				result.XMLAttrs = make(map[string]string)
+			}
+			result.XMLAttrs[key] = value
+		}
+		for j := 0; j+1 < len(g.XMLSchemaLocations); j += 2 {
+			if !slices.Contains(schemaLocationNamespaces(result.XMLSchemaLocations), g.XMLSchemaLocations[j]) {
+				result.XMLSchemaLocations = append(result.XMLSchemaLocations, g.XMLSchemaLocations[j], g.XMLSchemaLocations[j+1])
+			}
+		}
+		if g.Metadata != nil {
+			metadatas = append(metadatas, g.Metadata)
+		}
+		for _, wpt := range g.Wpt {
+			if o.dedupeWpts && slices.ContainsFunc(result.Wpt, func(w *WptType) bool {
+				return w.Name == wpt.Name && w.Distance(wpt) <= o.maxWptDistance
+			}) {
+				continue
+			}
+			result.Wpt = append(result.Wpt, wpt)
+		}
+		result.Rte = append(result.Rte, g.Rte...)
+		trks = append(trks, g.Trk...)
+		result.Extensions = mergeExtensions(result.Extensions, g.Extensions)
+	}
+	result.Metadata = mergeMetadata(metadatas)
+	result.Trk = mergeTrks(trks, o.trkMergeMode)
+	return result, nil
+}
+
+// withUniquePrefixes returns g with the namespace prefixes that are bound to a
+// different namespace in namespaces renamed, and adds the prefixes bound by g
+// to namespaces. If no prefixes are renamed then g itself is returned.
+func (g *GPX) withUniquePrefixes(namespaces map[string]string) (*GPX, error) {
+	bindings := make(map[string]string)
+	for key, value := range g.XMLAttrs {
+		if prefix, ok := strings.CutPrefix(key, "xmlns:"); ok {
+			bindings[prefix] = value
+		}
+	}
+	for prefix, namespace := range g.extensionNamespaces() {
+		if _, ok := bindings[prefix]; !ok {
+			bindings[prefix] = namespace
+		}
+	}
+	renames := make(map[string]string)
+	for _, prefix := range slices.Sorted(maps.Keys(bindings)) {
+		namespace := bindings[prefix]
+		if boundNamespace, ok := namespaces[prefix]; ok && boundNamespace != namespace {
+			renames[prefix] = uniquePrefix(prefix, namespace, namespaces, bindings)
+		}
+	}
+	for prefix, namespace := range bindings {
+		if newPrefix, ok := renames[prefix]; ok {
+			prefix = newPrefix
+		}
+		namespaces[prefix] = namespace
+	}
+	if len(renames) == 0 {
+		return g, nil
+	}
+	gCopy, err := g.mapExtensions(func(x *ExtensionsType) (*ExtensionsType, error) {
+		xRenames := make(map[string]string)
+		for prefix, newPrefix := range renames {
+			if x != nil && x.Namespaces[prefix] == bindings[prefix] {
+				xRenames[prefix] = newPrefix
+			}
+		}
+		return x.renamePrefixes(xRenames)
+	})
+	if err != nil {
+		return nil, err
+	}
+	gCopy.XMLAttrs = make(map[string]string, len(g.XMLAttrs))
+	for key, value := range g.XMLAttrs {
+		if prefix, ok := strings.CutPrefix(key, "xmlns:"); ok {
+			if newPrefix, ok := renames[prefix]; ok {
+				key = "xmlns:" + newPrefix
+			}
+		}
+		gCopy.XMLAttrs[key] = value
+	}
+	return gCopy, nil
+}
+
+// uniquePrefix returns a replacement for prefix, which is bound to namespace
+// in bindings but to a different namespace in namespaces. It is the prefix
+// already bound to namespace in namespaces, if any, or prefix with a numeric
+// suffix that is bound in neither namespaces nor bindings.
+func uniquePrefix(prefix, namespace string, namespaces, bindings map[string]string) string {
+	for _, p := range slices.Sorted(maps.Keys(namespaces)) {
+		if namespaces[p] == namespace {
+			return p
+		}
+	}
+	for i := 2; ; i++ {
+		newPrefix := prefix + strconv.Itoa(i)
+		_, inNamespaces := namespaces[newPrefix]
+		_, inBindings := bindings[newPrefix]
+		if !inNamespaces && !inBindings {
+			return newPrefix
+		}
+	}
+}
+
+// mergeExtensions returns the combination of x1 and x2.
+func mergeExtensions(x1, x2 *ExtensionsType) *ExtensionsType {
+	switch {
+	case x2 == nil:
+		return x1
+	case x1 == nil:
+		return x2
+	}
+	namespaces := maps.Clone(x1.Namespaces)
+	for prefix, namespace := range x2.Namespaces {
+		if _, ok := namespaces[prefix]; ok {
+			continue
+		}
+		if namespaces == nil {
+			namespaces = make(map[string]string)
+		}
+		namespaces[prefix] = namespace
+	}
+	return &ExtensionsType{
+		XML:        slices.Concat(x1.XML, x2.XML),
+		Namespaces: namespaces,
+		Typed:      slices.Concat(x1.Typed, x2.Typed),
+	}
+}
+
+// mergeMetadata returns the combination of metadatas, or nil if metadatas is
+// empty.
+func mergeMetadata(metadatas []*MetadataType) *MetadataType {
+	if len(metadatas) == 0 {
+		return nil
+	}
+	result := &MetadataType{}
+	var keywords []string
+	var bounds []*BoundsType
+	for _, m := range metadatas {
+		if result.Name == "" {
+			result.Name = m.Name
+		}
+		if result.Desc == "" {
+			result.Desc = m.Desc
+		}
+		if result.Author == nil {
+			result.Author = m.Author
+		}
+		if result.Copyright == nil {
+			result.Copyright = m.Copyright
+		}
+		for _, link := range m.Link {
+			if !slices.ContainsFunc(result.Link, func(l *LinkType) bool {
+				return l.HREF == link.HREF
+			}) {
+				result.Link = append(result.Link, link)
+			}
+		}
+		if !m.Time.IsZero() && (result.Time.IsZero() || m.Time.Before(result.Time)) {
+			result.Time = m.Time
+		}
+		for _, keyword := range strings.Split(m.Keywords, ",") {
+			if keyword = strings.TrimSpace(keyword); keyword != "" && !slices.Contains(keywords, keyword) {
+				keywords = append(keywords, keyword)
+			}
+		}
+		if m.Bounds != nil {
+			bounds = append(bounds, m.Bounds)
+		}
+		result.Extensions = mergeExtensions(result.Extensions, m.Extensions)
+	}
+	result.Keywords = strings.Join(keywords, ", ")
+	result.Bounds = unionBounds(bounds)
+	return result
+}
+
+// mergeTrks returns trks merged according to trkMergeMode.
+func mergeTrks(trks []*TrkType, trkMergeMode TrkMergeMode) []*TrkType {
+	if len(trks) == 0 {
+		return nil
+	}
+	switch trkMergeMode {
+	case TrkMergeConcatenate:
+		return trks
+	case TrkMergeJoin:
+		trk := joinTrks(trks)
+		if !slices.ContainsFunc(trk.TrkSeg, func(ts *TrkSegType) bool {
+			return len(ts.TrkPt) == 0 || ts.TrkPt[0].Time.IsZero()
+		}) {
+			slices.SortStableFunc(trk.TrkSeg, func(a, b *TrkSegType) int {
+				return a.TrkPt[0].Time.Compare(b.TrkPt[0].Time)
+			})
+		}
+		return []*TrkType{trk}
+	case TrkMergeInterleave:
+		trk := joinTrks(trks)
+		var trkPts []*WptType
+		var extensions *ExtensionsType
+		for _, trkSeg := range trk.TrkSeg {
+			trkPts = append(trkPts, trkSeg.TrkPt...)
+			extensions = mergeExtensions(extensions, trkSeg.Extensions)
+		}
+		slices.SortStableFunc(trkPts, func(a, b *WptType) int {
+			return a.Time.Compare(b.Time)
+		})
+		trk.TrkSeg = []*TrkSegType{
+			{
+				TrkPt:      trkPts,
+				Extensions: extensions,
+			},
+		}
+		return []*TrkType{trk}
+	}
+	return trks
+}
+
+// joinTrks returns a copy of the first of trks with the segments of all of
+// trks and the extensions of all of trks combined.
+func joinTrks(trks []*TrkType) *TrkType {
+	trk := *trks[0]
+	trk.TrkSeg = nil
+	for i, t := range trks {
+		trk.TrkSeg = append(trk.TrkSeg, t.TrkSeg...)
+		if i > 0 {
+			trk.Extensions = mergeExtensions(trk.Extensions, t.Extensions)
+		}
+	}
+	return &trk
+}
+
+// schemaLocationNamespaces returns the namespaces in schemaLocations, a list
+// of namespace and location pairs.
+func schemaLocationNamespaces(schemaLocations []string) []string {
+	namespaces := make([]string, 0, len(schemaLocations)/2)
+	for i := 0; i+1 < len(schemaLocations); i += 2 {
+		namespaces = append(namespaces, schemaLocations[i])
+	}
+	return namespaces
+}
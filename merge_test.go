@@ -0,0 +1,198 @@
+package gpx_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	gpx "github.com/twpayne/go-gpx"
+)
+
+func TestMerge(t *testing.T) {
+	t0 := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	g1 := &gpx.GPX{
+		Version: "1.1",
+		Creator: "device 1",
+		XMLAttrs: map[string]string{
+			"xmlns:gpxtpx": gpx.TrackPointExtensionV1Namespace,
+		},
+		XMLSchemaLocations: []string{
+			gpx.TrackPointExtensionV1Namespace, "http://www.garmin.com/xmlschemas/TrackPointExtensionv1.xsd",
+		},
+		Metadata: &gpx.MetadataType{
+			Name:     "ride",
+			Time:     t0.Add(time.Hour),
+			Keywords: "bike, morning",
+			Link: []*gpx.LinkType{
+				{HREF: "https://example.com/1"},
+			},
+			Bounds: &gpx.BoundsType{MinLat: 0, MinLon: 170, MaxLat: 1, MaxLon: 175},
+		},
+		Wpt: []*gpx.WptType{
+			{Lat: 1, Lon: 2, Name: "cafe"},
+		},
+		Trk: []*gpx.TrkType{
+			{
+				Name: "trk1",
+				TrkSeg: []*gpx.TrkSegType{
+					{
+						TrkPt: []*gpx.WptType{
+							{Time: t0.Add(2 * time.Minute)},
+							{Time: t0.Add(3 * time.Minute)},
+						},
+					},
+				},
+			},
+		},
+	}
+	g2 := &gpx.GPX{
+		Version: "1.1",
+		Creator: "device 2",
+		XMLAttrs: map[string]string{
+			"xmlns:gpxx": gpx.GpxExtensionsV3Namespace,
+		},
+		XMLSchemaLocations: []string{
+			gpx.TrackPointExtensionV1Namespace, "http://www.garmin.com/xmlschemas/TrackPointExtensionv1.xsd",
+			gpx.GpxExtensionsV3Namespace, "http://www8.garmin.com/xmlschemas/GpxExtensionsv3.xsd",
+		},
+		Metadata: &gpx.MetadataType{
+			Desc:     "desc",
+			Time:     t0,
+			Keywords: "morning,commute",
+			Link: []*gpx.LinkType{
+				{HREF: "https://example.com/1"},
+				{HREF: "https://example.com/2"},
+			},
+			Bounds: &gpx.BoundsType{MinLat: -1, MinLon: -175, MaxLat: 0.5, MaxLon: -170},
+		},
+		Wpt: []*gpx.WptType{
+			{Lat: 1, Lon: 2.00001, Name: "cafe"},
+			{Lat: 1, Lon: 2, Name: "shop"},
+		},
+		Rte: []*gpx.RteType{
+			{Name: "rte"},
+		},
+		Trk: []*gpx.TrkType{
+			{
+				Name: "trk2",
+				TrkSeg: []*gpx.TrkSegType{
+					{
+						TrkPt: []*gpx.WptType{
+							{Time: t0},
+							{Time: t0.Add(4 * time.Minute)},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	merged, err := gpx.Merge([]*gpx.GPX{g1, g2}, gpx.WithDedupeWpts(10))
+	assert.NoError(t, err)
+	assert.Equal(t, "device 1", merged.Creator)
+	assert.Equal(t, map[string]string{
+		"xmlns:gpxtpx": gpx.TrackPointExtensionV1Namespace,
+		"xmlns:gpxx":   gpx.GpxExtensionsV3Namespace,
+	}, merged.XMLAttrs)
+	assert.Equal(t, []string{
+		gpx.TrackPointExtensionV1Namespace, "http://www.garmin.com/xmlschemas/TrackPointExtensionv1.xsd",
+		gpx.GpxExtensionsV3Namespace, "http://www8.garmin.com/xmlschemas/GpxExtensionsv3.xsd",
+	}, merged.XMLSchemaLocations)
+	assert.Equal(t, &gpx.MetadataType{
+		Name:     "ride",
+		Desc:     "desc",
+		Time:     t0,
+		Keywords: "bike, morning, commute",
+		Link: []*gpx.LinkType{
+			{HREF: "https://example.com/1"},
+			{HREF: "https://example.com/2"},
+		},
+		Bounds: &gpx.BoundsType{MinLat: -1, MinLon: 170, MaxLat: 1, MaxLon: -170},
+	}, merged.Metadata)
+	assert.Equal(t, []*gpx.WptType{g1.Wpt[0], g2.Wpt[1]}, merged.Wpt)
+	assert.Equal(t, g2.Rte, merged.Rte)
+	assert.Equal(t, []*gpx.TrkType{g1.Trk[0], g2.Trk[0]}, merged.Trk)
+
+	merged, err = gpx.Merge([]*gpx.GPX{g1, g2})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(merged.Wpt))
+
+	merged, err = gpx.Merge([]*gpx.GPX{g1, g2}, gpx.WithTrkMergeMode(gpx.TrkMergeJoin))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(merged.Trk))
+	assert.Equal(t, "trk1", merged.Trk[0].Name)
+	assert.Equal(t, []*gpx.TrkSegType{g2.Trk[0].TrkSeg[0], g1.Trk[0].TrkSeg[0]}, merged.Trk[0].TrkSeg)
+	assert.Equal(t, 1, len(g1.Trk[0].TrkSeg))
+
+	merged, err = gpx.Merge([]*gpx.GPX{g1, g2}, gpx.WithTrkMergeMode(gpx.TrkMergeInterleave))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(merged.Trk))
+	assert.Equal(t, 1, len(merged.Trk[0].TrkSeg))
+	assert.Equal(t, []*gpx.WptType{
+		g2.Trk[0].TrkSeg[0].TrkPt[0],
+		g1.Trk[0].TrkSeg[0].TrkPt[0],
+		g1.Trk[0].TrkSeg[0].TrkPt[1],
+		g2.Trk[0].TrkSeg[0].TrkPt[1],
+	}, merged.Trk[0].TrkSeg[0].TrkPt)
+}
+
+func TestMergeConflictingPrefixes(t *testing.T) {
+	g1, err := gpx.Read(strings.NewReader(`<gpx version="1.1" xmlns:ns3="` + gpx.TrackPointExtensionV1Namespace + `">` +
+		`<trk><trkseg><trkpt lat="1" lon="2"><extensions>` +
+		`<ns3:TrackPointExtension><ns3:hr>100</ns3:hr></ns3:TrackPointExtension>` +
+		`</extensions></trkpt></trkseg></trk>` +
+		`<extensions><ns3:TrackPointExtension/></extensions>` +
+		`</gpx>`))
+	assert.NoError(t, err)
+	g2, err := gpx.Read(strings.NewReader(`<gpx version="1.1" xmlns:ns3="` + gpx.TrackPointExtensionV2Namespace + `">` +
+		`<trk><trkseg><trkpt lat="3" lon="4"><extensions>` +
+		`<ns3:TrackPointExtension><ns3:hr>120</ns3:hr><ns3:speed>5</ns3:speed></ns3:TrackPointExtension>` +
+		`</extensions></trkpt></trkseg><extensions><ns3:TrackPointExtension/></extensions></trk>` +
+		`<extensions><ns3:TrackPointExtension/></extensions>` +
+		`</gpx>`))
+	assert.NoError(t, err)
+
+	merged, err := gpx.Merge([]*gpx.GPX{g1, g2}, gpx.WithTrkMergeMode(gpx.TrkMergeJoin))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"xmlns:ns3":  gpx.TrackPointExtensionV1Namespace,
+		"xmlns:ns32": gpx.TrackPointExtensionV2Namespace,
+	}, merged.XMLAttrs)
+	assert.Equal(t, `<ns3:TrackPointExtension/><ns32:TrackPointExtension></ns32:TrackPointExtension>`, string(merged.Extensions.XML))
+	assert.Equal(t, `<ns32:TrackPointExtension></ns32:TrackPointExtension>`, string(merged.Trk[0].Extensions.XML))
+	assert.Equal(t, `<ns3:TrackPointExtension><ns3:hr>120</ns3:hr><ns3:speed>5</ns3:speed></ns3:TrackPointExtension>`, string(g2.Trk[0].TrkSeg[0].TrkPt[0].Extensions.XML))
+
+	sb := &strings.Builder{}
+	assert.NoError(t, merged.Write(sb))
+	roundTripped, err := gpx.Read(strings.NewReader(sb.String()))
+	assert.NoError(t, err)
+	for i, expected := range []*gpx.TrackPointExtension{
+		{Version: 1, HR: 100, Present: gpx.TrackPointExtensionHR},
+		{Version: 2, HR: 120, Speed: 5, Present: gpx.TrackPointExtensionHR | gpx.TrackPointExtensionSpeed},
+	} {
+		tpe, err := roundTripped.Trk[0].TrkSeg[i].TrkPt[0].TrackPointExtension()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, tpe)
+	}
+}
+
+func TestMergeEmpty(t *testing.T) {
+	merged, err := gpx.Merge(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, &gpx.GPX{}, merged)
+}
+
+func TestMergeNil(t *testing.T) {
+	g := &gpx.GPX{
+		Version: "1.1",
+		Creator: "device",
+		Wpt: []*gpx.WptType{
+			{Lat: 1, Lon: 2, Name: "cafe"},
+		},
+	}
+	merged, err := gpx.Merge([]*gpx.GPX{nil, g, nil})
+	assert.NoError(t, err)
+	assert.Equal(t, g, merged)
+}
@@ -0,0 +1,494 @@
+// Package nmea reads NMEA 0183 sentences into GPX tracks.
+package nmea
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/twpayne/go-gpx"
+)
+
+// metersPerSecondPerKnot is the number of meters per second in one knot.
+const metersPerSecondPerKnot = 1852.0 / 3600.0
+
+var (
+	errInvalidChecksum = errors.New("invalid checksum")
+	errInvalidField    = errors.New("invalid field")
+	errInvalidSentence = errors.New("invalid sentence")
+	errMissingChecksum = errors.New("missing checksum")
+)
+
+// A Sentence is an NMEA 0183 sentence.
+type Sentence struct {
+	Talker string
+	Type   string
+	Fields []string
+}
+
+// A ReadOption sets an option on Read.
+type ReadOption func(*readOptions)
+
+type readOptions struct {
+	date                 time.Time
+	skipInvalidSentences bool
+}
+
+// A fix accumulates the sentences of a single fix.
+type fix struct {
+	wpt         *gpx.WptType
+	timeOfDay   time.Duration
+	hasTime     bool
+	date        time.Time
+	hasPosition bool
+	lost        bool
+	quality     int
+	fixType     int
+}
+
+// A reader builds a GPX track from sentences.
+type reader struct {
+	trk           *gpx.TrkType
+	trkSeg        *gpx.TrkSegType
+	fix           *fix
+	date          time.Time
+	lastTimeOfDay time.Duration
+}
+
+// WithDate sets the date of fixes before the first RMC sentence, or of all
+// fixes if there are no RMC sentences. Subsequent dates are advanced when the
+// time of day wraps at midnight.
+func WithDate(date time.Time) ReadOption {
+	return func(o *readOptions) {
+		o.date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// WithSkipInvalidSentences skips sentences that cannot be parsed, for example
+// because of a bad checksum, instead of returning an error.
+func WithSkipInvalidSentences() ReadOption {
+	return func(o *readOptions) {
+		o.skipInvalidSentences = true
+	}
+}
+
+// ParseSentence parses s as an NMEA 0183 sentence and validates its checksum.
+func ParseSentence(s string) (*Sentence, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 6 || s[0] != '$' && s[0] != '!' {
+		return nil, errInvalidSentence
+	}
+	data, checksumStr, ok := strings.Cut(s[1:], "*")
+	if !ok {
+		return nil, errMissingChecksum
+	}
+	checksum, err := strconv.ParseUint(checksumStr, 16, 8)
+	if err != nil || len(checksumStr) != 2 {
+		return nil, errInvalidChecksum
+	}
+	var actualChecksum byte
+	for i := range len(data) {
+		actualChecksum ^= data[i]
+	}
+	if byte(checksum) != actualChecksum {
+		return nil, errInvalidChecksum
+	}
+	fields := strings.Split(data, ",")
+	address := fields[0]
+	if len(address) < 3 {
+		return nil, errInvalidSentence
+	}
+	return &Sentence{
+		Talker: address[:len(address)-3],
+		Type:   address[len(address)-3:],
+		Fields: fields[1:],
+	}, nil
+}
+
+// Read reads NMEA 0183 sentences, one per line, from r and returns a GPX
+// containing a single track. GGA, GLL, and RMC sentences with the same time
+// are combined into a single fix, and GSA and VTG sentences apply to the most
+// recent fix. Each fix becomes a track point, with a new track segment
+// started whenever the fix is lost. Dates are taken from RMC sentences.
+// Sentences of other types are ignored.
+func Read(r io.Reader, options ...ReadOption) (*gpx.GPX, error) {
+	var o readOptions
+	for _, option := range options {
+		option(&o)
+	}
+	nr := &reader{
+		trk:  &gpx.TrkType{},
+		date: o.date,
+	}
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		sentence, err := ParseSentence(line)
+		if err == nil {
+			err = nr.addSentence(sentence)
+		}
+		switch {
+		case err == nil:
+		case o.skipInvalidSentences:
+		default:
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	nr.flush()
+	return &gpx.GPX{
+		Version: "1.1",
+		Trk:     []*gpx.TrkType{nr.trk},
+	}, nil
+}
+
+// addSentence adds sentence to r.
+func (r *reader) addSentence(sentence *Sentence) error {
+	var err error
+	switch sentence.Type {
+	case "GGA":
+		err = r.addGGA(sentence.Fields)
+	case "GLL":
+		err = r.addGLL(sentence.Fields)
+	case "GSA":
+		err = r.currentFix().addGSA(sentence.Fields)
+	case "RMC":
+		err = r.addRMC(sentence.Fields)
+	case "VTG":
+		err = r.currentFix().addVTG(sentence.Fields)
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", sentence.Type, err)
+	}
+	return nil
+}
+
+// addGGA adds the fields of a GGA sentence to r.
+func (r *reader) addGGA(fields []string) error {
+	if len(fields) < 14 {
+		return errInvalidSentence
+	}
+	f, err := r.fixAt(fields[0])
+	if err != nil {
+		return err
+	}
+	if err := f.setPosition(fields[1:5]); err != nil {
+		return err
+	}
+	if fields[5] != "" {
+		if f.quality, err = strconv.Atoi(fields[5]); err != nil {
+			return fmt.Errorf("%s: %w", fields[5], errInvalidField)
+		}
+		if f.quality == 0 {
+			f.lost = true
+		}
+	}
+	if fields[6] != "" {
+		if f.wpt.Sat, err = strconv.Atoi(fields[6]); err != nil {
+			return fmt.Errorf("%s: %w", fields[6], errInvalidField)
+		}
+		f.wpt.Present |= gpx.WptSat
+	}
+	if err := parseFloat(fields[7], &f.wpt.HDOP, &f.wpt.Present, gpx.WptHDOP); err != nil {
+		return err
+	}
+	if err := parseFloat(fields[8], &f.wpt.Ele, &f.wpt.Present, gpx.WptEle); err != nil {
+		return err
+	}
+	if err := parseFloat(fields[10], &f.wpt.GeoidHeight, &f.wpt.Present, gpx.WptGeoidHeight); err != nil {
+		return err
+	}
+	if err := parseFloat(fields[12], &f.wpt.AgeOfDGPSData, &f.wpt.Present, gpx.WptAgeOfDGPSData); err != nil {
+		return err
+	}
+	if fields[13] != "" {
+		dgpsid, err := strconv.Atoi(fields[13])
+		if err != nil {
+			return fmt.Errorf("%s: %w", fields[13], errInvalidField)
+		}
+		f.wpt.DGPSID = []int{dgpsid}
+	}
+	return nil
+}
+
+// addGLL adds the fields of a GLL sentence to r.
+func (r *reader) addGLL(fields []string) error {
+	if len(fields) < 6 {
+		return errInvalidSentence
+	}
+	f, err := r.fixAt(fields[4])
+	if err != nil {
+		return err
+	}
+	if err := f.setPosition(fields[0:4]); err != nil {
+		return err
+	}
+	if fields[5] == "V" || len(fields) > 6 && fields[6] == "N" {
+		f.lost = true
+	}
+	return nil
+}
+
+// addRMC adds the fields of an RMC sentence to r.
+func (r *reader) addRMC(fields []string) error {
+	if len(fields) < 11 {
+		return errInvalidSentence
+	}
+	f, err := r.fixAt(fields[0])
+	if err != nil {
+		return err
+	}
+	if fields[1] == "V" || len(fields) > 11 && fields[11] == "N" {
+		f.lost = true
+	}
+	if err := f.setPosition(fields[2:6]); err != nil {
+		return err
+	}
+	var knots float64
+	var present gpx.WptField
+	if err := parseFloat(fields[6], &knots, &present, gpx.WptSpeed); err != nil {
+		return err
+	}
+	if present != 0 && !f.wpt.Has(gpx.WptSpeed) {
+		f.wpt.Speed = knots * metersPerSecondPerKnot
+		f.wpt.Present |= gpx.WptSpeed
+	}
+	if err := parseFloat(fields[7], &f.wpt.Course, &f.wpt.Present, gpx.WptCourse); err != nil {
+		return err
+	}
+	if fields[8] != "" {
+		date, err := time.Parse("020106", fields[8])
+		if err != nil {
+			return fmt.Errorf("%s: %w", fields[8], errInvalidField)
+		}
+		f.date = date
+		r.date = date
+	}
+	if err := parseFloat(fields[9], &f.wpt.MagVar, &f.wpt.Present, gpx.WptMagVar); err != nil {
+		return err
+	}
+	if fields[10] == "W" && f.wpt.MagVar != 0 {
+		f.wpt.MagVar = 360 - f.wpt.MagVar
+	}
+	return nil
+}
+
+// addGSA adds the fields of a GSA sentence to f.
+func (f *fix) addGSA(fields []string) error {
+	if len(fields) < 17 {
+		return errInvalidSentence
+	}
+	if fields[1] != "" {
+		var err error
+		if f.fixType, err = strconv.Atoi(fields[1]); err != nil {
+			return fmt.Errorf("%s: %w", fields[1], errInvalidField)
+		}
+		if f.fixType == 1 {
+			f.lost = true
+		}
+	}
+	if !f.wpt.Has(gpx.WptSat) {
+		for _, prn := range fields[2:14] {
+			if prn != "" {
+				f.wpt.Sat++
+				f.wpt.Present |= gpx.WptSat
+			}
+		}
+	}
+	if err := parseFloat(fields[14], &f.wpt.PDOP, &f.wpt.Present, gpx.WptPDOP); err != nil {
+		return err
+	}
+	if err := parseFloat(fields[15], &f.wpt.HDOP, &f.wpt.Present, gpx.WptHDOP); err != nil {
+		return err
+	}
+	return parseFloat(fields[16], &f.wpt.VDOP, &f.wpt.Present, gpx.WptVDOP)
+}
+
+// addVTG adds the fields of a VTG sentence to f.
+func (f *fix) addVTG(fields []string) error {
+	if len(fields) < 8 {
+		return errInvalidSentence
+	}
+	if len(fields) > 8 && fields[8] == "N" {
+		f.lost = true
+	}
+	if err := parseFloat(fields[0], &f.wpt.Course, &f.wpt.Present, gpx.WptCourse); err != nil {
+		return err
+	}
+	var kilometersPerHour float64
+	var present gpx.WptField
+	if err := parseFloat(fields[6], &kilometersPerHour, &present, gpx.WptSpeed); err != nil {
+		return err
+	}
+	if present != 0 {
+		f.wpt.Speed = kilometersPerHour / 3.6
+		f.wpt.Present |= gpx.WptSpeed
+	}
+	return nil
+}
+
+// setPosition sets f's position from the latitude, north or south, longitude,
+// and east or west fields.
+func (f *fix) setPosition(fields []string) error {
+	if fields[0] == "" || fields[2] == "" {
+		return nil
+	}
+	lat, err := parseLatLon(fields[0], fields[1], 2, "N", "S")
+	if err != nil {
+		return err
+	}
+	lon, err := parseLatLon(fields[2], fields[3], 3, "E", "W")
+	if err != nil {
+		return err
+	}
+	f.wpt.Lat = lat
+	f.wpt.Lon = lon
+	f.hasPosition = true
+	return nil
+}
+
+// wptFix returns f's GPX fix type.
+func (f *fix) wptFix() string {
+	switch {
+	case f.lost:
+		return "none"
+	case f.quality == 2:
+		return "dgps"
+	case f.quality == 3:
+		return "pps"
+	case f.fixType == 2:
+		return "2d"
+	case f.fixType == 3:
+		return "3d"
+	default:
+		return ""
+	}
+}
+
+// currentFix returns r's current fix, creating it if needed.
+func (r *reader) currentFix() *fix {
+	if r.fix == nil {
+		r.fix = &fix{
+			wpt: &gpx.WptType{},
+		}
+	}
+	return r.fix
+}
+
+// fixAt returns the fix at timeStr, the time of day in hhmmss.ss format,
+// flushing the current fix if it is at a different time.
+func (r *reader) fixAt(timeStr string) (*fix, error) {
+	if timeStr == "" {
+		return r.currentFix(), nil
+	}
+	timeOfDay, err := parseTimeOfDay(timeStr)
+	if err != nil {
+		return nil, err
+	}
+	if r.fix != nil && r.fix.hasTime && r.fix.timeOfDay != timeOfDay {
+		r.flush()
+	}
+	f := r.currentFix()
+	f.timeOfDay = timeOfDay
+	f.hasTime = true
+	return f, nil
+}
+
+// flush adds r's current fix to r's track.
+func (r *reader) flush() {
+	f := r.fix
+	if f == nil {
+		return
+	}
+	r.fix = nil
+	if f.lost || !f.hasPosition {
+		r.trkSeg = nil
+		return
+	}
+	if f.hasTime {
+		date := f.date
+		if date.IsZero() && !r.date.IsZero() {
+			if f.timeOfDay < r.lastTimeOfDay {
+				r.date = r.date.AddDate(0, 0, 1)
+			}
+			date = r.date
+		}
+		if !date.IsZero() {
+			f.wpt.Time = date.Add(f.timeOfDay)
+		}
+		r.lastTimeOfDay = f.timeOfDay
+	}
+	f.wpt.Fix = f.wptFix()
+	if r.trkSeg == nil {
+		r.trkSeg = &gpx.TrkSegType{}
+		r.trk.TrkSeg = append(r.trk.TrkSeg, r.trkSeg)
+	}
+	r.trkSeg.TrkPt = append(r.trkSeg.TrkPt, f.wpt)
+}
+
+// parseFloat parses s into value and sets field in present, if s is not
+// empty.
+func parseFloat(s string, value *float64, present *gpx.WptField, field gpx.WptField) error {
+	if s == "" {
+		return nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("%s: %w", s, errInvalidField)
+	}
+	*value = f
+	*present |= field
+	return nil
+}
+
+// parseLatLon parses s, in degrees and decimal minutes format with
+// degreeDigits digits of degrees, and hemisphere, which must be positive or
+// negative.
+func parseLatLon(s, hemisphere string, degreeDigits int, positive, negative string) (float64, error) {
+	if len(s) < degreeDigits+2 {
+		return 0, fmt.Errorf("%s: %w", s, errInvalidField)
+	}
+	degrees, err := strconv.Atoi(s[:degreeDigits])
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", s, errInvalidField)
+	}
+	minutes, err := strconv.ParseFloat(s[degreeDigits:], 64)
+	if err != nil || minutes >= 60 {
+		return 0, fmt.Errorf("%s: %w", s, errInvalidField)
+	}
+	value := float64(degrees) + minutes/60
+	switch hemisphere {
+	case positive:
+		return value, nil
+	case negative:
+		return -value, nil
+	default:
+		return 0, fmt.Errorf("%s: %w", hemisphere, errInvalidField)
+	}
+}
+
+// parseTimeOfDay parses s, in hhmmss.ss format.
+func parseTimeOfDay(s string) (time.Duration, error) {
+	if len(s) < 6 {
+		return 0, fmt.Errorf("%s: %w", s, errInvalidField)
+	}
+	hours, err1 := strconv.Atoi(s[0:2])
+	minutes, err2 := strconv.Atoi(s[2:4])
+	seconds, err3 := strconv.ParseFloat(s[4:], 64)
+	if err := errors.Join(err1, err2, err3); err != nil || hours >= 24 || minutes >= 60 || seconds >= 61 {
+		return 0, fmt.Errorf("%s: %w", s, errInvalidField)
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(math.Round(seconds*1e3))*time.Millisecond, nil
+}
@@ -0,0 +1,155 @@
+package nmea_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	"github.com/twpayne/go-gpx"
+	"github.com/twpayne/go-gpx/nmea"
+)
+
+func TestParseSentence(t *testing.T) {
+	for _, tc := range []struct {
+		s             string
+		expected      *nmea.Sentence
+		expectedError string
+	}{
+		{
+			s: "$GPGLL,4916.45,N,12311.12,W,225444,A,*1D",
+			expected: &nmea.Sentence{
+				Talker: "GP",
+				Type:   "GLL",
+				Fields: []string{"4916.45", "N", "12311.12", "W", "225444", "A", ""},
+			},
+		},
+		{
+			s:             "$GPGLL,4916.45,N,12311.12,W,225444,A,*1E",
+			expectedError: "invalid checksum",
+		},
+		{
+			s:             "$GPGLL,4916.45,N,12311.12,W,225444,A,",
+			expectedError: "missing checksum",
+		},
+		{
+			s:             "GPGLL,4916.45,N,12311.12,W,225444,A,*1D",
+			expectedError: "invalid sentence",
+		},
+	} {
+		t.Run(tc.s, func(t *testing.T) {
+			actual, err := nmea.ParseSentence(tc.s)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	data := strings.Join([]string{
+		"$GPRMC,123519.00,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W,A*29",
+		"$GPVTG,084.4,T,087.5,M,022.4,N,041.5,K,A*25",
+		"$GPGGA,123519.00,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*69",
+		"$GPGSA,A,3,04,05,,09,12,,,24,,,,,2.5,1.3,2.1*39",
+		"$GPGSV,2,1,08,01,40,083,46,02,17,308,41,12,07,344,39,14,22,228,45*75",
+		"$GPRMC,123520.00,A,4807.040,N,01131.002,E,022.0,084.0,230394,003.1,E,D*39",
+		"$GPGGA,123520.00,4807.040,N,01131.002,E,2,09,1.0,546.0,M,46.9,M,3.5,0120*48",
+		"$GPRMC,123521.00,V,,,,,,,230394,,,N*74",
+		"$GPGGA,123521.00,,,,,0,00,99.9,,M,,M,,*59",
+		"$GNGLL,4807.050,N,01131.010,E,123522.00,A,A*7F",
+		"$GNGGA,123522.00,4807.050,N,01131.010,E,1,07,1.1,547.0,M,46.9,M,,*70",
+	}, "\r\n")
+	degrees := func(degrees, minutes float64) float64 {
+		return degrees + minutes/60
+	}
+	knots := func(knots float64) float64 {
+		return knots * (1852.0 / 3600.0)
+	}
+	kilometersPerHour := 41.5
+	t0 := time.Date(1994, 3, 23, 12, 35, 19, 0, time.UTC)
+	expected := &gpx.GPX{
+		Version: "1.1",
+		Trk: []*gpx.TrkType{
+			{
+				TrkSeg: []*gpx.TrkSegType{
+					{
+						TrkPt: []*gpx.WptType{
+							{
+								Lat:         degrees(48, 7.038),
+								Lon:         degrees(11, 31),
+								Ele:         545.4,
+								Speed:       kilometersPerHour / 3.6,
+								Course:      84.4,
+								Time:        t0,
+								MagVar:      356.9,
+								GeoidHeight: 46.9,
+								Fix:         "3d",
+								Sat:         8,
+								HDOP:        1.3,
+								VDOP:        2.1,
+								PDOP:        2.5,
+								Present:     gpx.WptEle | gpx.WptSpeed | gpx.WptCourse | gpx.WptMagVar | gpx.WptGeoidHeight | gpx.WptSat | gpx.WptHDOP | gpx.WptVDOP | gpx.WptPDOP,
+							},
+							{
+								Lat:           degrees(48, 7.04),
+								Lon:           degrees(11, 31.002),
+								Ele:           546,
+								Speed:         knots(22),
+								Course:        84,
+								Time:          t0.Add(time.Second),
+								MagVar:        3.1,
+								GeoidHeight:   46.9,
+								Fix:           "dgps",
+								Sat:           9,
+								HDOP:          1,
+								AgeOfDGPSData: 3.5,
+								DGPSID:        []int{120},
+								Present:       gpx.WptEle | gpx.WptSpeed | gpx.WptCourse | gpx.WptMagVar | gpx.WptGeoidHeight | gpx.WptSat | gpx.WptHDOP | gpx.WptAgeOfDGPSData,
+							},
+						},
+					},
+					{
+						TrkPt: []*gpx.WptType{
+							{
+								Lat:         degrees(48, 7.05),
+								Lon:         degrees(11, 31.01),
+								Ele:         547,
+								Time:        t0.Add(3 * time.Second),
+								GeoidHeight: 46.9,
+								Sat:         7,
+								HDOP:        1.1,
+								Present:     gpx.WptEle | gpx.WptGeoidHeight | gpx.WptSat | gpx.WptHDOP,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	actual, err := nmea.Read(strings.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestReadOptions(t *testing.T) {
+	data := strings.Join([]string{
+		"$GPGGA,235959.00,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*65",
+		"$GPGGA,000000.00,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*00",
+		"$GPGGA,000000.00,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*64",
+	}, "\n")
+
+	_, err := nmea.Read(strings.NewReader(data))
+	assert.EqualError(t, err, "line 2: invalid checksum")
+
+	g, err := nmea.Read(strings.NewReader(data), nmea.WithDate(time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)), nmea.WithSkipInvalidSentences())
+	assert.NoError(t, err)
+	trkPts := g.Trk[0].TrkSeg[0].TrkPt
+	assert.Equal(t, 2, len(trkPts))
+	assert.Equal(t, time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC), trkPts[0].Time)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), trkPts[1].Time)
+}
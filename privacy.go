@@ -0,0 +1,226 @@
+package gpx
+
+// A PrivacyMode determines how points in privacy zones are redacted.
+type PrivacyMode int
+
+// Privacy modes.
+const (
+	// PrivacyRemove removes all points in privacy zones, splitting track
+	// segments where they pass through a zone.
+	PrivacyRemove PrivacyMode = iota
+	// PrivacyTruncate removes only the points in privacy zones at the start
+	// and end of each route and track segment, keeping points where they pass
+	// through a zone.
+	PrivacyTruncate
+)
+
+// A PrivacyZone is a circle around a sensitive location.
+type PrivacyZone struct {
+	Lat    float64
+	Lon    float64
+	Radius float64
+}
+
+// A PrivacyOption sets an option for Redact.
+type PrivacyOption func(*privacyOptions)
+
+type privacyOptions struct {
+	zones          []PrivacyZone
+	mode           PrivacyMode
+	trimDistance   float64
+	keepAuthorLink bool
+}
+
+// WithPrivacyZone adds a privacy zone of radius meters around lat and lon.
+func WithPrivacyZone(lat, lon, radius float64) PrivacyOption {
+	return func(o *privacyOptions) {
+		o.zones = append(o.zones, PrivacyZone{
+			Lat:    lat,
+			Lon:    lon,
+			Radius: radius,
+		})
+	}
+}
+
+// WithPrivacyZones adds privacy zones.
+func WithPrivacyZones(zones ...PrivacyZone) PrivacyOption {
+	return func(o *privacyOptions) {
+		o.zones = append(o.zones, zones...)
+	}
+}
+
+// WithPrivacyMode sets how points in privacy zones are redacted. The default
+// is PrivacyRemove.
+func WithPrivacyMode(mode PrivacyMode) PrivacyOption {
+	return func(o *privacyOptions) {
+		o.mode = mode
+	}
+}
+
+// WithTrimDistance removes the first and last trimDistance meters of every
+// track, measured along its segments.
+func WithTrimDistance(trimDistance float64) PrivacyOption {
+	return func(o *privacyOptions) {
+		o.trimDistance = trimDistance
+	}
+}
+
+// WithKeepAuthorLink keeps the link of the metadata author, for example to a
+// public profile page, which is otherwise removed.
+func WithKeepAuthorLink() PrivacyOption {
+	return func(o *privacyOptions) {
+		o.keepAuthorLink = true
+	}
+}
+
+// Redact returns a copy of g with sensitive information removed for
+// publishing. Tracks are first trimmed as set by WithTrimDistance, then route
+// and track points in privacy zones are redacted according to the privacy
+// mode. Waypoints in privacy zones are always removed, as are routes and
+// tracks left with no points. The metadata author, except for its link if
+// WithKeepAuthorLink is set, and the author of the metadata copyright are
+// removed, and the metadata bounds, if any, are recalculated from the
+// remaining points.
+func (g *GPX) Redact(options ...PrivacyOption) *GPX {
+	o := &privacyOptions{}
+	for _, option := range options {
+		option(o)
+	}
+	result := *g
+	result.Wpt = nil
+	for _, wpt := range g.Wpt {
+		if !o.inZone(wpt) {
+			result.Wpt = append(result.Wpt, wpt)
+		}
+	}
+	result.Rte = nil
+	for _, rte := range g.Rte {
+		var rtePts []*WptType
+		for _, run := range o.redactWpts(rte.RtePt) {
+			rtePts = append(rtePts, run...)
+		}
+		if len(rtePts) > 0 {
+			redactedRte := *rte
+			redactedRte.RtePt = rtePts
+			result.Rte = append(result.Rte, &redactedRte)
+		}
+	}
+	result.Trk = nil
+	for _, trk := range g.Trk {
+		redactedTrk := *trk
+		redactedTrk.TrkSeg = nil
+		for _, trkSeg := range o.trim(trk.TrkSeg) {
+			for _, run := range o.redactWpts(trkSeg.TrkPt) {
+				redactedTrk.TrkSeg = append(redactedTrk.TrkSeg, &TrkSegType{
+					TrkPt:      run,
+					Extensions: trkSeg.Extensions,
+				})
+			}
+		}
+		if len(redactedTrk.TrkSeg) > 0 {
+			result.Trk = append(result.Trk, &redactedTrk)
+		}
+	}
+	if g.Metadata != nil {
+		metadata := *g.Metadata
+		if o.keepAuthorLink && metadata.Author != nil && metadata.Author.Link != nil {
+			metadata.Author = &PersonType{
+				Link: metadata.Author.Link,
+			}
+		} else {
+			metadata.Author = nil
+		}
+		if metadata.Copyright != nil {
+			copyright := *metadata.Copyright
+			copyright.Author = ""
+			metadata.Copyright = &copyright
+		}
+		if metadata.Bounds != nil {
+			metadata.Bounds = result.Bounds()
+		}
+		result.Metadata = &metadata
+	}
+	return &result
+}
+
+// inZone returns whether w is in any privacy zone.
+func (o *privacyOptions) inZone(w *WptType) bool {
+	for _, zone := range o.zones {
+		if haversineDistance(zone.Lat, zone.Lon, w.Lat, w.Lon) <= zone.Radius {
+			return true
+		}
+	}
+	return false
+}
+
+// redactWpts returns the runs of consecutive points in wpts that remain after
+// redacting points in privacy zones.
+func (o *privacyOptions) redactWpts(wpts []*WptType) [][]*WptType {
+	switch o.mode {
+	case PrivacyRemove:
+		var runs [][]*WptType
+		start := 0
+		for i := 0; i <= len(wpts); i++ {
+			if i < len(wpts) && !o.inZone(wpts[i]) {
+				continue
+			}
+			if i > start {
+				runs = append(runs, wpts[start:i:i])
+			}
+			start = i + 1
+		}
+		return runs
+	case PrivacyTruncate:
+		start, end := 0, len(wpts)
+		for start < end && o.inZone(wpts[start]) {
+			start++
+		}
+		for end > start && o.inZone(wpts[end-1]) {
+			end--
+		}
+		if start == end {
+			return nil
+		}
+		return [][]*WptType{wpts[start:end:end]}
+	default:
+		return [][]*WptType{wpts}
+	}
+}
+
+// trim returns trkSegs with points within o.trimDistance meters of the start
+// or end of the track removed. Distances are measured along each segment and
+// do not include gaps between segments. Segments left with no points are
+// removed.
+func (o *privacyOptions) trim(trkSegs []*TrkSegType) []*TrkSegType {
+	if o.trimDistance <= 0 {
+		return trkSegs
+	}
+	var distances [][]float64
+	total := 0.0
+	for _, trkSeg := range trkSegs {
+		segDistances := make([]float64, len(trkSeg.TrkPt))
+		for i, trkPt := range trkSeg.TrkPt {
+			if i > 0 {
+				total += trkSeg.TrkPt[i-1].Distance(trkPt)
+			}
+			segDistances[i] = total
+		}
+		distances = append(distances, segDistances)
+	}
+	var result []*TrkSegType
+	for i, trkSeg := range trkSegs {
+		var trkPts []*WptType
+		for j, trkPt := range trkSeg.TrkPt {
+			if distances[i][j] >= o.trimDistance && total-distances[i][j] >= o.trimDistance {
+				trkPts = append(trkPts, trkPt)
+			}
+		}
+		if len(trkPts) > 0 {
+			result = append(result, &TrkSegType{
+				TrkPt:      trkPts,
+				Extensions: trkSeg.Extensions,
+			})
+		}
+	}
+	return result
+}
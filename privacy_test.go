@@ -0,0 +1,132 @@
+package gpx_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	gpx "github.com/twpayne/go-gpx"
+)
+
+func TestGPXRedact(t *testing.T) {
+	// Points along the equator every 100m, starting and ending at home and
+	// passing a sensitive location in the middle.
+	trkPts := make([]*gpx.WptType, 0, 11)
+	for _, lon := range []float64{0, 1, 2, 3, 4, 5, 4, 3, 2, 1, 0} {
+		trkPts = append(trkPts, &gpx.WptType{
+			Lon: lon * 100 / metersPerDegree,
+		})
+	}
+	home := gpx.PrivacyZone{Lat: 0, Lon: 0, Radius: 150}
+	work := gpx.PrivacyZone{Lat: 0, Lon: 5 * 100 / metersPerDegree, Radius: 50}
+	g := &gpx.GPX{
+		Metadata: &gpx.MetadataType{
+			Name: "commute",
+			Author: &gpx.PersonType{
+				Name: "Jane Doe",
+				Email: &gpx.EmailType{
+					Name:   "jane",
+					Domain: "example.com",
+				},
+				Link: &gpx.LinkType{
+					HREF: "https://example.com/jane",
+				},
+			},
+			Copyright: &gpx.CopyrightType{
+				Author:  "Jane Doe",
+				Year:    2024,
+				License: "https://creativecommons.org/licenses/by/4.0/",
+			},
+			Bounds: &gpx.BoundsType{MinLat: 0, MinLon: 0, MaxLat: 0, MaxLon: 1},
+		},
+		Wpt: []*gpx.WptType{
+			{Lat: 0, Lon: 0, Name: "home"},
+			{Lat: 1, Lon: 1, Name: "park"},
+		},
+		Trk: []*gpx.TrkType{
+			{
+				Name: "trk",
+				TrkSeg: []*gpx.TrkSegType{
+					{
+						TrkPt: trkPts,
+					},
+				},
+			},
+		},
+	}
+
+	for i, tc := range []struct {
+		options        []gpx.PrivacyOption
+		expectedTrkPts [][]*gpx.WptType
+	}{
+		{
+			expectedTrkPts: [][]*gpx.WptType{trkPts},
+		},
+		{
+			options: []gpx.PrivacyOption{
+				gpx.WithPrivacyZones(home, work),
+			},
+			expectedTrkPts: [][]*gpx.WptType{trkPts[2:5], trkPts[6:9]},
+		},
+		{
+			options: []gpx.PrivacyOption{
+				gpx.WithPrivacyZone(home.Lat, home.Lon, home.Radius),
+				gpx.WithPrivacyZone(work.Lat, work.Lon, work.Radius),
+				gpx.WithPrivacyMode(gpx.PrivacyTruncate),
+			},
+			expectedTrkPts: [][]*gpx.WptType{trkPts[2:9]},
+		},
+		{
+			options: []gpx.PrivacyOption{
+				gpx.WithTrimDistance(250),
+			},
+			expectedTrkPts: [][]*gpx.WptType{trkPts[3:8]},
+		},
+		{
+			options: []gpx.PrivacyOption{
+				gpx.WithPrivacyZones(work),
+				gpx.WithTrimDistance(350),
+			},
+			expectedTrkPts: [][]*gpx.WptType{trkPts[4:5], trkPts[6:7]},
+		},
+		{
+			options: []gpx.PrivacyOption{
+				gpx.WithTrimDistance(1000),
+			},
+		},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			actual := g.Redact(tc.options...)
+			assert.Equal(t, &gpx.MetadataType{
+				Name: "commute",
+				Copyright: &gpx.CopyrightType{
+					Year:    2024,
+					License: "https://creativecommons.org/licenses/by/4.0/",
+				},
+				Bounds: actual.Bounds(),
+			}, actual.Metadata)
+			var actualTrkPts [][]*gpx.WptType
+			for _, trk := range actual.Trk {
+				assert.Equal(t, "trk", trk.Name)
+				for _, trkSeg := range trk.TrkSeg {
+					actualTrkPts = append(actualTrkPts, trkSeg.TrkPt)
+				}
+			}
+			assert.Equal(t, tc.expectedTrkPts, actualTrkPts)
+		})
+	}
+
+	redacted := g.Redact(gpx.WithPrivacyZones(home))
+	assert.Equal(t, []*gpx.WptType{g.Wpt[1]}, redacted.Wpt)
+	assert.Equal(t, "Jane Doe", g.Metadata.Author.Name)
+	assert.Equal(t, "Jane Doe", g.Metadata.Copyright.Author)
+
+	redacted = g.Redact(gpx.WithKeepAuthorLink())
+	assert.Equal(t, &gpx.PersonType{
+		Link: &gpx.LinkType{
+			HREF: "https://example.com/jane",
+		},
+	}, redacted.Metadata.Author)
+	assert.Equal(t, 11, len(g.Trk[0].TrkSeg[0].TrkPt))
+}
@@ -0,0 +1,240 @@
+package gpx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+)
+
+var (
+	errDuplicateExtension    = errors.New("duplicate extension")
+	errUnregisteredExtension = errors.New("unregistered extension type")
+)
+
+// An extensionCodec decodes and encodes extension elements in a namespace.
+type extensionCodec struct {
+	namespace string
+	prefix    string
+	decode    func(*xml.Decoder, xml.StartElement) (any, error)
+	encode    func(*xml.Encoder, string, any) error
+}
+
+// An ExtensionRegistry contains functions to decode and encode extension
+// elements in specific namespaces. It is used when reading with
+// WithExtensionRegistry and when writing with WithWriteExtensionRegistry. The
+// zero value is an empty registry ready to use. An ExtensionRegistry must not
+// be modified while it is being used.
+type ExtensionRegistry struct {
+	byNamespace map[string]*extensionCodec
+	byType      map[reflect.Type]*extensionCodec
+}
+
+// RegisterExtension registers decode and encode functions for extension
+// elements in namespace in r. When reading, each top-level extension element
+// in namespace is decoded with decode and its value is added to the Typed
+// field of its ExtensionsType. When writing, each value of type T in Typed is
+// encoded with encode, which is passed the namespace prefix to use. prefix is
+// the preferred prefix for namespace when it is not already declared.
+// RegisterExtension returns an error if namespace or T is already registered
+// in r.
+func RegisterExtension[T any](r *ExtensionRegistry, namespace, prefix string, decode func(*xml.Decoder, xml.StartElement) (T, error), encode func(*xml.Encoder, string, T) error) error {
+	typ := reflect.TypeFor[T]()
+	if _, ok := r.byNamespace[namespace]; ok {
+		return fmt.Errorf("%s: %w", namespace, errDuplicateExtension)
+	}
+	if _, ok := r.byType[typ]; ok {
+		return fmt.Errorf("%s: %w", typ, errDuplicateExtension)
+	}
+	codec := &extensionCodec{
+		namespace: namespace,
+		prefix:    prefix,
+		decode: func(d *xml.Decoder, start xml.StartElement) (any, error) {
+			return decode(d, start)
+		},
+		encode: func(e *xml.Encoder, prefix string, v any) error {
+			value, ok := v.(T)
+			if !ok {
+				return fmt.Errorf("%T: %w", v, errUnregisteredExtension)
+			}
+			return encode(e, prefix, value)
+		},
+	}
+	if r.byNamespace == nil {
+		r.byNamespace = make(map[string]*extensionCodec)
+		r.byType = make(map[reflect.Type]*extensionCodec)
+	}
+	r.byNamespace[namespace] = codec
+	r.byType[typ] = codec
+	return nil
+}
+
+// WithExtensionRegistry decodes extension elements in the namespaces
+// registered in r into typed values.
+func WithExtensionRegistry(r *ExtensionRegistry) ReadOption {
+	return func(o *readOptions) {
+		o.extensionRegistry = r
+	}
+}
+
+// WithWriteExtensionRegistry encodes typed extension values with the
+// functions registered in r.
+func WithWriteExtensionRegistry(r *ExtensionRegistry) WriteOption {
+	return func(o *writeOptions) {
+		o.extensionRegistry = r
+	}
+}
+
+// ExtensionValues returns all values of type T in x.Typed.
+func ExtensionValues[T any](x *ExtensionsType) []T {
+	if x == nil {
+		return nil
+	}
+	var values []T
+	for _, typed := range x.Typed {
+		if value, ok := typed.(T); ok {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// MarshalXML implements xml.Marshaler.MarshalXML. Typed values must already
+// have been encoded into x.XML.
+func (x *ExtensionsType) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if len(x.Typed) > 0 {
+		return fmt.Errorf("%T: %w", x.Typed[0], errUnregisteredExtension)
+	}
+	return e.EncodeElement(struct {
+		XML []byte `xml:",innerxml"`
+	}{
+		XML: x.XML,
+	}, start)
+}
+
+// UnmarshalXML implements xml.Unmarshaler.UnmarshalXML. The namespace
+// prefixes declared on start are recorded in x.Namespaces.
+func (x *ExtensionsType) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var e struct {
+		XML []byte `xml:",innerxml"`
+	}
+	if err := d.DecodeElement(&e, &start); err != nil {
+		return err
+	}
+	x.XML = e.XML
+	x.Namespaces = declaredNamespaces(start.Attr)
+	return nil
+}
+
+// decodeTyped decodes all top-level elements of x in namespaces registered in
+// r, appends their values to x.Typed, and removes them from x.XML.
+func (x *ExtensionsType) decodeTyped(r *ExtensionRegistry) error {
+	if r == nil || len(r.byNamespace) == 0 {
+		return nil
+	}
+	elements, err := x.elements()
+	if err != nil {
+		return err
+	}
+	var xmlData []byte
+	var offset int64
+	decoded := false
+	for _, element := range elements {
+		codec := r.byNamespace[element.namespace]
+		if codec == nil {
+			continue
+		}
+		d, start, err := x.fragmentDecoder(x.XML[element.startIndex:element.endIndex])
+		if err != nil {
+			return err
+		}
+		value, err := codec.decode(d, start)
+		if err != nil {
+			return fmt.Errorf("%s: %w", element.namespace, err)
+		}
+		x.Typed = append(x.Typed, value)
+		xmlData = append(xmlData, x.XML[offset:element.startIndex]...)
+		offset = element.endIndex
+		decoded = true
+	}
+	if decoded {
+		x.XML = append(xmlData, x.XML[offset:]...)
+	}
+	return nil
+}
+
+// fragmentDecoder returns an xml.Decoder positioned after the start element of
+// data, a single element from x.XML, with the namespace prefixes in
+// x.Namespaces declared.
+func (x *ExtensionsType) fragmentDecoder(data []byte) (*xml.Decoder, xml.StartElement, error) {
+	buffer := &bytes.Buffer{}
+	buffer.WriteString("<extensions")
+	for _, prefix := range slices.Sorted(maps.Keys(x.Namespaces)) {
+		buffer.WriteString(" xmlns:" + prefix + "=\"")
+		if err := xml.EscapeText(buffer, []byte(x.Namespaces[prefix])); err != nil {
+			return nil, xml.StartElement{}, err
+		}
+		buffer.WriteString("\"")
+	}
+	buffer.WriteString(">")
+	buffer.Write(data)
+	buffer.WriteString("</extensions>")
+	d := xml.NewDecoder(buffer)
+	if _, err := d.Token(); err != nil {
+		return nil, xml.StartElement{}, err
+	}
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return nil, xml.StartElement{}, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return d, start, nil
+		}
+	}
+}
+
+// encodeTyped returns x with its typed values encoded with r and appended to
+// its XML. If x has no typed values then x itself is returned.
+func (x *ExtensionsType) encodeTyped(r *ExtensionRegistry) (*ExtensionsType, error) {
+	if x == nil || len(x.Typed) == 0 {
+		return x, nil
+	}
+	encoded := &ExtensionsType{
+		Namespaces: x.Namespaces,
+	}
+	buffer := bytes.NewBuffer(slices.Clone(x.XML))
+	xmlEncoder := xml.NewEncoder(buffer)
+	for _, value := range x.Typed {
+		codec := r.codecForType(reflect.TypeOf(value))
+		if codec == nil {
+			return nil, fmt.Errorf("%T: %w", value, errUnregisteredExtension)
+		}
+		if err := codec.encode(xmlEncoder, encoded.prefix(codec.namespace, codec.prefix), value); err != nil {
+			return nil, err
+		}
+	}
+	if err := xmlEncoder.Flush(); err != nil {
+		return nil, err
+	}
+	encoded.XML = buffer.Bytes()
+	return encoded, nil
+}
+
+// withEncodedTyped returns g with the typed values of all extensions encoded
+// with r. Only the parts of g that contain typed values are copied.
+func (g *GPX) withEncodedTyped(r *ExtensionRegistry) (*GPX, error) {
+	return g.mapExtensions(func(x *ExtensionsType) (*ExtensionsType, error) {
+		return x.encodeTyped(r)
+	})
+}
+
+func (r *ExtensionRegistry) codecForType(typ reflect.Type) *extensionCodec {
+	if r == nil {
+		return nil
+	}
+	return r.byType[typ]
+}
@@ -0,0 +1,166 @@
+package gpx_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+
+	gpx "github.com/twpayne/go-gpx"
+)
+
+const sensorNamespace = "https://example.com/xmlschemas/Sensor/v1"
+
+type sensor struct {
+	ID      string  `xml:"id,attr"`
+	Battery float64 `xml:"battery,attr"`
+}
+
+func newSensorRegistry(t *testing.T) *gpx.ExtensionRegistry {
+	t.Helper()
+	r := &gpx.ExtensionRegistry{}
+	assert.NoError(t, gpx.RegisterExtension(r, sensorNamespace, "sensor",
+		func(d *xml.Decoder, start xml.StartElement) (sensor, error) {
+			var s sensor
+			err := d.DecodeElement(&s, &start)
+			return s, err
+		},
+		func(e *xml.Encoder, prefix string, s sensor) error {
+			return e.EncodeElement(s, xml.StartElement{Name: xml.Name{Local: prefix + ":sensor"}})
+		},
+	))
+	return r
+}
+
+func TestRegisterExtension(t *testing.T) {
+	r := newSensorRegistry(t)
+
+	data := "<gpx" +
+		" version=\"1.1\"" +
+		" creator=\"creator\"" +
+		" xmlns:xsi=\"http://www.w3.org/2001/XMLSchema-instance\"" +
+		" xmlns=\"http://www.topografix.com/GPX/1/1\"" +
+		" xsi:schemaLocation=\"http://www.topografix.com/GPX/1/1 https://www.topografix.com/GPX/1/1/gpx.xsd\"" +
+		" xmlns:other=\"https://example.com/other\"" +
+		" xmlns:s=\"" + sensorNamespace + "\">\n" +
+		"\t<trk>\n" +
+		"\t\t<trkseg>\n" +
+		"\t\t\t<trkpt lat=\"1\" lon=\"2\">\n" +
+		"\t\t\t\t<extensions><other:foo>bar</other:foo><s:sensor id=\"a\" battery=\"0.5\"></s:sensor></extensions>\n" +
+		"\t\t\t</trkpt>\n" +
+		"\t\t</trkseg>\n" +
+		"\t\t<extensions><s:sensor id=\"b\" battery=\"1\"></s:sensor></extensions>\n" +
+		"\t</trk>\n" +
+		"</gpx>"
+	namespaces := map[string]string{
+		"other": "https://example.com/other",
+		"s":     sensorNamespace,
+	}
+	expected := &gpx.GPX{
+		Version: "1.1",
+		Creator: "creator",
+		XMLAttrs: map[string]string{
+			"xmlns:other": "https://example.com/other",
+			"xmlns:s":     sensorNamespace,
+		},
+		Trk: []*gpx.TrkType{
+			{
+				Extensions: &gpx.ExtensionsType{
+					XML:        []byte{},
+					Namespaces: namespaces,
+					Typed:      []any{sensor{ID: "b", Battery: 1}},
+				},
+				TrkSeg: []*gpx.TrkSegType{
+					{
+						TrkPt: []*gpx.WptType{
+							{
+								Lat: 1,
+								Lon: 2,
+								Extensions: &gpx.ExtensionsType{
+									XML:        []byte("<other:foo>bar</other:foo>"),
+									Namespaces: namespaces,
+									Typed:      []any{sensor{ID: "a", Battery: 0.5}},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	got, err := gpx.Read(bytes.NewBufferString(data), gpx.WithExtensionRegistry(r))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, got)
+	assert.Equal(t, []sensor{{ID: "a", Battery: 0.5}}, gpx.ExtensionValues[sensor](got.Trk[0].TrkSeg[0].TrkPt[0].Extensions))
+
+	assert.EqualError(t, got.Write(&strings.Builder{}), "gpx_test.sensor: unregistered extension type")
+
+	sb := &strings.Builder{}
+	assert.NoError(t, got.WriteIndent(sb, "", "\t", gpx.WithWriteExtensionRegistry(r)))
+	roundTripped, err := gpx.Read(strings.NewReader(sb.String()), gpx.WithExtensionRegistry(r))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, roundTripped)
+
+	untyped, err := gpx.Read(strings.NewReader(sb.String()))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(untyped.Trk[0].Extensions.Typed))
+	assert.Equal(t, `<s:sensor id="b" battery="1"></s:sensor>`, string(untyped.Trk[0].Extensions.XML))
+}
+
+func TestRegisterExtensionTwice(t *testing.T) {
+	r := newSensorRegistry(t)
+	assert.EqualError(t, gpx.RegisterExtension(r, sensorNamespace, "sensor",
+		func(*xml.Decoder, xml.StartElement) (int, error) { return 0, nil },
+		func(*xml.Encoder, string, int) error { return nil },
+	), sensorNamespace+": duplicate extension")
+}
+
+func TestRegisterExtensionWithoutNamespace(t *testing.T) {
+	r := newSensorRegistry(t)
+
+	g := &gpx.GPX{
+		Version: "1.1",
+		Wpt: []*gpx.WptType{
+			{
+				Lat: 1,
+				Lon: 2,
+				Extensions: &gpx.ExtensionsType{
+					Typed: []any{sensor{ID: "c"}},
+				},
+			},
+		},
+	}
+	sb := &strings.Builder{}
+	assert.NoError(t, g.Write(sb, gpx.WithWriteExtensionRegistry(r)))
+	assert.Contains(t, sb.String(), ` xmlns:sensor="`+sensorNamespace+`"`)
+	assert.Contains(t, sb.String(), `<extensions><sensor:sensor id="c" battery="0"></sensor:sensor></extensions>`)
+	assert.Equal(t, 1, len(g.Wpt[0].Extensions.Typed))
+}
+
+func TestRegisterExtensionPrefixConflict(t *testing.T) {
+	r := newSensorRegistry(t)
+	g := &gpx.GPX{
+		Version: "1.1",
+		Wpt: []*gpx.WptType{
+			{
+				Lat: 1,
+				Lon: 2,
+				Extensions: &gpx.ExtensionsType{
+					XML: []byte(`<sensor:other/>`),
+					Namespaces: map[string]string{
+						"sensor": "https://example.com/other",
+					},
+					Typed: []any{sensor{ID: "d"}},
+				},
+			},
+		},
+	}
+	sb := &strings.Builder{}
+	assert.NoError(t, g.Write(sb, gpx.WithWriteExtensionRegistry(r)))
+	assert.Contains(t, sb.String(), ` xmlns:sensor="https://example.com/other"`)
+	assert.Contains(t, sb.String(), ` xmlns:sensor2="`+sensorNamespace+`"`)
+	assert.Contains(t, sb.String(), `<extensions><sensor:other/><sensor2:sensor id="d" battery="0"></sensor2:sensor></extensions>`)
+}
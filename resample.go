@@ -0,0 +1,213 @@
+package gpx
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+var (
+	errInvalidInterval = errors.New("invalid interval")
+	errMissingTime     = errors.New("missing time")
+)
+
+// A ResampleOption sets an option for resampling.
+type ResampleOption func(*resampleOptions)
+
+type resampleOptions struct {
+	maxGap time.Duration
+}
+
+// WithMaxGap prevents interpolation across gaps, where consecutive points are
+// more than maxGap apart in time. The points either side of a gap are kept and
+// resampling restarts at the point after the gap. Gaps are only detected by
+// time, including when resampling by distance, and points without a time
+// never start a gap.
+func WithMaxGap(maxGap time.Duration) ResampleOption {
+	return func(o *resampleOptions) {
+		o.maxGap = maxGap
+	}
+}
+
+// ResampleTime returns a new TrkSegType with points every interval, starting
+// at ts's first point and ending with ts's last point. All points must have a
+// time and times must not decrease. Lat and Lon are interpolated along great
+// circles, Course and the Course and Bearing of Garmin TrackPointExtensions
+// are interpolated along the shorter arc, and Ele, Time, and other numeric
+// Garmin TrackPointExtension values are interpolated linearly. Resampled
+// points that coincide with original points are the original points.
+func (ts *TrkSegType) ResampleTime(interval time.Duration, options ...ResampleOption) (*TrkSegType, error) {
+	if interval <= 0 {
+		return nil, errInvalidInterval
+	}
+	for _, trkPt := range ts.TrkPt {
+		if trkPt.Time.IsZero() {
+			return nil, errMissingTime
+		}
+	}
+	positions := make([]float64, len(ts.TrkPt))
+	for i, trkPt := range ts.TrkPt {
+		positions[i] = float64(trkPt.Time.Sub(ts.TrkPt[0].Time))
+	}
+	return ts.resample(positions, float64(interval), options)
+}
+
+// ResampleDistance returns a new TrkSegType with points every distance
+// meters along ts, starting at ts's first point and ending with ts's last
+// point. Values are interpolated as ResampleTime.
+func (ts *TrkSegType) ResampleDistance(distance float64, options ...ResampleOption) (*TrkSegType, error) {
+	if distance <= 0 {
+		return nil, errInvalidInterval
+	}
+	positions := make([]float64, len(ts.TrkPt))
+	for i := 1; i < len(ts.TrkPt); i++ {
+		positions[i] = positions[i-1] + ts.TrkPt[i-1].Distance(ts.TrkPt[i])
+	}
+	return ts.resample(positions, distance, options)
+}
+
+// resample returns a new TrkSegType with points every step along positions,
+// the non-decreasing positions of ts's points.
+func (ts *TrkSegType) resample(positions []float64, step float64, options []ResampleOption) (*TrkSegType, error) {
+	var o resampleOptions
+	for _, option := range options {
+		option(&o)
+	}
+	result := &TrkSegType{
+		Extensions: ts.Extensions,
+	}
+	if len(ts.TrkPt) == 0 {
+		return result, nil
+	}
+	result.TrkPt = append(result.TrkPt, ts.TrkPt[0])
+	start, k := positions[0], 1
+	for i := 1; i < len(ts.TrkPt); i++ {
+		prev, trkPt := ts.TrkPt[i-1], ts.TrkPt[i]
+		if o.maxGap > 0 && !prev.Time.IsZero() && !trkPt.Time.IsZero() && trkPt.Time.Sub(prev.Time) > o.maxGap {
+			if result.TrkPt[len(result.TrkPt)-1] != prev {
+				result.TrkPt = append(result.TrkPt, prev)
+			}
+			result.TrkPt = append(result.TrkPt, trkPt)
+			start, k = positions[i], 1
+			continue
+		}
+		for ; start+float64(k)*step < positions[i]; k++ {
+			f := (start + float64(k)*step - positions[i-1]) / (positions[i] - positions[i-1])
+			wpt, err := interpolateWpt(prev, trkPt, f)
+			if err != nil {
+				return nil, err
+			}
+			result.TrkPt = append(result.TrkPt, wpt)
+		}
+		switch {
+		case start+float64(k)*step == positions[i]:
+			result.TrkPt = append(result.TrkPt, trkPt)
+			k++
+		case i == len(ts.TrkPt)-1:
+			result.TrkPt = append(result.TrkPt, trkPt)
+		}
+	}
+	return result, nil
+}
+
+// interpolateWpt returns a new point the fraction f of the way from w1 to w2.
+func interpolateWpt(w1, w2 *WptType, f float64) (*WptType, error) {
+	w := &WptType{}
+	w.Lat, w.Lon = interpolateLatLon(w1, w2, f)
+	if w1.Has(WptEle) && w2.Has(WptEle) {
+		w.Ele = w1.Ele + f*(w2.Ele-w1.Ele)
+		w.Present |= WptEle
+	}
+	if w1.Has(WptCourse) && w2.Has(WptCourse) {
+		w.Course = interpolateAngle(w1.Course, w2.Course, f)
+		w.Present |= WptCourse
+	}
+	if !w1.Time.IsZero() && !w2.Time.IsZero() {
+		w.Time = w1.Time.Add(time.Duration(f * float64(w2.Time.Sub(w1.Time))))
+	}
+	tpe1, err := w1.TrackPointExtension()
+	if err != nil {
+		return nil, err
+	}
+	tpe2, err := w2.TrackPointExtension()
+	if err != nil {
+		return nil, err
+	}
+	if tpe1 != nil && tpe2 != nil {
+		lerp := func(a, b float64) float64 {
+			return a + f*(b-a)
+		}
+		tpe := &TrackPointExtension{
+			Version: tpe1.Version,
+		}
+		// Only fields present at both points are interpolated.
+		has := func(field TrackPointExtensionField) bool {
+			if !tpe1.Has(field) || !tpe2.Has(field) {
+				return false
+			}
+			tpe.Present |= field
+			return true
+		}
+		if has(TrackPointExtensionATemp) {
+			tpe.ATemp = lerp(tpe1.ATemp, tpe2.ATemp)
+		}
+		if has(TrackPointExtensionWTemp) {
+			tpe.WTemp = lerp(tpe1.WTemp, tpe2.WTemp)
+		}
+		if has(TrackPointExtensionDepth) {
+			tpe.Depth = lerp(tpe1.Depth, tpe2.Depth)
+		}
+		if has(TrackPointExtensionHR) {
+			tpe.HR = int(math.Round(lerp(float64(tpe1.HR), float64(tpe2.HR))))
+		}
+		if has(TrackPointExtensionCad) {
+			tpe.Cad = int(math.Round(lerp(float64(tpe1.Cad), float64(tpe2.Cad))))
+		}
+		if has(TrackPointExtensionSpeed) {
+			tpe.Speed = lerp(tpe1.Speed, tpe2.Speed)
+		}
+		if has(TrackPointExtensionCourse) {
+			tpe.Course = interpolateAngle(tpe1.Course, tpe2.Course, f)
+		}
+		if has(TrackPointExtensionBearing) {
+			tpe.Bearing = interpolateAngle(tpe1.Bearing, tpe2.Bearing, f)
+		}
+		w.Extensions = &ExtensionsType{
+			Namespaces: w1.Extensions.Namespaces,
+		}
+		if err := w.SetTrackPointExtension(tpe); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// interpolateAngle returns the angle in degrees the fraction f of the way
+// from a to b along the shorter arc, in the range [0, 360).
+func interpolateAngle(a, b, f float64) float64 {
+	angle := math.Mod(a+f*math.Remainder(b-a, 360), 360)
+	if angle < 0 {
+		angle += 360
+	}
+	if angle >= 360 {
+		angle -= 360
+	}
+	return angle
+}
+
+// interpolateLatLon returns the point the fraction f of the way along the
+// great circle from w1 to w2.
+func interpolateLatLon(w1, w2 *WptType, f float64) (float64, float64) {
+	delta := w1.Distance(w2) / earthRadius
+	if delta < 1e-12 {
+		return w1.Lat + f*(w2.Lat-w1.Lat), w1.Lon + f*(w2.Lon-w1.Lon)
+	}
+	phi1, lambda1 := w1.Lat*math.Pi/180, w1.Lon*math.Pi/180
+	phi2, lambda2 := w2.Lat*math.Pi/180, w2.Lon*math.Pi/180
+	a := math.Sin((1-f)*delta) / math.Sin(delta)
+	b := math.Sin(f*delta) / math.Sin(delta)
+	x := a*math.Cos(phi1)*math.Cos(lambda1) + b*math.Cos(phi2)*math.Cos(lambda2)
+	y := a*math.Cos(phi1)*math.Sin(lambda1) + b*math.Cos(phi2)*math.Sin(lambda2)
+	z := a*math.Sin(phi1) + b*math.Sin(phi2)
+	return math.Atan2(z, math.Hypot(x, y)) * 180 / math.Pi, math.Atan2(y, x) * 180 / math.Pi
+}
@@ -0,0 +1,147 @@
+package gpx_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	gpx "github.com/twpayne/go-gpx"
+)
+
+func TestResampleTime(t *testing.T) {
+	t0 := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	trkPt0 := &gpx.WptType{Lon: 0, Time: t0, Present: gpx.WptEle}
+	assert.NoError(t, trkPt0.SetTrackPointExtension(&gpx.TrackPointExtension{Version: 1, HR: 100, Present: gpx.TrackPointExtensionATemp}))
+	trkPt1 := &gpx.WptType{Lon: 0.001, Ele: 10, Time: t0.Add(10 * time.Second)}
+	assert.NoError(t, trkPt1.SetTrackPointExtension(&gpx.TrackPointExtension{Version: 1, HR: 120, ATemp: 20, WTemp: 15}))
+	trkSeg := &gpx.TrkSegType{
+		TrkPt: []*gpx.WptType{trkPt0, trkPt1},
+	}
+
+	resampled, err := trkSeg.ResampleTime(4 * time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(resampled.TrkPt))
+	assert.True(t, resampled.TrkPt[0] == trkPt0)
+	assert.True(t, resampled.TrkPt[3] == trkPt1)
+
+	trkPt := resampled.TrkPt[1]
+	assertInDelta(t, 0, trkPt.Lat, 1e-12)
+	assertInDelta(t, 0.0004, trkPt.Lon, 1e-12)
+	assertInDelta(t, 4, trkPt.Ele, 1e-9)
+	assert.True(t, trkPt.Has(gpx.WptEle))
+	assert.Equal(t, t0.Add(4*time.Second), trkPt.Time)
+	tpe, err := trkPt.TrackPointExtension()
+	assert.NoError(t, err)
+	assert.Equal(t, &gpx.TrackPointExtension{
+		Version: 1,
+		HR:      108,
+		ATemp:   8,
+		Present: gpx.TrackPointExtensionATemp | gpx.TrackPointExtensionHR,
+	}, tpe)
+
+	assert.Equal(t, t0.Add(8*time.Second), resampled.TrkPt[2].Time)
+}
+
+func TestResampleTimeAngles(t *testing.T) {
+	t0 := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name     string
+		course1  float64
+		course2  float64
+		expected float64
+	}{
+		{
+			name:     "wrap_up",
+			course1:  350,
+			course2:  10,
+			expected: 355,
+		},
+		{
+			name:     "wrap_down",
+			course1:  10,
+			course2:  330,
+			expected: 0,
+		},
+		{
+			name:     "no_wrap",
+			course1:  90,
+			course2:  170,
+			expected: 110,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			trkPt0 := &gpx.WptType{Lon: 0, Course: tc.course1, Time: t0, Present: gpx.WptCourse}
+			assert.NoError(t, trkPt0.SetTrackPointExtension(&gpx.TrackPointExtension{Version: 2, Course: tc.course1, Bearing: tc.course1}))
+			trkPt1 := &gpx.WptType{Lon: 0.001, Course: tc.course2, Time: t0.Add(4 * time.Second), Present: gpx.WptCourse}
+			assert.NoError(t, trkPt1.SetTrackPointExtension(&gpx.TrackPointExtension{Version: 2, Course: tc.course2, Bearing: tc.course2}))
+			trkSeg := &gpx.TrkSegType{
+				TrkPt: []*gpx.WptType{trkPt0, trkPt1},
+			}
+
+			resampled, err := trkSeg.ResampleTime(time.Second)
+			assert.NoError(t, err)
+			trkPt := resampled.TrkPt[1]
+			assert.True(t, trkPt.Has(gpx.WptCourse))
+			assertInDelta(t, tc.expected, trkPt.Course, 1e-9)
+			tpe, err := trkPt.TrackPointExtension()
+			assert.NoError(t, err)
+			assertInDelta(t, tc.expected, tpe.Course, 1e-9)
+			assertInDelta(t, tc.expected, tpe.Bearing, 1e-9)
+		})
+	}
+}
+
+func TestResampleTimeMaxGap(t *testing.T) {
+	t0 := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	trkSeg := &gpx.TrkSegType{
+		TrkPt: []*gpx.WptType{
+			{Lon: 0, Time: t0},
+			{Lon: 0.002, Time: t0.Add(2 * time.Second)},
+			{Lon: 1, Time: t0.Add(100 * time.Second)},
+			{Lon: 1.002, Time: t0.Add(102 * time.Second)},
+		},
+	}
+
+	resampled, err := trkSeg.ResampleTime(time.Second, gpx.WithMaxGap(10*time.Second))
+	assert.NoError(t, err)
+	times := make([]time.Duration, 0, len(resampled.TrkPt))
+	for _, trkPt := range resampled.TrkPt {
+		times = append(times, trkPt.Time.Sub(t0))
+	}
+	assert.Equal(t, []time.Duration{0, time.Second, 2 * time.Second, 100 * time.Second, 101 * time.Second, 102 * time.Second}, times)
+
+	resampled, err = trkSeg.ResampleTime(time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, 103, len(resampled.TrkPt))
+}
+
+func TestResampleDistance(t *testing.T) {
+	trkSeg := &gpx.TrkSegType{
+		TrkPt: []*gpx.WptType{
+			{Lon: 0},
+			{Lon: 0.002},
+		},
+	}
+	resampled, err := trkSeg.ResampleDistance(100)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(resampled.TrkPt))
+	assertInDelta(t, 100/metersPerDegree, resampled.TrkPt[1].Lon, 1e-12)
+	assertInDelta(t, 200/metersPerDegree, resampled.TrkPt[2].Lon, 1e-12)
+	assert.True(t, resampled.TrkPt[3].Time.IsZero())
+	assert.Equal(t, 0.002, resampled.TrkPt[3].Lon)
+}
+
+func TestResampleErrors(t *testing.T) {
+	trkSeg := &gpx.TrkSegType{
+		TrkPt: []*gpx.WptType{
+			{Lon: 0},
+		},
+	}
+	_, err := trkSeg.ResampleTime(time.Second)
+	assert.Error(t, err)
+	_, err = trkSeg.ResampleTime(0)
+	assert.Error(t, err)
+	_, err = trkSeg.ResampleDistance(-1)
+	assert.Error(t, err)
+}
@@ -0,0 +1,236 @@
+package gpx
+
+import (
+	"container/heap"
+	"math"
+)
+
+// A vwPoint is a point in a Visvalingam-Whyatt simplification.
+type vwPoint struct {
+	wpt        *WptType
+	prev, next *vwPoint
+	area       float64
+	heapIndex  int
+}
+
+// A vwHeap is a min-heap of vwPoints ordered by area.
+type vwHeap []*vwPoint
+
+func (h vwHeap) Len() int           { return len(h) }
+func (h vwHeap) Less(i, j int) bool { return h[i].area < h[j].area }
+
+func (h vwHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapIndex = i
+	h[j].heapIndex = j
+}
+
+func (h *vwHeap) Push(x any) {
+	p, _ := x.(*vwPoint)
+	p.heapIndex = len(*h)
+	*h = append(*h, p)
+}
+
+func (h *vwHeap) Pop() any {
+	old := *h
+	n := len(old)
+	p := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return p
+}
+
+// SimplifyDouglasPeucker returns a new TrkSegType containing ts's points
+// simplified with the Douglas-Peucker algorithm, keeping all points that are
+// more than tolerance meters from the simplified segment.
+func (ts *TrkSegType) SimplifyDouglasPeucker(tolerance float64) *TrkSegType {
+	return &TrkSegType{
+		TrkPt:      simplifyDouglasPeucker(ts.TrkPt, tolerance),
+		Extensions: ts.Extensions,
+	}
+}
+
+// SimplifyVisvalingamWhyatt returns a new TrkSegType containing ts's points
+// simplified with the Visvalingam-Whyatt algorithm. Points are removed in
+// order of increasing effective area until all remaining points have an
+// effective area of at least minArea square meters or only minPoints points
+// remain. The first and last points are always kept.
+func (ts *TrkSegType) SimplifyVisvalingamWhyatt(minArea float64, minPoints int) *TrkSegType {
+	return &TrkSegType{
+		TrkPt:      simplifyVisvalingamWhyatt(ts.TrkPt, minArea, minPoints),
+		Extensions: ts.Extensions,
+	}
+}
+
+// SimplifyDouglasPeucker returns a copy of t with each segment simplified with
+// TrkSegType.SimplifyDouglasPeucker.
+func (t *TrkType) SimplifyDouglasPeucker(tolerance float64) *TrkType {
+	return t.mapTrkSegs(func(ts *TrkSegType) *TrkSegType {
+		return ts.SimplifyDouglasPeucker(tolerance)
+	})
+}
+
+// SimplifyVisvalingamWhyatt returns a copy of t with each segment simplified
+// with TrkSegType.SimplifyVisvalingamWhyatt.
+func (t *TrkType) SimplifyVisvalingamWhyatt(minArea float64, minPoints int) *TrkType {
+	return t.mapTrkSegs(func(ts *TrkSegType) *TrkSegType {
+		return ts.SimplifyVisvalingamWhyatt(minArea, minPoints)
+	})
+}
+
+// SimplifyDouglasPeucker returns a copy of r with its points simplified as
+// TrkSegType.SimplifyDouglasPeucker.
+func (r *RteType) SimplifyDouglasPeucker(tolerance float64) *RteType {
+	rte := *r
+	rte.RtePt = simplifyDouglasPeucker(r.RtePt, tolerance)
+	return &rte
+}
+
+// SimplifyVisvalingamWhyatt returns a copy of r with its points simplified
+// as TrkSegType.SimplifyVisvalingamWhyatt.
+func (r *RteType) SimplifyVisvalingamWhyatt(minArea float64, minPoints int) *RteType {
+	rte := *r
+	rte.RtePt = simplifyVisvalingamWhyatt(r.RtePt, minArea, minPoints)
+	return &rte
+}
+
+// mapTrkSegs returns a copy of t with each of its segments replaced by the
+// result of calling f.
+func (t *TrkType) mapTrkSegs(f func(*TrkSegType) *TrkSegType) *TrkType {
+	trk := *t
+	trk.TrkSeg = make([]*TrkSegType, 0, len(t.TrkSeg))
+	for _, trkSeg := range t.TrkSeg {
+		trk.TrkSeg = append(trk.TrkSeg, f(trkSeg))
+	}
+	return &trk
+}
+
+func simplifyDouglasPeucker(wpts []*WptType, tolerance float64) []*WptType {
+	if len(wpts) <= 2 {
+		return append([]*WptType(nil), wpts...)
+	}
+	keep := make([]bool, len(wpts))
+	keep[0] = true
+	keep[len(wpts)-1] = true
+	type span struct{ start, end int }
+	stack := []span{{0, len(wpts) - 1}}
+	for len(stack) > 0 {
+		s := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		maxDistance, maxIndex := 0.0, -1
+		for i := s.start + 1; i < s.end; i++ {
+			if d := crossTrackDistance(wpts[i], wpts[s.start], wpts[s.end]); d > maxDistance {
+				maxDistance, maxIndex = d, i
+			}
+		}
+		if maxIndex == -1 || maxDistance <= tolerance {
+			continue
+		}
+		keep[maxIndex] = true
+		stack = append(stack, span{s.start, maxIndex}, span{maxIndex, s.end})
+	}
+	var result []*WptType
+	for i, wpt := range wpts {
+		if keep[i] {
+			result = append(result, wpt)
+		}
+	}
+	return result
+}
+
+func simplifyVisvalingamWhyatt(wpts []*WptType, minArea float64, minPoints int) []*WptType {
+	minPoints = max(minPoints, 2)
+	if len(wpts) <= minPoints {
+		return append([]*WptType(nil), wpts...)
+	}
+	points := make([]*vwPoint, len(wpts))
+	for i, wpt := range wpts {
+		points[i] = &vwPoint{
+			wpt:  wpt,
+			area: math.Inf(1),
+		}
+		if i > 0 {
+			points[i].prev = points[i-1]
+			points[i-1].next = points[i]
+		}
+	}
+	h := make(vwHeap, 0, len(points)-2)
+	for _, p := range points[1 : len(points)-1] {
+		p.area = triangleArea(p.prev.wpt, p.wpt, p.next.wpt)
+		h = append(h, p)
+	}
+	for i, p := range h {
+		p.heapIndex = i
+	}
+	heap.Init(&h)
+	remaining := len(points)
+	for h.Len() > 0 && remaining > minPoints {
+		p, _ := heap.Pop(&h).(*vwPoint)
+		if p.area >= minArea {
+			break
+		}
+		p.prev.next = p.next
+		p.next.prev = p.prev
+		remaining--
+		for _, neighbor := range []*vwPoint{p.prev, p.next} {
+			if neighbor.prev == nil || neighbor.next == nil {
+				continue
+			}
+			// Ensure that effective areas never decrease, so that a point
+			// is not removed before a point that was removed before it.
+			neighbor.area = max(triangleArea(neighbor.prev.wpt, neighbor.wpt, neighbor.next.wpt), p.area)
+			heap.Fix(&h, neighbor.heapIndex)
+		}
+	}
+	result := make([]*WptType, 0, remaining)
+	for p := points[0]; p != nil; p = p.next {
+		result = append(result, p.wpt)
+	}
+	return result
+}
+
+// crossTrackDistance returns the distance in meters from p to the great circle
+// segment from a to b.
+func crossTrackDistance(p, a, b *WptType) float64 {
+	d13 := a.Distance(p) / earthRadius
+	if a.Lat == b.Lat && a.Lon == b.Lon {
+		return d13 * earthRadius
+	}
+	theta := initialBearing(a, p) - initialBearing(a, b)
+	if math.Cos(theta) < 0 {
+		return d13 * earthRadius
+	}
+	dxt := math.Asin(math.Sin(d13) * math.Sin(theta))
+	dat := math.Acos(min(math.Cos(d13)/math.Cos(dxt), 1))
+	if dat > a.Distance(b)/earthRadius {
+		return b.Distance(p)
+	}
+	return math.Abs(dxt) * earthRadius
+}
+
+// initialBearing returns the initial bearing from w1 to w2 in radians.
+func initialBearing(w1, w2 *WptType) float64 {
+	phi1 := w1.Lat * math.Pi / 180
+	phi2 := w2.Lat * math.Pi / 180
+	dLambda := (w2.Lon - w1.Lon) * math.Pi / 180
+	y := math.Sin(dLambda) * math.Cos(phi2)
+	x := math.Cos(phi1)*math.Sin(phi2) - math.Sin(phi1)*math.Cos(phi2)*math.Cos(dLambda)
+	return math.Atan2(y, x)
+}
+
+// triangleArea returns the area in square meters of the triangle with
+// vertices w1, w2, and w3, using an equirectangular projection centered on w2.
+func triangleArea(w1, w2, w3 *WptType) float64 {
+	x1, y1 := localXY(w2, w1)
+	x3, y3 := localXY(w2, w3)
+	return math.Abs(x1*y3-x3*y1) / 2
+}
+
+// localXY returns the position of w in meters in an equirectangular
+// projection centered on origin.
+func localXY(origin, w *WptType) (float64, float64) {
+	dLon := math.Remainder(w.Lon-origin.Lon, 360)
+	x := dLon * math.Pi / 180 * earthRadius * math.Cos(origin.Lat*math.Pi/180)
+	y := (w.Lat - origin.Lat) * math.Pi / 180 * earthRadius
+	return x, y
+}
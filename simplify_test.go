@@ -0,0 +1,111 @@
+package gpx_test
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	gpx "github.com/twpayne/go-gpx"
+)
+
+func newSimplifyTestTrkPts() []*gpx.WptType {
+	t0 := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	return []*gpx.WptType{
+		{Lat: 0, Lon: 0, Time: t0},
+		{Lat: 0, Lon: 0.001, Time: t0.Add(1 * time.Second)},
+		{Lat: 0.0001, Lon: 0.002, Time: t0.Add(2 * time.Second)},
+		{Lat: 0, Lon: 0.003, Time: t0.Add(3 * time.Second)},
+		{Lat: 0, Lon: 0.004, Time: t0.Add(4 * time.Second)},
+	}
+}
+
+func TestSimplifyDouglasPeucker(t *testing.T) {
+	for i, tc := range []struct {
+		tolerance       float64
+		expectedIndexes []int
+	}{
+		{
+			tolerance:       20,
+			expectedIndexes: []int{0, 4},
+		},
+		{
+			tolerance:       10,
+			expectedIndexes: []int{0, 2, 4},
+		},
+		{
+			tolerance:       1,
+			expectedIndexes: []int{0, 1, 2, 3, 4},
+		},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			trkPts := newSimplifyTestTrkPts()
+			trk := &gpx.TrkType{
+				Name: "name",
+				TrkSeg: []*gpx.TrkSegType{
+					{TrkPt: trkPts},
+				},
+			}
+			expected := make([]*gpx.WptType, 0, len(tc.expectedIndexes))
+			for _, index := range tc.expectedIndexes {
+				expected = append(expected, trkPts[index])
+			}
+			simplified := trk.SimplifyDouglasPeucker(tc.tolerance)
+			assert.Equal(t, "name", simplified.Name)
+			assert.Equal(t, 1, len(simplified.TrkSeg))
+			assert.Equal(t, len(expected), len(simplified.TrkSeg[0].TrkPt))
+			for j := range expected {
+				assert.True(t, expected[j] == simplified.TrkSeg[0].TrkPt[j])
+			}
+			assert.Equal(t, 5, len(trk.TrkSeg[0].TrkPt))
+
+			rte := &gpx.RteType{RtePt: trkPts}
+			assert.Equal(t, expected, rte.SimplifyDouglasPeucker(tc.tolerance).RtePt)
+		})
+	}
+}
+
+func TestSimplifyVisvalingamWhyatt(t *testing.T) {
+	for i, tc := range []struct {
+		minArea         float64
+		minPoints       int
+		expectedIndexes []int
+	}{
+		{
+			minArea:         100,
+			expectedIndexes: []int{0, 1, 2, 3, 4},
+		},
+		{
+			minArea:         1000,
+			expectedIndexes: []int{0, 2, 4},
+		},
+		{
+			minArea:         math.Inf(1),
+			expectedIndexes: []int{0, 4},
+		},
+		{
+			minArea:         math.Inf(1),
+			minPoints:       3,
+			expectedIndexes: []int{0, 2, 4},
+		},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			trkPts := newSimplifyTestTrkPts()
+			trkSeg := &gpx.TrkSegType{TrkPt: trkPts}
+			expected := make([]*gpx.WptType, 0, len(tc.expectedIndexes))
+			for _, index := range tc.expectedIndexes {
+				expected = append(expected, trkPts[index])
+			}
+			simplified := trkSeg.SimplifyVisvalingamWhyatt(tc.minArea, tc.minPoints)
+			assert.Equal(t, len(expected), len(simplified.TrkPt))
+			for j := range expected {
+				assert.True(t, expected[j] == simplified.TrkPt[j])
+			}
+
+			rte := &gpx.RteType{RtePt: trkPts}
+			assert.Equal(t, expected, rte.SimplifyVisvalingamWhyatt(tc.minArea, tc.minPoints).RtePt)
+		})
+	}
+}
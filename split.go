@@ -0,0 +1,140 @@
+package gpx
+
+import (
+	"strconv"
+	"time"
+)
+
+// A SplitOption sets a condition for splitting.
+type SplitOption func(*splitOptions)
+
+type splitOptions struct {
+	maxTimeGap     time.Duration
+	maxDistanceGap float64
+	location       *time.Location
+}
+
+// WithSplitTimeGap splits where consecutive points are more than maxTimeGap
+// apart in time.
+func WithSplitTimeGap(maxTimeGap time.Duration) SplitOption {
+	return func(o *splitOptions) {
+		o.maxTimeGap = maxTimeGap
+	}
+}
+
+// WithSplitDistanceGap splits where consecutive points are more than
+// maxDistanceGap meters apart.
+func WithSplitDistanceGap(maxDistanceGap float64) SplitOption {
+	return func(o *splitOptions) {
+		o.maxDistanceGap = maxDistanceGap
+	}
+}
+
+// WithSplitDays splits where consecutive points are on different days in loc.
+func WithSplitDays(loc *time.Location) SplitOption {
+	return func(o *splitOptions) {
+		o.location = loc
+	}
+}
+
+// Split returns ts split into new segments wherever a condition in options is
+// met between consecutive points. The new segments share ts's points and
+// extensions.
+func (ts *TrkSegType) Split(options ...SplitOption) []*TrkSegType {
+	o := newSplitOptions(options)
+	var trkSegs []*TrkSegType
+	start := 0
+	for i := 1; i <= len(ts.TrkPt); i++ {
+		if i < len(ts.TrkPt) && !o.split(ts.TrkPt[i-1], ts.TrkPt[i]) {
+			continue
+		}
+		trkSegs = append(trkSegs, &TrkSegType{
+			TrkPt:      ts.TrkPt[start:i:i],
+			Extensions: ts.Extensions,
+		})
+		start = i
+	}
+	if trkSegs == nil {
+		trkSegs = []*TrkSegType{
+			{
+				Extensions: ts.Extensions,
+			},
+		}
+	}
+	return trkSegs
+}
+
+// SplitSegments returns a copy of t with each segment split as
+// TrkSegType.Split.
+func (t *TrkType) SplitSegments(options ...SplitOption) *TrkType {
+	trk := *t
+	trk.TrkSeg = nil
+	for _, trkSeg := range t.TrkSeg {
+		trk.TrkSeg = append(trk.TrkSeg, trkSeg.Split(options...)...)
+	}
+	return &trk
+}
+
+// SplitTracks returns t split into new tracks wherever a condition in options
+// is met between consecutive points in a segment. Segments that are not split
+// stay in the same track. If t is split then the new tracks are copies of t
+// named "<name> (1)", "<name> (2)", and so on, or "1", "2", and so on if t
+// has no name.
+func (t *TrkType) SplitTracks(options ...SplitOption) []*TrkType {
+	var trkSegGroups [][]*TrkSegType
+	var current []*TrkSegType
+	for _, trkSeg := range t.TrkSeg {
+		for i, splitTrkSeg := range trkSeg.Split(options...) {
+			if i > 0 {
+				trkSegGroups = append(trkSegGroups, current)
+				current = nil
+			}
+			current = append(current, splitTrkSeg)
+		}
+	}
+	trkSegGroups = append(trkSegGroups, current)
+	if len(trkSegGroups) == 1 {
+		trk := *t
+		trk.TrkSeg = trkSegGroups[0]
+		return []*TrkType{&trk}
+	}
+	trks := make([]*TrkType, 0, len(trkSegGroups))
+	for i, trkSegs := range trkSegGroups {
+		trk := *t
+		if t.Name == "" {
+			trk.Name = strconv.Itoa(i + 1)
+		} else {
+			trk.Name = t.Name + " (" + strconv.Itoa(i+1) + ")"
+		}
+		trk.TrkSeg = trkSegs
+		trks = append(trks, &trk)
+	}
+	return trks
+}
+
+func newSplitOptions(options []SplitOption) *splitOptions {
+	o := &splitOptions{}
+	for _, option := range options {
+		option(o)
+	}
+	return o
+}
+
+// split returns whether to split between w1 and w2.
+func (o *splitOptions) split(w1, w2 *WptType) bool {
+	if o.maxDistanceGap > 0 && w1.Distance(w2) > o.maxDistanceGap {
+		return true
+	}
+	if w1.Time.IsZero() || w2.Time.IsZero() {
+		return false
+	}
+	if o.maxTimeGap > 0 && w2.Time.Sub(w1.Time) > o.maxTimeGap {
+		return true
+	}
+	if o.location != nil {
+		y1, m1, d1 := w1.Time.In(o.location).Date()
+		y2, m2, d2 := w2.Time.In(o.location).Date()
+		return y1 != y2 || m1 != m2 || d1 != d2
+	}
+	return false
+}
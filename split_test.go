@@ -0,0 +1,109 @@
+package gpx_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	gpx "github.com/twpayne/go-gpx"
+)
+
+func TestTrkSegTypeSplit(t *testing.T) {
+	t0 := time.Date(2024, 6, 1, 21, 0, 0, 0, time.UTC)
+	trkPts := []*gpx.WptType{
+		{Lon: 0, Time: t0},
+		{Lon: 0.001, Time: t0.Add(time.Minute)},
+		{Lon: 0.002, Time: t0.Add(2 * time.Hour)},
+		{Lon: 0.1, Time: t0.Add(2*time.Hour + time.Minute)},
+		{Lon: 0.101, Time: t0.Add(4 * time.Hour)},
+	}
+	newYork := time.FixedZone("EDT", -4*60*60)
+
+	for i, tc := range []struct {
+		options      []gpx.SplitOption
+		expectedLens []int
+	}{
+		{
+			expectedLens: []int{5},
+		},
+		{
+			options:      []gpx.SplitOption{gpx.WithSplitTimeGap(time.Hour)},
+			expectedLens: []int{2, 2, 1},
+		},
+		{
+			options:      []gpx.SplitOption{gpx.WithSplitDistanceGap(1000)},
+			expectedLens: []int{3, 2},
+		},
+		{
+			options:      []gpx.SplitOption{gpx.WithSplitTimeGap(time.Hour), gpx.WithSplitDistanceGap(1000)},
+			expectedLens: []int{2, 1, 1, 1},
+		},
+		{
+			options:      []gpx.SplitOption{gpx.WithSplitDays(time.UTC)},
+			expectedLens: []int{4, 1},
+		},
+		{
+			options:      []gpx.SplitOption{gpx.WithSplitDays(newYork)},
+			expectedLens: []int{5},
+		},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			trkSegs := (&gpx.TrkSegType{TrkPt: trkPts}).Split(tc.options...)
+			lens := make([]int, 0, len(trkSegs))
+			var got []*gpx.WptType
+			for _, trkSeg := range trkSegs {
+				lens = append(lens, len(trkSeg.TrkPt))
+				got = append(got, trkSeg.TrkPt...)
+			}
+			assert.Equal(t, tc.expectedLens, lens)
+			assert.Equal(t, trkPts, got)
+		})
+	}
+}
+
+func TestTrkTypeSplit(t *testing.T) {
+	t0 := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	trk := &gpx.TrkType{
+		Name: "week",
+		Type: "hiking",
+		TrkSeg: []*gpx.TrkSegType{
+			{
+				TrkPt: []*gpx.WptType{
+					{Time: t0},
+					{Time: t0.Add(time.Minute)},
+				},
+			},
+			{
+				TrkPt: []*gpx.WptType{
+					{Time: t0.Add(10 * time.Minute)},
+					{Time: t0.Add(24 * time.Hour)},
+				},
+			},
+		},
+	}
+
+	splitSegments := trk.SplitSegments(gpx.WithSplitTimeGap(time.Hour))
+	assert.Equal(t, "week", splitSegments.Name)
+	assert.Equal(t, 3, len(splitSegments.TrkSeg))
+	assert.Equal(t, 2, len(trk.TrkSeg))
+
+	trks := trk.SplitTracks(gpx.WithSplitTimeGap(time.Hour))
+	assert.Equal(t, 2, len(trks))
+	assert.Equal(t, "week (1)", trks[0].Name)
+	assert.Equal(t, "hiking", trks[0].Type)
+	assert.Equal(t, 2, len(trks[0].TrkSeg))
+	assert.Equal(t, "week (2)", trks[1].Name)
+	assert.Equal(t, 1, len(trks[1].TrkSeg))
+	assert.Equal(t, t0.Add(24*time.Hour), trks[1].TrkSeg[0].TrkPt[0].Time)
+
+	trks = trk.SplitTracks(gpx.WithSplitTimeGap(48 * time.Hour))
+	assert.Equal(t, 1, len(trks))
+	assert.Equal(t, "week", trks[0].Name)
+
+	trk.Name = ""
+	trks = trk.SplitTracks(gpx.WithSplitTimeGap(time.Hour))
+	assert.Equal(t, "1", trks[0].Name)
+	assert.Equal(t, "2", trks[1].Name)
+}
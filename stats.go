@@ -0,0 +1,159 @@
+package gpx
+
+import (
+	"math"
+	"time"
+)
+
+// earthRadius is the mean radius of the Earth in meters.
+const earthRadius = 6371008.8
+
+// defaultStopSpeed is the default speed in meters per second below which a
+// point is considered stopped.
+const defaultStopSpeed = 0.5
+
+// Stats are statistics of a track, track segment, or route. Distances are in
+// meters and speeds are in meters per second.
+type Stats struct {
+	Distance2D     float64
+	Distance3D     float64
+	Duration       time.Duration
+	MovingTime     time.Duration
+	AvgMovingSpeed float64
+	MaxSpeed       float64
+	StartTime      time.Time
+	EndTime        time.Time
+	Points         int
+	Segments       int
+}
+
+// A StatsOption sets an option for computing Stats.
+type StatsOption func(*statsOptions)
+
+type statsOptions struct {
+	stopSpeed float64
+}
+
+// statsAccumulator accumulates Stats over segments.
+type statsAccumulator struct {
+	o              statsOptions
+	stats          Stats
+	movingDistance float64
+}
+
+// WithStopSpeed sets the speed in meters per second below which the time
+// between two points is not counted as moving time. The default is 0.5m/s.
+func WithStopSpeed(stopSpeed float64) StatsOption {
+	return func(o *statsOptions) {
+		o.stopSpeed = stopSpeed
+	}
+}
+
+// Distance returns the great circle distance between w and other in meters.
+func (w *WptType) Distance(other *WptType) float64 {
+	return haversineDistance(w.Lat, w.Lon, other.Lat, other.Lon)
+}
+
+// Stats returns statistics of ts.
+func (ts *TrkSegType) Stats(options ...StatsOption) *Stats {
+	a := newStatsAccumulator(options)
+	a.addSegment(ts.TrkPt)
+	return a.result()
+}
+
+// Stats returns statistics of t. Distance and moving time are only
+// accumulated within segments, so gaps between segments are excluded. The
+// duration is the total elapsed time from the first point to the last point.
+func (t *TrkType) Stats(options ...StatsOption) *Stats {
+	a := newStatsAccumulator(options)
+	for _, trkSeg := range t.TrkSeg {
+		a.addSegment(trkSeg.TrkPt)
+	}
+	return a.result()
+}
+
+// Stats returns statistics of r, treating its points as a single segment.
+func (r *RteType) Stats(options ...StatsOption) *Stats {
+	a := newStatsAccumulator(options)
+	a.addSegment(r.RtePt)
+	return a.result()
+}
+
+func newStatsAccumulator(options []StatsOption) *statsAccumulator {
+	a := &statsAccumulator{
+		o: statsOptions{
+			stopSpeed: defaultStopSpeed,
+		},
+	}
+	for _, option := range options {
+		option(&a.o)
+	}
+	return a
+}
+
+func (a *statsAccumulator) addSegment(wpts []*WptType) {
+	a.stats.Segments++
+	a.stats.Points += len(wpts)
+	for i, wpt := range wpts {
+		if !wpt.Time.IsZero() {
+			if a.stats.StartTime.IsZero() || wpt.Time.Before(a.stats.StartTime) {
+				a.stats.StartTime = wpt.Time
+			}
+			if a.stats.EndTime.IsZero() || wpt.Time.After(a.stats.EndTime) {
+				a.stats.EndTime = wpt.Time
+			}
+		}
+		if i == 0 {
+			continue
+		}
+		prev := wpts[i-1]
+		distance2D := prev.Distance(wpt)
+		a.stats.Distance2D += distance2D
+		a.stats.Distance3D += distance3D(prev, wpt, distance2D)
+		if prev.Time.IsZero() || wpt.Time.IsZero() {
+			continue
+		}
+		dt := wpt.Time.Sub(prev.Time)
+		if dt <= 0 {
+			continue
+		}
+		speed := distance2D / dt.Seconds()
+		if speed < a.o.stopSpeed {
+			continue
+		}
+		a.stats.MovingTime += dt
+		a.movingDistance += distance2D
+		a.stats.MaxSpeed = max(a.stats.MaxSpeed, speed)
+	}
+}
+
+func (a *statsAccumulator) result() *Stats {
+	stats := a.stats
+	if !stats.StartTime.IsZero() {
+		stats.Duration = stats.EndTime.Sub(stats.StartTime)
+	}
+	if stats.MovingTime > 0 {
+		stats.AvgMovingSpeed = a.movingDistance / stats.MovingTime.Seconds()
+	}
+	return &stats
+}
+
+// distance3D returns the distance between w1 and w2 including the change in
+// elevation, if both have an elevation, given their 2D distance.
+func distance3D(w1, w2 *WptType, distance2D float64) float64 {
+	if !w1.Has(WptEle) || !w2.Has(WptEle) {
+		return distance2D
+	}
+	return math.Hypot(distance2D, w2.Ele-w1.Ele)
+}
+
+// haversineDistance returns the great circle distance between two points in
+// meters.
+func haversineDistance(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1 := lat1 * math.Pi / 180
+	phi2 := lat2 * math.Pi / 180
+	sinDPhi := math.Sin((phi2 - phi1) / 2)
+	sinDLambda := math.Sin((lon2 - lon1) * math.Pi / 180 / 2)
+	h := sinDPhi*sinDPhi + math.Cos(phi1)*math.Cos(phi2)*sinDLambda*sinDLambda
+	return 2 * earthRadius * math.Asin(math.Sqrt(min(h, 1)))
+}
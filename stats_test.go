@@ -0,0 +1,124 @@
+package gpx_test
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	gpx "github.com/twpayne/go-gpx"
+)
+
+// metersPerDegree is the length of one degree of a great circle in meters.
+const metersPerDegree = 2 * math.Pi * 6371008.8 / 360
+
+func assertInDelta(t *testing.T, expected, actual, delta float64) {
+	t.Helper()
+	assert.True(t, math.Abs(expected-actual) <= delta, "expected %v, got %v", expected, actual)
+}
+
+func TestWptTypeDistance(t *testing.T) {
+	for i, tc := range []struct {
+		w1, w2   *gpx.WptType
+		expected float64
+	}{
+		{
+			w1:       &gpx.WptType{},
+			w2:       &gpx.WptType{},
+			expected: 0,
+		},
+		{
+			w1:       &gpx.WptType{},
+			w2:       &gpx.WptType{Lon: 1},
+			expected: metersPerDegree,
+		},
+		{
+			w1:       &gpx.WptType{Lat: 90},
+			w2:       &gpx.WptType{Lat: -90},
+			expected: 180 * metersPerDegree,
+		},
+		{
+			w1:       &gpx.WptType{Lon: 179.5},
+			w2:       &gpx.WptType{Lon: -179.5},
+			expected: metersPerDegree,
+		},
+		{
+			w1:       &gpx.WptType{Lat: 51.5007, Lon: -0.1246},
+			w2:       &gpx.WptType{Lat: 40.6892, Lon: -74.0445},
+			expected: 5574848,
+		},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assertInDelta(t, tc.expected, tc.w1.Distance(tc.w2), 1)
+		})
+	}
+}
+
+func TestTrkTypeStats(t *testing.T) {
+	t0 := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	trk := &gpx.TrkType{
+		TrkSeg: []*gpx.TrkSegType{
+			{
+				TrkPt: []*gpx.WptType{
+					{Lon: 0, Ele: 0, Time: t0, Present: gpx.WptEle},
+					{Lon: 0.001, Ele: 10, Time: t0.Add(10 * time.Second)},
+					{Lon: 0.001, Ele: 10, Time: t0.Add(70 * time.Second)},
+					{Lon: 0.003, Ele: 10, Time: t0.Add(90 * time.Second)},
+				},
+			},
+			{
+				TrkPt: []*gpx.WptType{
+					{Lon: 1, Time: t0.Add(time.Hour)},
+					{Lon: 1.001, Time: t0.Add(time.Hour + 20*time.Second)},
+				},
+			},
+		},
+	}
+	stats := trk.Stats()
+	d := 0.001 * metersPerDegree
+	assertInDelta(t, 4*d, stats.Distance2D, 1e-6)
+	assertInDelta(t, math.Hypot(d, 10)+3*d, stats.Distance3D, 1e-6)
+	assert.Equal(t, time.Hour+20*time.Second, stats.Duration)
+	assert.Equal(t, 50*time.Second, stats.MovingTime)
+	assertInDelta(t, 4*d/50, stats.AvgMovingSpeed, 1e-9)
+	assertInDelta(t, 2*d/20, stats.MaxSpeed, 1e-9)
+	assert.Equal(t, t0, stats.StartTime)
+	assert.Equal(t, t0.Add(time.Hour+20*time.Second), stats.EndTime)
+	assert.Equal(t, 6, stats.Points)
+	assert.Equal(t, 2, stats.Segments)
+
+	stats = trk.Stats(gpx.WithStopSpeed(10))
+	assert.Equal(t, 30*time.Second, stats.MovingTime)
+	assertInDelta(t, 3*d/30, stats.AvgMovingSpeed, 1e-9)
+}
+
+func TestTrkSegTypeStats(t *testing.T) {
+	assert.Equal(t, &gpx.Stats{Segments: 1}, (&gpx.TrkSegType{}).Stats())
+	stats := (&gpx.TrkSegType{
+		TrkPt: []*gpx.WptType{
+			{Lat: 0},
+			{Lat: 0.001},
+		},
+	}).Stats()
+	assertInDelta(t, 0.001*metersPerDegree, stats.Distance2D, 1e-6)
+	assert.Equal(t, stats.Distance2D, stats.Distance3D)
+	assert.Zero(t, stats.Duration)
+	assert.Zero(t, stats.MovingTime)
+	assert.Equal(t, 2, stats.Points)
+}
+
+func TestRteTypeStats(t *testing.T) {
+	t0 := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	stats := (&gpx.RteType{
+		RtePt: []*gpx.WptType{
+			{Time: t0},
+			{Lon: 0.01, Time: t0.Add(time.Minute)},
+		},
+	}).Stats()
+	assertInDelta(t, 0.01*metersPerDegree, stats.Distance2D, 1e-6)
+	assert.Equal(t, time.Minute, stats.Duration)
+	assert.Equal(t, time.Minute, stats.MovingTime)
+	assert.Equal(t, 1, stats.Segments)
+}
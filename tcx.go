@@ -0,0 +1,642 @@
+package gpx
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// TCX namespaces.
+const (
+	TCXNamespace                 = "http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2"
+	ActivityExtensionV2Namespace = "http://www.garmin.com/xmlschemas/ActivityExtension/v2"
+)
+
+// TCXExtensionV1Namespace is the namespace of TCXExtensions. It is a private
+// namespace with no published schema that is only understood by this package,
+// and it may change.
+const TCXExtensionV1Namespace = "https://github.com/twpayne/go-gpx/xmlschemas/TCXExtension/v1"
+
+var tcxExtensionNamespaces = []string{
+	TCXExtensionV1Namespace,
+}
+
+// tcxSports are the sports allowed in TCX activities.
+var tcxSports = []string{"Running", "Biking", "Other"}
+
+// tcxCoursePointTypes are the types allowed in TCX course points.
+var tcxCoursePointTypes = []string{
+	"Generic", "Summit", "Valley", "Water", "Food", "Danger", "Left", "Right",
+	"Straight", "First Aid", "4th Category", "3rd Category", "2nd Category",
+	"1st Category", "Hors Category", "Sprint",
+}
+
+// A TCXExtension contains the values of a TCX trackpoint that have no
+// equivalent in GPX.
+type TCXExtension struct {
+	DistanceMeters float64 `xml:"DistanceMeters"`
+}
+
+type tcxTrainingCenterDatabase struct {
+	Activities []*tcxActivity `xml:"Activities>Activity"`
+	Courses    []*tcxCourse   `xml:"Courses>Course"`
+}
+
+type tcxActivity struct {
+	Sport string    `xml:"Sport,attr"`
+	Laps  []*tcxLap `xml:"Lap"`
+	Notes string    `xml:"Notes"`
+}
+
+type tcxLap struct {
+	Tracks []*tcxTrack `xml:"Track"`
+}
+
+type tcxCourse struct {
+	Name         string            `xml:"Name"`
+	Tracks       []*tcxTrack       `xml:"Track"`
+	Notes        string            `xml:"Notes"`
+	CoursePoints []*tcxCoursePoint `xml:"CoursePoint"`
+}
+
+type tcxTrack struct {
+	Trackpoints []*tcxTrackpoint `xml:"Trackpoint"`
+}
+
+type tcxTrackpoint struct {
+	Time           string       `xml:"Time"`
+	Position       *tcxPosition `xml:"Position"`
+	AltitudeMeters *float64     `xml:"AltitudeMeters"`
+	DistanceMeters *float64     `xml:"DistanceMeters"`
+	HeartRateBpm   int          `xml:"HeartRateBpm>Value"`
+	Cadence        *int         `xml:"Cadence"`
+	TPX            *tcxTPX      `xml:"Extensions>TPX"`
+}
+
+type tcxTPX struct {
+	Speed      *float64 `xml:"Speed"`
+	RunCadence *int     `xml:"RunCadence"`
+	Watts      int      `xml:"Watts"`
+}
+
+type tcxCoursePoint struct {
+	Name           string       `xml:"Name"`
+	Time           string       `xml:"Time"`
+	Position       *tcxPosition `xml:"Position"`
+	AltitudeMeters *float64     `xml:"AltitudeMeters"`
+	PointType      string       `xml:"PointType"`
+	Notes          string       `xml:"Notes"`
+}
+
+type tcxPosition struct {
+	LatitudeDegrees  float64 `xml:"LatitudeDegrees"`
+	LongitudeDegrees float64 `xml:"LongitudeDegrees"`
+}
+
+// ReadTCX reads a GPX from the TCX document in r. Each activity becomes a
+// track, with its sport as the track's type and its notes as the track's
+// description, and each track in each of its laps becomes a track segment.
+// Heart rate, cadence, and speed are stored in each point's Garmin
+// TrackPointExtension, power in its Garmin PowerExtension, and distance in its
+// TCXExtension. Each course becomes a route and course points become
+// waypoints. Trackpoints without a position are skipped.
+func ReadTCX(r io.Reader) (*GPX, error) {
+	var tcx tcxTrainingCenterDatabase
+	if err := xml.NewDecoder(r).Decode(&tcx); err != nil {
+		return nil, err
+	}
+	g := &GPX{
+		Version: "1.1",
+	}
+	for i, activity := range tcx.Activities {
+		trk := &TrkType{
+			Desc: activity.Notes,
+			Type: activity.Sport,
+		}
+		for _, lap := range activity.Laps {
+			for _, track := range lap.Tracks {
+				trkPts, err := track.wptTypes()
+				if err != nil {
+					return nil, fmt.Errorf("activity %d: %w", i, err)
+				}
+				if len(trkPts) > 0 {
+					trk.TrkSeg = append(trk.TrkSeg, &TrkSegType{
+						TrkPt: trkPts,
+					})
+				}
+			}
+		}
+		g.Trk = append(g.Trk, trk)
+	}
+	for i, course := range tcx.Courses {
+		rte := &RteType{
+			Name: course.Name,
+			Desc: course.Notes,
+		}
+		for _, track := range course.Tracks {
+			rtePts, err := track.wptTypes()
+			if err != nil {
+				return nil, fmt.Errorf("course %d: %w", i, err)
+			}
+			rte.RtePt = append(rte.RtePt, rtePts...)
+		}
+		g.Rte = append(g.Rte, rte)
+		for _, coursePoint := range course.CoursePoints {
+			if coursePoint.Position == nil {
+				continue
+			}
+			wpt, err := coursePoint.wptType()
+			if err != nil {
+				return nil, fmt.Errorf("course %d: %w", i, err)
+			}
+			g.Wpt = append(g.Wpt, wpt)
+		}
+	}
+	return g, nil
+}
+
+// WriteTCX writes g to w as a TCX document. Each track becomes an activity
+// and each of its segments a lap. The activity's sport is the track's type if
+// it is Running or Biking, and Other otherwise. Lap durations and distances
+// are calculated from the points, ignoring the gaps between segments.
+// Trackpoint distances are taken from each point's TCXExtension, if any, and
+// otherwise calculated from the previous point. Each route becomes a course,
+// and waypoints become course points of the first course. If there are
+// waypoints but no routes then a course without a track, named after the
+// metadata, is written to hold them. Tracks and segments without points are
+// skipped. TCX requires times, so all track points, route points, and
+// waypoints must have a time.
+func (g *GPX) WriteTCX(w io.Writer) error {
+	trks := slices.DeleteFunc(slices.Clone(g.Trk), func(trk *TrkType) bool {
+		return !slices.ContainsFunc(trk.TrkSeg, func(trkSeg *TrkSegType) bool {
+			return len(trkSeg.TrkPt) > 0
+		})
+	})
+	var wpts []*WptType
+	for _, trk := range trks {
+		for _, trkSeg := range trk.TrkSeg {
+			wpts = append(wpts, trkSeg.TrkPt...)
+		}
+	}
+	for _, rte := range g.Rte {
+		wpts = append(wpts, rte.RtePt...)
+	}
+	wpts = append(wpts, g.Wpt...)
+	for _, wpt := range wpts {
+		if wpt.Time.IsZero() {
+			return errMissingTime
+		}
+	}
+	e := xml.NewEncoder(w)
+	start := xml.StartElement{
+		Name: xml.Name{Local: "TrainingCenterDatabase"},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "xmlns"}, Value: TCXNamespace},
+			{Name: xml.Name{Local: "xmlns:ns3"}, Value: ActivityExtensionV2Namespace},
+		},
+	}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if len(trks) > 0 {
+		activitiesStart := xml.StartElement{Name: xml.Name{Local: "Activities"}}
+		if err := e.EncodeToken(activitiesStart); err != nil {
+			return err
+		}
+		for _, trk := range trks {
+			if err := trk.encodeTCXActivity(e); err != nil {
+				return err
+			}
+		}
+		if err := e.EncodeToken(activitiesStart.End()); err != nil {
+			return err
+		}
+	}
+	if len(g.Rte) > 0 || len(g.Wpt) > 0 {
+		coursesStart := xml.StartElement{Name: xml.Name{Local: "Courses"}}
+		if err := e.EncodeToken(coursesStart); err != nil {
+			return err
+		}
+		rtes := g.Rte
+		if len(rtes) == 0 {
+			rte := &RteType{}
+			if g.Metadata != nil {
+				rte.Name = g.Metadata.Name
+			}
+			rtes = []*RteType{rte}
+		}
+		for i, rte := range rtes {
+			var coursePoints []*WptType
+			if i == 0 {
+				coursePoints = g.Wpt
+			}
+			if err := rte.encodeTCXCourse(e, coursePoints); err != nil {
+				return err
+			}
+		}
+		if err := e.EncodeToken(coursesStart.End()); err != nil {
+			return err
+		}
+	}
+	if err := e.EncodeToken(start.End()); err != nil {
+		return err
+	}
+	return e.Close()
+}
+
+// encodeTCXCourse writes r as a TCX course with coursePoints to e.
+func (r *RteType) encodeTCXCourse(e *xml.Encoder, coursePoints []*WptType) error {
+	start := xml.StartElement{Name: xml.Name{Local: "Course"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := emitStringElement(e, "Name", r.Name); err != nil {
+		return err
+	}
+	lapStart := xml.StartElement{Name: xml.Name{Local: "Lap"}}
+	if err := e.EncodeToken(lapStart); err != nil {
+		return err
+	}
+	if err := encodeTCXLapTotals(e, r.RtePt); err != nil {
+		return err
+	}
+	if len(r.RtePt) > 0 {
+		if err := r.RtePt[0].encodeTCXPosition(e, "BeginPosition"); err != nil {
+			return err
+		}
+		if err := r.RtePt[len(r.RtePt)-1].encodeTCXPosition(e, "EndPosition"); err != nil {
+			return err
+		}
+	}
+	if err := emitStringElement(e, "Intensity", "Active"); err != nil {
+		return err
+	}
+	if err := e.EncodeToken(lapStart.End()); err != nil {
+		return err
+	}
+	if len(r.RtePt) > 0 {
+		if _, err := encodeTCXTrack(e, r.RtePt, 0); err != nil {
+			return err
+		}
+	}
+	if err := maybeEmitStringElement(e, "Notes", r.Desc); err != nil {
+		return err
+	}
+	for _, coursePoint := range coursePoints {
+		if err := coursePoint.encodeTCXCoursePoint(e); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// encodeTCXActivity writes t, which must have at least one point, as a TCX
+// activity to e.
+func (t *TrkType) encodeTCXActivity(e *xml.Encoder) error {
+	sport := "Other"
+	if slices.Contains(tcxSports, t.Type) {
+		sport = t.Type
+	}
+	start := xml.StartElement{
+		Name: xml.Name{Local: "Activity"},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "Sport"}, Value: sport},
+		},
+	}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	trkSegs := slices.DeleteFunc(slices.Clone(t.TrkSeg), func(trkSeg *TrkSegType) bool {
+		return len(trkSeg.TrkPt) == 0
+	})
+	if err := emitStringElement(e, "Id", trkSegs[0].TrkPt[0].Time.Format(time.RFC3339Nano)); err != nil {
+		return err
+	}
+	distance := 0.0
+	for _, trkSeg := range trkSegs {
+		lapStart := xml.StartElement{
+			Name: xml.Name{Local: "Lap"},
+			Attr: []xml.Attr{
+				{Name: xml.Name{Local: "StartTime"}, Value: trkSeg.TrkPt[0].Time.Format(time.RFC3339Nano)},
+			},
+		}
+		if err := e.EncodeToken(lapStart); err != nil {
+			return err
+		}
+		if err := encodeTCXLapTotals(e, trkSeg.TrkPt); err != nil {
+			return err
+		}
+		if err := emitIntElement(e, "Calories", 0); err != nil {
+			return err
+		}
+		if err := emitStringElement(e, "Intensity", "Active"); err != nil {
+			return err
+		}
+		if err := emitStringElement(e, "TriggerMethod", "Manual"); err != nil {
+			return err
+		}
+		var err error
+		if distance, err = encodeTCXTrack(e, trkSeg.TrkPt, distance); err != nil {
+			return err
+		}
+		if err := e.EncodeToken(lapStart.End()); err != nil {
+			return err
+		}
+	}
+	if err := maybeEmitStringElement(e, "Notes", t.Desc); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+// wptTypes returns the trackpoints in t that have a position.
+func (t *tcxTrack) wptTypes() ([]*WptType, error) {
+	wpts := make([]*WptType, 0, len(t.Trackpoints))
+	for _, trackpoint := range t.Trackpoints {
+		if trackpoint.Position == nil {
+			continue
+		}
+		wpt, err := trackpoint.wptType()
+		if err != nil {
+			return nil, err
+		}
+		wpts = append(wpts, wpt)
+	}
+	return wpts, nil
+}
+
+// wptType returns a new WptType from t, which must have a position.
+func (t *tcxTrackpoint) wptType() (*WptType, error) {
+	w := &WptType{
+		Lat: t.Position.LatitudeDegrees,
+		Lon: t.Position.LongitudeDegrees,
+	}
+	if t.AltitudeMeters != nil {
+		w.Ele = *t.AltitudeMeters
+		w.Present |= WptEle
+	}
+	if t.Time != "" {
+		var err error
+		if w.Time, err = parseDefaultTime(t.Time); err != nil {
+			return nil, err
+		}
+	}
+	if t.DistanceMeters != nil {
+		if err := w.SetTCXExtension(&TCXExtension{DistanceMeters: *t.DistanceMeters}); err != nil {
+			return nil, err
+		}
+	}
+	tpe := &TrackPointExtension{
+		Version: 2,
+		HR:      t.HeartRateBpm,
+	}
+	if t.Cadence != nil {
+		tpe.Cad = *t.Cadence
+		tpe.Present |= TrackPointExtensionCad
+	}
+	if t.TPX != nil {
+		if t.TPX.Speed != nil {
+			tpe.Speed = *t.TPX.Speed
+			tpe.Present |= TrackPointExtensionSpeed
+		}
+		if t.TPX.RunCadence != nil && !tpe.Has(TrackPointExtensionCad) {
+			tpe.Cad = *t.TPX.RunCadence
+			tpe.Present |= TrackPointExtensionCad
+		}
+		if t.TPX.Watts != 0 {
+			if err := w.SetPowerExtension(&PowerExtension{PowerInWatts: t.TPX.Watts}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if tpe.Has(TrackPointExtensionHR) || tpe.Has(TrackPointExtensionCad) || tpe.Has(TrackPointExtensionSpeed) {
+		if err := w.SetTrackPointExtension(tpe); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// TCXExtension returns w's TCXExtension, or nil if w does not have one.
+func (w *WptType) TCXExtension() (*TCXExtension, error) {
+	te := &TCXExtension{}
+	if _, ok, err := w.Extensions.decodeElement(tcxExtensionNamespaces, "TCXExtension", te); err != nil || !ok {
+		return nil, err
+	}
+	return te, nil
+}
+
+// SetTCXExtension sets w's TCXExtension to te. If te is nil then any existing
+// TCXExtension is removed.
+func (w *WptType) SetTCXExtension(te *TCXExtension) error {
+	if te == nil {
+		return setElement(&w.Extensions, tcxExtensionNamespaces, "TCXExtension", nil)
+	}
+	return setElement(&w.Extensions, tcxExtensionNamespaces, "TCXExtension", te.encode)
+}
+
+func (te *TCXExtension) encode(e *xml.Encoder, extensions *ExtensionsType) error {
+	prefix := extensions.prefix(TCXExtensionV1Namespace, "tcx")
+	start := xml.StartElement{Name: xml.Name{Local: prefix + ":TCXExtension"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := emitFloatElement(e, prefix+":DistanceMeters", te.DistanceMeters); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+// wptType returns a new WptType from c, which must have a position.
+func (c *tcxCoursePoint) wptType() (*WptType, error) {
+	w := &WptType{
+		Lat:  c.Position.LatitudeDegrees,
+		Lon:  c.Position.LongitudeDegrees,
+		Name: c.Name,
+		Desc: c.Notes,
+		Type: c.PointType,
+	}
+	if c.AltitudeMeters != nil {
+		w.Ele = *c.AltitudeMeters
+		w.Present |= WptEle
+	}
+	if c.Time != "" {
+		var err error
+		if w.Time, err = parseDefaultTime(c.Time); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// encodeTCXCoursePoint writes w as a TCX course point to e.
+func (w *WptType) encodeTCXCoursePoint(e *xml.Encoder) error {
+	start := xml.StartElement{Name: xml.Name{Local: "CoursePoint"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := emitStringElement(e, "Name", w.Name); err != nil {
+		return err
+	}
+	if err := emitStringElement(e, "Time", w.Time.Format(time.RFC3339Nano)); err != nil {
+		return err
+	}
+	if err := w.encodeTCXPosition(e, "Position"); err != nil {
+		return err
+	}
+	if w.Has(WptEle) {
+		if err := emitFloatElement(e, "AltitudeMeters", w.Ele); err != nil {
+			return err
+		}
+	}
+	pointType := "Generic"
+	if slices.Contains(tcxCoursePointTypes, w.Type) {
+		pointType = w.Type
+	}
+	if err := emitStringElement(e, "PointType", pointType); err != nil {
+		return err
+	}
+	if err := maybeEmitStringElement(e, "Notes", w.Desc); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+// encodeTCXPosition writes w's position as an element called localName to e.
+func (w *WptType) encodeTCXPosition(e *xml.Encoder, localName string) error {
+	start := xml.StartElement{Name: xml.Name{Local: localName}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := emitFloatElement(e, "LatitudeDegrees", w.Lat); err != nil {
+		return err
+	}
+	if err := emitFloatElement(e, "LongitudeDegrees", w.Lon); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+// encodeTCXTrackpoint writes w as a TCX trackpoint at distance meters to e.
+func (w *WptType) encodeTCXTrackpoint(e *xml.Encoder, distance float64) error {
+	start := xml.StartElement{Name: xml.Name{Local: "Trackpoint"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := emitStringElement(e, "Time", w.Time.Format(time.RFC3339Nano)); err != nil {
+		return err
+	}
+	if err := w.encodeTCXPosition(e, "Position"); err != nil {
+		return err
+	}
+	if w.Has(WptEle) {
+		if err := emitFloatElement(e, "AltitudeMeters", w.Ele); err != nil {
+			return err
+		}
+	}
+	if err := emitStringElement(e, "DistanceMeters", formatTCXFloat(distance)); err != nil {
+		return err
+	}
+	tpe, err := w.TrackPointExtension()
+	if err != nil {
+		return err
+	}
+	if tpe == nil {
+		tpe = &TrackPointExtension{}
+	}
+	if tpe.Has(TrackPointExtensionHR) {
+		heartRateBpmStart := xml.StartElement{Name: xml.Name{Local: "HeartRateBpm"}}
+		if err := e.EncodeToken(heartRateBpmStart); err != nil {
+			return err
+		}
+		if err := emitIntElement(e, "Value", tpe.HR); err != nil {
+			return err
+		}
+		if err := e.EncodeToken(heartRateBpmStart.End()); err != nil {
+			return err
+		}
+	}
+	if err := tpe.maybeEmitIntField(e, TrackPointExtensionCad, "Cadence", tpe.Cad); err != nil {
+		return err
+	}
+	pe, err := w.PowerExtension()
+	if err != nil {
+		return err
+	}
+	if pe == nil {
+		pe = &PowerExtension{}
+	}
+	if tpe.Has(TrackPointExtensionSpeed) || pe.PowerInWatts != 0 {
+		extensionsStart := xml.StartElement{Name: xml.Name{Local: "Extensions"}}
+		if err := e.EncodeToken(extensionsStart); err != nil {
+			return err
+		}
+		tpxStart := xml.StartElement{Name: xml.Name{Local: "ns3:TPX"}}
+		if err := e.EncodeToken(tpxStart); err != nil {
+			return err
+		}
+		if err := tpe.maybeEmitFloatField(e, TrackPointExtensionSpeed, "ns3:Speed", tpe.Speed); err != nil {
+			return err
+		}
+		if err := maybeEmitIntElement(e, "ns3:Watts", pe.PowerInWatts); err != nil {
+			return err
+		}
+		if err := e.EncodeToken(tpxStart.End()); err != nil {
+			return err
+		}
+		if err := e.EncodeToken(extensionsStart.End()); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// encodeTCXLapTotals writes the total time and distance of wpts to e.
+func encodeTCXLapTotals(e *xml.Encoder, wpts []*WptType) error {
+	totalTime, distance := 0.0, 0.0
+	if len(wpts) > 0 {
+		totalTime = wpts[len(wpts)-1].Time.Sub(wpts[0].Time).Seconds()
+	}
+	for i := 1; i < len(wpts); i++ {
+		distance += wpts[i-1].Distance(wpts[i])
+	}
+	if err := emitStringElement(e, "TotalTimeSeconds", formatTCXFloat(totalTime)); err != nil {
+		return err
+	}
+	return emitStringElement(e, "DistanceMeters", formatTCXFloat(distance))
+}
+
+// encodeTCXTrack writes wpts as a TCX track to e, starting at distance
+// meters, and returns the distance at the last point. The distance at points
+// with a TCXExtension is the distance in their TCXExtension.
+func encodeTCXTrack(e *xml.Encoder, wpts []*WptType, distance float64) (float64, error) {
+	start := xml.StartElement{Name: xml.Name{Local: "Track"}}
+	if err := e.EncodeToken(start); err != nil {
+		return 0, err
+	}
+	for i, wpt := range wpts {
+		te, err := wpt.TCXExtension()
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case te != nil:
+			distance = te.DistanceMeters
+		case i > 0:
+			distance += wpts[i-1].Distance(wpt)
+		}
+		if err := wpt.encodeTCXTrackpoint(e, distance); err != nil {
+			return 0, err
+		}
+	}
+	return distance, e.EncodeToken(start.End())
+}
+
+// formatTCXFloat formats f with at most two decimal places.
+func formatTCXFloat(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
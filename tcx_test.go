@@ -0,0 +1,303 @@
+package gpx_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	gpx "github.com/twpayne/go-gpx"
+)
+
+func TestTCX(t *testing.T) {
+	t0 := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	newRtePt := func(lat, lon float64, dt time.Duration, distance float64) *gpx.WptType {
+		rtePt := &gpx.WptType{Lat: lat, Lon: lon, Time: t0.Add(dt)}
+		assert.NoError(t, rtePt.SetTCXExtension(&gpx.TCXExtension{DistanceMeters: distance}))
+		return rtePt
+	}
+	newTrkPt := func(lat, lon float64, dt time.Duration, distance float64, hr, cad, watts int, speed float64) *gpx.WptType {
+		trkPt := &gpx.WptType{Lat: lat, Lon: lon, Ele: 372, Time: t0.Add(dt), Present: gpx.WptEle}
+		assert.NoError(t, trkPt.SetTCXExtension(&gpx.TCXExtension{DistanceMeters: distance}))
+		if watts != 0 {
+			assert.NoError(t, trkPt.SetPowerExtension(&gpx.PowerExtension{PowerInWatts: watts}))
+		}
+		assert.NoError(t, trkPt.SetTrackPointExtension(&gpx.TrackPointExtension{Version: 2, HR: hr, Cad: cad, Speed: speed}))
+		return trkPt
+	}
+	g := &gpx.GPX{
+		Version: "1.1",
+		Wpt: []*gpx.WptType{
+			{Lat: 46.52, Lon: 6.52, Time: t0, Name: "top", Desc: "view", Type: "Summit"},
+		},
+		Rte: []*gpx.RteType{
+			{
+				Name: "loop",
+				Desc: "lake loop",
+				RtePt: []*gpx.WptType{
+					newRtePt(46.5, 6.5, 0, 0),
+					newRtePt(46.51, 6.51, time.Minute, 1359.2),
+				},
+			},
+		},
+		Trk: []*gpx.TrkType{
+			{
+				Desc: "intervals",
+				Type: "Biking",
+				TrkSeg: []*gpx.TrkSegType{
+					{
+						TrkPt: []*gpx.WptType{
+							newTrkPt(46.5, 6.5, 0, 0, 120, 80, 250, 8.5),
+							newTrkPt(46.501, 6.501, 10*time.Second, 135, 125, 82, 0, 8.75),
+						},
+					},
+					{
+						TrkPt: []*gpx.WptType{
+							newTrkPt(46.502, 6.502, time.Minute, 270.5, 150, 0, 300, 0),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	var tcx strings.Builder
+	assert.NoError(t, g.WriteTCX(&tcx))
+	for _, s := range []string{
+		`<TrainingCenterDatabase xmlns="http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2" xmlns:ns3="http://www.garmin.com/xmlschemas/ActivityExtension/v2">`,
+		`<Activity Sport="Biking"><Id>2024-06-01T10:00:00Z</Id><Lap StartTime="2024-06-01T10:00:00Z"><TotalTimeSeconds>10</TotalTimeSeconds><DistanceMeters>134.99</DistanceMeters>`,
+		`<Lap StartTime="2024-06-01T10:01:00Z"><TotalTimeSeconds>0</TotalTimeSeconds><DistanceMeters>0</DistanceMeters>`,
+		`<DistanceMeters>270.5</DistanceMeters><HeartRateBpm><Value>150</Value></HeartRateBpm><Extensions><ns3:TPX><ns3:Watts>300</ns3:Watts></ns3:TPX></Extensions></Trackpoint>`,
+		`<Extensions><ns3:TPX><ns3:Speed>8.5</ns3:Speed><ns3:Watts>250</ns3:Watts></ns3:TPX></Extensions>`,
+		`<Notes>intervals</Notes></Activity>`,
+		`<CoursePoint><Name>top</Name><Time>2024-06-01T10:00:00Z</Time><Position><LatitudeDegrees>46.52</LatitudeDegrees><LongitudeDegrees>6.52</LongitudeDegrees></Position><PointType>Summit</PointType><Notes>view</Notes></CoursePoint>`,
+	} {
+		assert.Contains(t, tcx.String(), s)
+	}
+
+	actual, err := gpx.ReadTCX(strings.NewReader(tcx.String()))
+	assert.NoError(t, err)
+	assert.Equal(t, g, actual)
+}
+
+func TestWriteTCXDistances(t *testing.T) {
+	t0 := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	trkPt := &gpx.WptType{Lat: 46.501, Lon: 6.501, Time: t0.Add(10 * time.Second)}
+	assert.NoError(t, trkPt.SetTCXExtension(&gpx.TCXExtension{DistanceMeters: 1000}))
+	g := &gpx.GPX{
+		Version: "1.1",
+		Trk: []*gpx.TrkType{
+			{
+				TrkSeg: []*gpx.TrkSegType{
+					{
+						TrkPt: []*gpx.WptType{
+							{Lat: 46.5, Lon: 6.5, Time: t0},
+							trkPt,
+							{Lat: 46.502, Lon: 6.502, Time: t0.Add(20 * time.Second)},
+						},
+					},
+				},
+			},
+		},
+	}
+	var tcx strings.Builder
+	assert.NoError(t, g.WriteTCX(&tcx))
+	for _, s := range []string{
+		`<Time>2024-06-01T10:00:00Z</Time><Position><LatitudeDegrees>46.5</LatitudeDegrees><LongitudeDegrees>6.5</LongitudeDegrees></Position><DistanceMeters>0</DistanceMeters>`,
+		`<Time>2024-06-01T10:00:10Z</Time><Position><LatitudeDegrees>46.501</LatitudeDegrees><LongitudeDegrees>6.501</LongitudeDegrees></Position><DistanceMeters>1000</DistanceMeters>`,
+		`<Time>2024-06-01T10:00:20Z</Time><Position><LatitudeDegrees>46.502</LatitudeDegrees><LongitudeDegrees>6.502</LongitudeDegrees></Position><DistanceMeters>1134.99</DistanceMeters>`,
+	} {
+		assert.Contains(t, tcx.String(), s)
+	}
+}
+
+func TestWriteTCXWptsWithoutRte(t *testing.T) {
+	t0 := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	g := &gpx.GPX{
+		Version: "1.1",
+		Metadata: &gpx.MetadataType{
+			Name: "viewpoints",
+		},
+		Wpt: []*gpx.WptType{
+			{Lat: 46.52, Lon: 6.52, Time: t0, Name: "top", Type: "Summit"},
+		},
+	}
+	var tcx strings.Builder
+	assert.NoError(t, g.WriteTCX(&tcx))
+	assert.Contains(t, tcx.String(), `<Courses><Course><Name>viewpoints</Name>`)
+	assert.NotContains(t, tcx.String(), `<Track>`)
+
+	actual, err := gpx.ReadTCX(strings.NewReader(tcx.String()))
+	assert.NoError(t, err)
+	assert.Equal(t, g.Wpt, actual.Wpt)
+	assert.Equal(t, []*gpx.RteType{{Name: "viewpoints"}}, actual.Rte)
+}
+
+func TestWriteTCXMissingTime(t *testing.T) {
+	t0 := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name string
+		g    *gpx.GPX
+	}{
+		{
+			name: "trkpt",
+			g: &gpx.GPX{
+				Trk: []*gpx.TrkType{
+					{
+						TrkSeg: []*gpx.TrkSegType{
+							{
+								TrkPt: []*gpx.WptType{
+									{Lat: 46.5, Lon: 6.5, Time: t0},
+									{Lat: 46.501, Lon: 6.501},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "rtept",
+			g: &gpx.GPX{
+				Rte: []*gpx.RteType{
+					{
+						RtePt: []*gpx.WptType{
+							{Lat: 46.5, Lon: 6.5},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "wpt",
+			g: &gpx.GPX{
+				Wpt: []*gpx.WptType{
+					{Lat: 46.52, Lon: 6.52, Name: "top"},
+				},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var tcx strings.Builder
+			assert.EqualError(t, tc.g.WriteTCX(&tcx), "missing time")
+			assert.Equal(t, "", tcx.String())
+		})
+	}
+}
+
+func TestWriteTCXSkipsEmptyTrks(t *testing.T) {
+	t0 := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	g := &gpx.GPX{
+		Trk: []*gpx.TrkType{
+			{
+				Name: "empty",
+			},
+			{
+				TrkSeg: []*gpx.TrkSegType{
+					{},
+					{
+						TrkPt: []*gpx.WptType{
+							{Lat: 46.5, Lon: 6.5, Time: t0},
+						},
+					},
+					{},
+				},
+			},
+		},
+	}
+	var tcx strings.Builder
+	assert.NoError(t, g.WriteTCX(&tcx))
+	assert.Equal(t, 1, strings.Count(tcx.String(), `<Activity `))
+	assert.Equal(t, 1, strings.Count(tcx.String(), `<Lap `))
+	assert.Contains(t, tcx.String(), `<Id>2024-06-01T10:00:00Z</Id><Lap StartTime="2024-06-01T10:00:00Z">`)
+
+	g.Trk = g.Trk[:1]
+	tcx.Reset()
+	assert.NoError(t, g.WriteTCX(&tcx))
+	assert.NotContains(t, tcx.String(), `<Activities>`)
+}
+
+func TestReadTCX(t *testing.T) {
+	tcx := `<?xml version="1.0" encoding="UTF-8"?>
+<TrainingCenterDatabase xmlns="http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2">
+  <Activities>
+    <Activity Sport="Running">
+      <Id>2024-06-01T10:00:00Z</Id>
+      <Lap StartTime="2024-06-01T10:00:00Z">
+        <Track>
+          <Trackpoint>
+            <Time>2024-06-01T10:00:00Z</Time>
+            <HeartRateBpm><Value>110</Value></HeartRateBpm>
+          </Trackpoint>
+          <Trackpoint>
+            <Time>2024-06-01T10:00:01Z</Time>
+            <Position>
+              <LatitudeDegrees>37.4</LatitudeDegrees>
+              <LongitudeDegrees>-122.2</LongitudeDegrees>
+            </Position>
+            <AltitudeMeters>150</AltitudeMeters>
+            <DistanceMeters>0</DistanceMeters>
+            <HeartRateBpm><Value>112</Value></HeartRateBpm>
+            <Extensions>
+              <TPX xmlns="http://www.garmin.com/xmlschemas/ActivityExtension/v2">
+                <Speed>3.2</Speed>
+                <RunCadence>88</RunCadence>
+              </TPX>
+            </Extensions>
+          </Trackpoint>
+        </Track>
+        <Track>
+          <Trackpoint>
+            <Time>2024-06-01T10:02:00Z</Time>
+            <Position>
+              <LatitudeDegrees>37.5</LatitudeDegrees>
+              <LongitudeDegrees>-122.3</LongitudeDegrees>
+            </Position>
+          </Trackpoint>
+        </Track>
+      </Lap>
+      <Lap StartTime="2024-06-01T10:05:00Z">
+        <Track>
+          <Trackpoint>
+            <Time>2024-06-01T10:05:00Z</Time>
+          </Trackpoint>
+        </Track>
+      </Lap>
+    </Activity>
+  </Activities>
+</TrainingCenterDatabase>`
+	g, err := gpx.ReadTCX(strings.NewReader(tcx))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(g.Trk))
+	assert.Equal(t, "Running", g.Trk[0].Type)
+	assert.Equal(t, 2, len(g.Trk[0].TrkSeg))
+	assert.Equal(t, 1, len(g.Trk[0].TrkSeg[0].TrkPt))
+	assert.Equal(t, 1, len(g.Trk[0].TrkSeg[1].TrkPt))
+	trkPt := g.Trk[0].TrkSeg[0].TrkPt[0]
+	assert.Equal(t, 37.4, trkPt.Lat)
+	assert.Equal(t, -122.2, trkPt.Lon)
+	assert.Equal(t, 150.0, trkPt.Ele)
+	assert.True(t, trkPt.Has(gpx.WptEle))
+	assert.Equal(t, time.Date(2024, 6, 1, 10, 0, 1, 0, time.UTC), trkPt.Time)
+	tpe, err := trkPt.TrackPointExtension()
+	assert.NoError(t, err)
+	assert.Equal(t, &gpx.TrackPointExtension{
+		Version: 2,
+		HR:      112,
+		Cad:     88,
+		Speed:   3.2,
+		Present: gpx.TrackPointExtensionHR | gpx.TrackPointExtensionCad | gpx.TrackPointExtensionSpeed,
+	}, tpe)
+	te, err := trkPt.TCXExtension()
+	assert.NoError(t, err)
+	assert.Equal(t, &gpx.TCXExtension{DistanceMeters: 0}, te)
+	te, err = g.Trk[0].TrkSeg[1].TrkPt[0].TCXExtension()
+	assert.NoError(t, err)
+	assert.Zero(t, te)
+
+	_, err = gpx.ReadTCX(strings.NewReader(`<TrainingCenterDatabase><Activities><Activity><Lap><Track><Trackpoint>` +
+		`<Time>yesterday</Time><Position><LatitudeDegrees>0</LatitudeDegrees><LongitudeDegrees>0</LongitudeDegrees></Position>` +
+		`</Trackpoint></Track></Lap></Activity></Activities></TrainingCenterDatabase>`))
+	assert.Error(t, err)
+}
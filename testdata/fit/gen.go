@@ -0,0 +1,185 @@
+//go:build ignore
+
+// gen generates the FIT files in this directory. Run it with:
+//
+//	go run gen.go
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log"
+	"math"
+	"os"
+)
+
+// t0 is 2024-06-01T10:00:00Z as a FIT timestamp.
+const t0 = 1717236000 - 631065600
+
+var crcTable = [16]uint16{
+	0x0000, 0xcc01, 0xd801, 0x1400, 0xf001, 0x3c00, 0x2800, 0xe401,
+	0xa001, 0x6c00, 0x7800, 0xb401, 0x5000, 0x9c01, 0x8801, 0x4400,
+}
+
+type field struct {
+	num      uint8
+	size     uint8
+	baseType uint8
+}
+
+type devField struct {
+	num      uint8
+	size     uint8
+	devIndex uint8
+}
+
+var (
+	fileIDFields = []field{{0, 1, 0x00}, {1, 2, 0x84}, {2, 2, 0x84}, {4, 4, 0x86}}
+	eventFields  = []field{{253, 4, 0x86}, {0, 1, 0x00}, {1, 1, 0x00}}
+	recordFields = []field{
+		{253, 4, 0x86}, {0, 4, 0x85}, {1, 4, 0x85}, {78, 4, 0x86}, {3, 1, 0x02},
+		{4, 1, 0x02}, {7, 2, 0x84}, {13, 1, 0x01}, {73, 4, 0x86},
+	}
+	compressedRecordFields = []field{{0, 4, 0x85}, {1, 4, 0x85}, {2, 2, 0x84}, {3, 1, 0x02}}
+	courseFields           = []field{{4, 1, 0x00}, {5, 16, 0x07}}
+	courseRecordFields     = []field{{253, 4, 0x86}, {0, 4, 0x85}, {1, 4, 0x85}, {2, 2, 0x84}, {5, 4, 0x86}}
+	coursePointFields      = []field{{254, 2, 0x84}, {1, 4, 0x86}, {2, 4, 0x85}, {3, 4, 0x85}, {5, 1, 0x00}, {6, 16, 0x07}}
+)
+
+// A fitWriter writes the records of a FIT file.
+type fitWriter struct {
+	bytes.Buffer
+}
+
+// definition writes a definition message to w.
+func (w *fitWriter) definition(localMesgType uint8, globalMesgNum uint16, byteOrder binary.ByteOrder, fields []field, devFields []devField) {
+	header := 0x40 | localMesgType
+	if devFields != nil {
+		header |= 0x20
+	}
+	architecture := uint8(0)
+	if byteOrder == binary.BigEndian {
+		architecture = 1
+	}
+	w.Write([]byte{header, 0, architecture})
+	if err := binary.Write(w, byteOrder, globalMesgNum); err != nil {
+		log.Fatal(err)
+	}
+	w.WriteByte(uint8(len(fields)))
+	for _, f := range fields {
+		w.Write([]byte{f.num, f.size, f.baseType})
+	}
+	if devFields != nil {
+		w.WriteByte(uint8(len(devFields)))
+		for _, f := range devFields {
+			w.Write([]byte{f.num, f.size, f.devIndex})
+		}
+	}
+}
+
+// data writes a data message with header and values to w.
+func (w *fitWriter) data(header uint8, byteOrder binary.ByteOrder, values ...any) {
+	w.WriteByte(header)
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			value = padString(s, 16)
+		}
+		if err := binary.Write(w, byteOrder, value); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// file returns the records in w as a complete FIT file.
+func (w *fitWriter) file() []byte {
+	header := []byte{14, 0x20}
+	header = binary.LittleEndian.AppendUint16(header, 2132)
+	header = binary.LittleEndian.AppendUint32(header, uint32(w.Len()))
+	header = append(header, ".FIT"...)
+	header = binary.LittleEndian.AppendUint16(header, crc(header))
+	file := append(header, w.Bytes()...)
+	return binary.LittleEndian.AppendUint16(file, crc(file))
+}
+
+// crc returns the FIT CRC of data.
+func crc(data []byte) uint16 {
+	var c uint16
+	for _, b := range data {
+		t := crcTable[c&0xf]
+		c = c >> 4 & 0x0fff
+		c ^= t ^ crcTable[b&0xf]
+		t = crcTable[c&0xf]
+		c = c >> 4 & 0x0fff
+		c ^= t ^ crcTable[b>>4&0xf]
+	}
+	return c
+}
+
+// semicircles returns deg in semicircles.
+func semicircles(deg float64) int32 {
+	return int32(math.RoundToEven(deg * (1 << 31) / 180))
+}
+
+// altitude returns m as a scaled and offset FIT altitude.
+func altitude(m float64) uint32 {
+	return uint32(math.RoundToEven((m + 500) * 5))
+}
+
+// padString returns s padded with zeros to size bytes.
+func padString(s string, size int) []byte {
+	b := make([]byte, size)
+	copy(b, s)
+	return b
+}
+
+// activity returns an activity file with two timer sessions, an invalid
+// position, developer data, and a compressed timestamp header.
+func activity() []byte {
+	const invalid = int32(math.MaxInt32)
+	be := binary.BigEndian
+	le := binary.LittleEndian
+	devBytes := []byte{0x01, 0x02}
+	var w fitWriter
+	w.definition(0, 0, le, fileIDFields, nil)
+	w.data(0, le, uint8(4), uint16(1), uint16(1234), uint32(t0))
+	w.definition(1, 21, le, eventFields, nil)
+	w.data(1, le, uint32(t0), uint8(0), uint8(0))
+	w.definition(2, 20, be, recordFields, []devField{{0, 2, 0}})
+	w.data(2, be, uint32(t0), semicircles(46.5), semicircles(6.5), altitude(372), uint8(120), uint8(80), uint16(250), int8(21), uint32(8500), devBytes)
+	w.data(2, be, uint32(t0+1), semicircles(46.501), semicircles(6.501), altitude(373), uint8(121), uint8(0xff), uint16(0xffff), int8(0x7f), uint32(0xffffffff), devBytes)
+	w.data(1, le, uint32(t0+2), uint8(0), uint8(4))
+	w.data(1, le, uint32(t0+27), uint8(0), uint8(0))
+	w.data(2, be, uint32(t0+27), invalid, invalid, altitude(374), uint8(122), uint8(0xff), uint16(0xffff), int8(0x7f), uint32(0xffffffff), devBytes)
+	w.data(2, be, uint32(t0+28), semicircles(46.502), semicircles(6.502), altitude(374), uint8(130), uint8(0xff), uint16(0xffff), int8(0x7f), uint32(0xffffffff), devBytes)
+	w.definition(3, 20, le, compressedRecordFields, nil)
+	w.data(0x80|3<<5|1, le, semicircles(46.503), semicircles(6.503), uint16(altitude(375)), uint8(131))
+	return w.file()
+}
+
+// course returns a course file with records and course points.
+func course() []byte {
+	le := binary.LittleEndian
+	var w fitWriter
+	w.definition(0, 0, le, fileIDFields, nil)
+	w.data(0, le, uint8(6), uint16(1), uint16(1234), uint32(t0))
+	w.definition(1, 31, le, courseFields, nil)
+	w.data(1, le, uint8(2), "Lake loop")
+	w.definition(2, 20, le, courseRecordFields, nil)
+	w.data(2, le, uint32(t0), semicircles(46.5), semicircles(6.5), uint16(altitude(372)), uint32(0))
+	w.data(2, le, uint32(t0+60), semicircles(46.51), semicircles(6.51), uint16(altitude(380)), uint32(135000))
+	w.definition(3, 32, le, coursePointFields, nil)
+	w.data(3, le, uint16(0), uint32(t0+30), semicircles(46.505), semicircles(6.505), uint8(1), "Top")
+	w.data(3, le, uint16(1), uint32(t0+60), semicircles(46.51), semicircles(6.51), uint8(24), "Segment")
+	return w.file()
+}
+
+func main() {
+	for name, data := range map[string][]byte{
+		"activity.fit": activity(),
+		"course.fit":   course(),
+	} {
+		if err := os.WriteFile(name, data, 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}